@@ -0,0 +1,410 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	// DefaultJWTSecret is only acceptable outside of production.
+	DefaultJWTSecret = "your_secret_key"
+
+	redacted = "[REDACTED]"
+)
+
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+type Config struct {
+	Env             string          `yaml:"env"`
+	ListenAddr      string          `yaml:"listen_addr"`
+	AdminAddr       string          `yaml:"admin_addr"`
+	LogLevel        string          `yaml:"log_level"`
+	Server          ServerConfig    `yaml:"server"`
+	DatabaseURL     string          `yaml:"database_url"`
+	JWTSecret       string          `yaml:"jwt_secret"`
+	AccessTokenTTL  time.Duration   `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration   `yaml:"refresh_token_ttl"`
+	RateLimit       RateLimitConfig `yaml:"rate_limit"`
+	Database        DatabaseConfig  `yaml:"database"`
+	Trash           TrashConfig     `yaml:"trash"`
+	Tracing         TracingConfig   `yaml:"tracing"`
+}
+
+// ServerConfig bounds how long the HTTP server spends reading a request,
+// writing its response and keeping an idle connection open. On SIGTERM or
+// SIGINT the readiness probe starts failing, the server waits DrainDelay for
+// load balancers to notice and then gives in-flight requests up to
+// ShutdownTimeout to finish.
+type ServerConfig struct {
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	DrainDelay      time.Duration `yaml:"drain_delay"`
+}
+
+// RateLimitConfig has a policy per route class. Auth covers the credential
+// endpoints, the other routes count as reads or writes by method. Each client
+// gets its own buckets, which are dropped after IdleTimeout without use.
+type RateLimitConfig struct {
+	Auth        RateLimitPolicy `yaml:"auth"`
+	Read        RateLimitPolicy `yaml:"read"`
+	Write       RateLimitPolicy `yaml:"write"`
+	IdleTimeout time.Duration   `yaml:"idle_timeout"`
+}
+
+type RateLimitPolicy struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
+
+// DatabaseConfig bounds how long a single read or write may take and tunes
+// the connection pool. Zero lifetimes keep connections open indefinitely.
+type DatabaseConfig struct {
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+// TrashConfig sets how long deleted snippets stay in the trash before they're
+// purged for good, and how often the purger looks for them.
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention"`
+	PurgeInterval time.Duration `yaml:"purge_interval"`
+}
+
+// TracingConfig turns on OpenTelemetry tracing. Spans go to OTLPEndpoint, an
+// OTLP/HTTP URL, when it is set and are otherwise written as JSON to File, or
+// to stdout when File is empty too. SampleRatio is the share of new traces
+// that are kept.
+type TracingConfig struct {
+	Enabled      bool    `yaml:"enabled"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint"`
+	File         string  `yaml:"file"`
+	SampleRatio  float64 `yaml:"sample_ratio"`
+}
+
+func Default() Config {
+	return Config{
+		Env:         EnvDevelopment,
+		ListenAddr:  ":8080",
+		AdminAddr:   "127.0.0.1:9090",
+		LogLevel:    "info",
+		DatabaseURL: "host=localhost port=5432 user=postgres password=mysecretpassword dbname=snippet_manager sslmode=disable",
+		JWTSecret:   DefaultJWTSecret,
+		Server: ServerConfig{
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 20 * time.Second,
+			DrainDelay:      5 * time.Second,
+		},
+
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 30 * 24 * time.Hour,
+		RateLimit: RateLimitConfig{
+			Auth:        RateLimitPolicy{RequestsPerSecond: 0.2, Burst: 5},
+			Read:        RateLimitPolicy{RequestsPerSecond: 10, Burst: 20},
+			Write:       RateLimitPolicy{RequestsPerSecond: 2, Burst: 10},
+			IdleTimeout: 10 * time.Minute,
+		},
+		Database: DatabaseConfig{
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Tracing: TracingConfig{SampleRatio: 1},
+	}
+}
+
+// Load builds the configuration from the defaults, then the YAML file named
+// by SNIPPET_CONFIG_FILE if set, then individual SNIPPET_* environment
+// variables, and validates the result.
+func Load() (Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv("SNIPPET_CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	stringVars := map[string]*string{
+		"SNIPPET_ENV":           &c.Env,
+		"SNIPPET_LISTEN_ADDR":   &c.ListenAddr,
+		"SNIPPET_ADMIN_ADDR":    &c.AdminAddr,
+		"SNIPPET_LOG_LEVEL":     &c.LogLevel,
+		"SNIPPET_DATABASE_URL":  &c.DatabaseURL,
+		"SNIPPET_JWT_SECRET":    &c.JWTSecret,
+		"SNIPPET_OTLP_ENDPOINT": &c.Tracing.OTLPEndpoint,
+		"SNIPPET_TRACING_FILE":  &c.Tracing.File,
+	}
+	for name, field := range stringVars {
+		if value, ok := lookup(name); ok {
+			*field = value
+		}
+	}
+
+	durationVars := map[string]*time.Duration{
+		"SNIPPET_SERVER_READ_TIMEOUT":     &c.Server.ReadTimeout,
+		"SNIPPET_SERVER_WRITE_TIMEOUT":    &c.Server.WriteTimeout,
+		"SNIPPET_SERVER_IDLE_TIMEOUT":     &c.Server.IdleTimeout,
+		"SNIPPET_SHUTDOWN_TIMEOUT":        &c.Server.ShutdownTimeout,
+		"SNIPPET_DRAIN_DELAY":             &c.Server.DrainDelay,
+		"SNIPPET_ACCESS_TOKEN_TTL":        &c.AccessTokenTTL,
+		"SNIPPET_REFRESH_TOKEN_TTL":       &c.RefreshTokenTTL,
+		"SNIPPET_RATE_LIMIT_IDLE_TIMEOUT": &c.RateLimit.IdleTimeout,
+		"SNIPPET_DB_READ_TIMEOUT":         &c.Database.ReadTimeout,
+		"SNIPPET_DB_WRITE_TIMEOUT":        &c.Database.WriteTimeout,
+		"SNIPPET_DB_CONN_MAX_LIFETIME":    &c.Database.ConnMaxLifetime,
+		"SNIPPET_DB_CONN_MAX_IDLE_TIME":   &c.Database.ConnMaxIdleTime,
+		"SNIPPET_TRASH_RETENTION":         &c.Trash.Retention,
+		"SNIPPET_TRASH_PURGE_INTERVAL":    &c.Trash.PurgeInterval,
+	}
+	for name, field := range durationVars {
+		if value, ok := lookup(name); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*field = d
+		}
+	}
+
+	intVars := map[string]*int{
+		"SNIPPET_DB_MAX_OPEN_CONNS": &c.Database.MaxOpenConns,
+		"SNIPPET_DB_MAX_IDLE_CONNS": &c.Database.MaxIdleConns,
+	}
+	for name, field := range intVars {
+		if value, ok := lookup(name); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*field = n
+		}
+	}
+
+	if value, ok := lookup("SNIPPET_TRACING_ENABLED"); ok {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("SNIPPET_TRACING_ENABLED: %w", err)
+		}
+		c.Tracing.Enabled = enabled
+	}
+	if value, ok := lookup("SNIPPET_TRACING_SAMPLE_RATIO"); ok {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("SNIPPET_TRACING_SAMPLE_RATIO: %w", err)
+		}
+		c.Tracing.SampleRatio = ratio
+	}
+
+	policies := map[string]*RateLimitPolicy{
+		"AUTH":  &c.RateLimit.Auth,
+		"READ":  &c.RateLimit.Read,
+		"WRITE": &c.RateLimit.Write,
+	}
+	for class, policy := range policies {
+		name := "SNIPPET_RATE_LIMIT_" + class + "_RPS"
+		if value, ok := lookup(name); ok {
+			rps, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			policy.RequestsPerSecond = rps
+		}
+		name = "SNIPPET_RATE_LIMIT_" + class + "_BURST"
+		if value, ok := lookup(name); ok {
+			burst, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			policy.Burst = burst
+		}
+	}
+	return nil
+}
+
+func (c Config) Validate() error {
+	var problems []string
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		problems = append(
+			problems,
+			fmt.Sprintf("env must be %q or %q", EnvDevelopment, EnvProduction),
+		)
+	}
+	if c.ListenAddr == "" {
+		problems = append(problems, "listen_addr can't be empty")
+	}
+	if c.AdminAddr == "" {
+		problems = append(problems, "admin_addr can't be empty")
+	} else if c.AdminAddr == c.ListenAddr {
+		problems = append(problems, "admin_addr must differ from listen_addr")
+	}
+	if !slices.Contains(logLevels, c.LogLevel) {
+		problems = append(
+			problems,
+			fmt.Sprintf("log_level must be one of %s", strings.Join(logLevels, ", ")),
+		)
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 {
+		problems = append(
+			problems,
+			"server.read_timeout, server.write_timeout and server.idle_timeout must be positive",
+		)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "server.shutdown_timeout must be positive")
+	}
+	if c.Server.DrainDelay < 0 {
+		problems = append(problems, "server.drain_delay can't be negative")
+	}
+	if c.DatabaseURL == "" {
+		problems = append(problems, "database_url can't be empty")
+	}
+	if c.JWTSecret == "" {
+		problems = append(problems, "jwt_secret can't be empty")
+	}
+	if c.IsProduction() && c.JWTSecret == DefaultJWTSecret {
+		problems = append(problems, "jwt_secret must be changed from the default in production")
+	}
+	if c.AccessTokenTTL <= 0 {
+		problems = append(problems, "access_token_ttl must be positive")
+	}
+	if c.RefreshTokenTTL <= c.AccessTokenTTL {
+		problems = append(problems, "refresh_token_ttl must be longer than access_token_ttl")
+	}
+	policies := []struct {
+		class  string
+		policy RateLimitPolicy
+	}{
+		{"auth", c.RateLimit.Auth},
+		{"read", c.RateLimit.Read},
+		{"write", c.RateLimit.Write},
+	}
+	for _, p := range policies {
+		if p.policy.RequestsPerSecond <= 0 {
+			problems = append(
+				problems,
+				fmt.Sprintf("rate_limit.%s.requests_per_second must be positive", p.class),
+			)
+		}
+		if p.policy.Burst < 1 {
+			problems = append(problems, fmt.Sprintf("rate_limit.%s.burst must be at least 1", p.class))
+		}
+	}
+	if c.RateLimit.IdleTimeout <= 0 {
+		problems = append(problems, "rate_limit.idle_timeout must be positive")
+	}
+	if c.Database.ReadTimeout <= 0 {
+		problems = append(problems, "database.read_timeout must be positive")
+	}
+	if c.Database.WriteTimeout <= 0 {
+		problems = append(problems, "database.write_timeout must be positive")
+	}
+	if c.Database.MaxOpenConns < 1 {
+		problems = append(problems, "database.max_open_conns must be at least 1")
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problems = append(
+			problems,
+			"database.max_idle_conns must be between 0 and database.max_open_conns",
+		)
+	}
+	if c.Database.ConnMaxLifetime < 0 || c.Database.ConnMaxIdleTime < 0 {
+		problems = append(
+			problems,
+			"database.conn_max_lifetime and database.conn_max_idle_time can't be negative",
+		)
+	}
+	if c.Trash.Retention <= 0 || c.Trash.PurgeInterval <= 0 {
+		problems = append(problems, "trash.retention and trash.purge_interval must be positive")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (c Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// Redacted returns a copy of the config that is safe to print.
+func (c Config) Redacted() Config {
+	c.JWTSecret = redacted
+	c.DatabaseURL = redactDSN(c.DatabaseURL)
+	return c
+}
+
+// String renders the redacted config as YAML.
+func (c Config) String() string {
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|[^\s&]*)`)
+
+// redactDSN hides the password in both URL and key=value connection strings.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			dsn = strings.Replace(u.String(), "xxxxx", redacted, 1)
+		}
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
@@ -0,0 +1,100 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/config"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := "listen_addr: \":9090\"\naccess_token_ttl: 5m\n" +
+		"rate_limit:\n  write:\n    requests_per_second: 10\n    burst: 20\n" +
+		"database:\n  read_timeout: 2s\n"
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SNIPPET_CONFIG_FILE", path)
+	t.Setenv("SNIPPET_RATE_LIMIT_WRITE_BURST", "30")
+	t.Setenv("SNIPPET_REFRESH_TOKEN_TTL", "48h")
+	t.Setenv("SNIPPET_DB_MAX_OPEN_CONNS", "50")
+	t.Setenv("SNIPPET_TRASH_RETENTION", "168h")
+	t.Setenv("SNIPPET_TRACING_ENABLED", "true")
+	t.Setenv("SNIPPET_SHUTDOWN_TIMEOUT", "45s")
+	t.Setenv("SNIPPET_LOG_LEVEL", "debug")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ListenAddr != ":9090" {
+		t.Errorf("got listen addr %q want value from file", cfg.ListenAddr)
+	}
+	if cfg.RateLimit.Write.RequestsPerSecond != 10 {
+		t.Errorf("got rps %v want value from file", cfg.RateLimit.Write.RequestsPerSecond)
+	}
+	if cfg.RateLimit.Write.Burst != 30 {
+		t.Errorf("got burst %d want value from environment", cfg.RateLimit.Write.Burst)
+	}
+	if cfg.RateLimit.Read != config.Default().RateLimit.Read {
+		t.Errorf("got read policy %+v want the default", cfg.RateLimit.Read)
+	}
+	if cfg.AccessTokenTTL != 5*time.Minute {
+		t.Errorf("got access token ttl %v want value from file", cfg.AccessTokenTTL)
+	}
+	if cfg.RefreshTokenTTL != 48*time.Hour {
+		t.Errorf("got refresh token ttl %v want value from environment", cfg.RefreshTokenTTL)
+	}
+	if cfg.Database.ReadTimeout != 2*time.Second {
+		t.Errorf("got read timeout %v want value from file", cfg.Database.ReadTimeout)
+	}
+	if cfg.Database.MaxOpenConns != 50 {
+		t.Errorf("got max open conns %d want value from environment", cfg.Database.MaxOpenConns)
+	}
+	if cfg.Trash.Retention != 7*24*time.Hour {
+		t.Errorf("got trash retention %v want value from environment", cfg.Trash.Retention)
+	}
+	if !cfg.Tracing.Enabled || cfg.Tracing.SampleRatio != 1 {
+		t.Errorf("got tracing %+v want it enabled from environment", cfg.Tracing)
+	}
+	if cfg.Server.ShutdownTimeout != 45*time.Second {
+		t.Errorf("got shutdown timeout %v want value from environment", cfg.Server.ShutdownTimeout)
+	}
+	if cfg.LogLevel != "debug" {
+		t.Errorf("got log level %q want value from environment", cfg.LogLevel)
+	}
+}
+
+func TestLoadRejectsDefaultSecretInProduction(t *testing.T) {
+	t.Setenv("SNIPPET_ENV", config.EnvProduction)
+	if _, err := config.Load(); err == nil {
+		t.Fatal("expected production with the default JWT secret to be rejected")
+	}
+
+	t.Setenv("SNIPPET_JWT_SECRET", "a-real-secret")
+	if _, err := config.Load(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestStringRedactsSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.JWTSecret = "super-secret"
+	cfg.DatabaseURL = "postgres://app:hunter2@db:5432/snippets?sslmode=require"
+
+	out := cfg.String()
+	for _, secret := range []string{"super-secret", "hunter2", "mysecretpassword"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("printed config leaks %q:\n%s", secret, out)
+		}
+	}
+
+	cfg.DatabaseURL = config.Default().DatabaseURL
+	if strings.Contains(cfg.String(), "mysecretpassword") {
+		t.Errorf("printed config leaks the key=value DSN password")
+	}
+}
@@ -4,37 +4,154 @@ const (
 	// General errors
 	ErrMethodNotAllowed   = "Method not allowed"
 	ErrInvalidPayload     = "Invalid request payload"
+	ErrUnsupportedMedia   = "Unsupported content type"
+	ErrPayloadTooLarge    = "Request body is too large"
 	ErrFailedToGetUserID  = "Failed to get userID"
 	ErrInvalidCredentials = "Invalid credentials"
+	ErrNotFound           = "Not found"
+	ErrInternal           = "Something went wrong on our side"
+	ErrTimeout            = "The request took too long, try again later"
+	ErrUnavailable        = "The service is unavailable, try again later"
+	ErrNotReady           = "The service isn't ready to serve requests"
+	ErrShuttingDown       = "The service is shutting down"
+
+	// Authentication-related errors
+	ErrAuthorizationMissing = "Authorization header missing"
+	ErrInvalidToken         = "Invalid or expired token"
+	ErrSessionEnded         = "Session has ended, log in again"
+	ErrTokenNotAllowed      = "Personal access tokens can't be used here"
+	ErrMissingScope         = "Token is missing the %s scope"
+	ErrFailedToAuthenticate = "Failed to authenticate"
+	ErrTooManyRequests      = "Too many requests"
 
 	// Snippet-related errors
-	ErrFailedToGetSnippets     = "Failed to get snippets"
-	ErrFailedToCreateSnippet   = "Failed to create snippet"
-	ErrFailedToUpdateSnippet   = "Failed to update snippet"
-	ErrSnippetNotFound         = "Snippet not found"
-	ErrFailedToDeleteSnippet   = "Failed to delete snippet"
-	ErrInvalidSnippetID        = "Invalid snippet ID"
-	ErrSnippetValidationFailed = "Invalid request payload: "
+	ErrFailedToGetSnippets    = "Failed to get snippets"
+	ErrFailedToCreateSnippet  = "Failed to create snippet"
+	ErrFailedToUpdateSnippet  = "Failed to update snippet"
+	ErrSnippetNotFound        = "Snippet not found"
+	ErrFailedToDeleteSnippet  = "Failed to delete snippet"
+	ErrInvalidSnippetID       = "Invalid snippet ID"
+	ErrInvalidSearchQuery     = "Invalid search query"
+	ErrFailedToSearchSnippets = "Failed to search snippets"
+	ErrInvalidLimit           = "Limit must be a positive integer"
+	ErrInvalidCursor          = "Invalid cursor"
+	ErrCursorSortMismatch     = "Cursor was issued for a different sort order"
+	ErrInvalidSortOptions     = "Sort options are invalid"
+	ErrMissingLanguage        = "Missing language parameter"
+	ErrSnippetChanged         = "Snippet was changed since you read it"
+	ErrIfMatchRequired        = "If-Match header is required, send the ETag you last read"
+	ErrVersionRequired        = "Version is required, send the version you last read"
+
+	// Batch-related errors
+	ErrFailedToRunBatch  = "Failed to run batch"
+	ErrInvalidBatchMode  = "Mode must be \"atomic\" or \"best_effort\""
+	ErrEmptyBatch        = "A batch needs at least one operation"
+	ErrTooManyOperations = "A batch can have at most %d operations"
+	ErrInvalidOperation  = "Operation must be create, update or delete"
+	ErrBatchAborted      = "Not applied because another operation in the batch failed"
+
+	// Import and export errors
+	ErrFailedToExport        = "Failed to export snippets"
+	ErrFailedToImport        = "Failed to import snippets"
+	ErrUnknownFormat         = "Unknown format"
+	ErrInvalidLibrary        = "The library can't be read"
+	ErrInvalidConflictPolicy = "Conflict policy must be skip, overwrite or rename"
+	ErrInvalidDryRun         = "dry_run must be true or false"
+	ErrImportTooLarge        = "Import is too large, split it into smaller ones"
+
+	// Revision-related errors
+	ErrFailedToGetRevisions    = "Failed to get revisions"
+	ErrFailedToRestoreRevision = "Failed to restore revision"
+	ErrRevisionNotFound        = "Revision not found"
+	ErrInvalidRevision         = "Revision must be a positive integer"
+	ErrDiffTooLarge            = "Revisions are too large to diff"
+
+	// Trash-related errors
+	ErrFailedToGetTrash       = "Failed to get the trash"
+	ErrFailedToRestoreSnippet = "Failed to restore snippet"
+	ErrFailedToPurgeSnippet   = "Failed to purge snippet"
+
+	// Sharing-related errors
+	ErrFailedToShareSnippet    = "Failed to share snippet"
+	ErrFailedToGetShareLinks   = "Failed to get share links"
+	ErrFailedToRevokeShareLink = "Failed to revoke share link"
+	ErrShareLinkNotFound       = "Share link not found"
+	ErrInvalidShareID          = "Invalid share link ID"
+	ErrSnippetPrivate          = "Private snippets can't be shared, make it unlisted or public first"
+
+	// Organization-related errors
+	ErrFailedToCreateOrg    = "Failed to create organization"
+	ErrFailedToGetOrgs      = "Failed to get organizations"
+	ErrFailedToGetMembers   = "Failed to get organization members"
+	ErrFailedToRemoveMember = "Failed to remove organization member"
+	ErrFailedToInvite       = "Failed to invite member"
+	ErrFailedToGetInvites   = "Failed to get invites"
+	ErrFailedToAcceptInvite = "Failed to accept invite"
+	ErrInvalidOrgID         = "Invalid organization ID"
+	ErrInvalidInviteID      = "Invalid invite ID"
+	ErrInvalidMemberID      = "Invalid member ID"
+	ErrOrgNotFound          = "Organization not found"
+	ErrMemberNotFound       = "Member not found"
+	ErrInviteNotFound       = "Invite not found"
+	ErrAlreadyMember        = "User is already a member of this organization"
+	ErrInviteExists         = "An invite is already pending for this email"
+	ErrLastOwner            = "An organization must keep at least one owner"
+	ErrAccessDenied         = "You don't have permission to do that"
+
+	// Tag-related errors
+	ErrFailedToGetTags   = "Failed to get tags"
+	ErrFailedToRenameTag = "Failed to rename tag"
+	ErrFailedToMergeTags = "Failed to merge tags"
+	ErrTagNotFound       = "Tag not found"
+	ErrTagAlreadyExists  = "Tag already exists, merge the tags instead"
+	ErrInvalidTagMatch   = "Tag match must be \"all\" or \"any\""
+	ErrMergeIntoItself   = "Can't merge a tag into itself"
 
 	// User-related errors
 	ErrInvalidEmailFormat     = "Invalid email format"
 	ErrFailedToCreateUser     = "Failed to create user"
 	ErrFailedToGenerateToken  = "Failed to generate JWT token"
 	ErrFailedToDeleteUser     = "Failed to delete user"
-	ErrFailedToExtractTokenID = "Failed to extract ID from token"
 	ErrFailedToUpdatePassword = "Failed to updated password"
+	ErrPasswordUnchanged      = "New password must be different from the old password"
+	ErrUsernameTaken          = "Username is already taken"
+	ErrEmailTaken             = "Email is already registered"
+
+	// Session-related errors
+	ErrInvalidRefreshToken  = "Invalid or expired refresh token"
+	ErrRefreshTokenReused   = "Refresh token was already used, the session has been revoked"
+	ErrFailedToRefreshToken = "Failed to refresh token"
+	ErrFailedToLogout       = "Failed to log out"
+
+	// Access token-related errors
+	ErrFailedToCreateAccessToken = "Failed to create access token"
+	ErrFailedToGetAccessTokens   = "Failed to get access tokens"
+	ErrFailedToRotateAccessToken = "Failed to rotate access token"
+	ErrFailedToRevokeAccessToken = "Failed to revoke access token"
+	ErrAccessTokenNotFound       = "Access token not found"
+	ErrInvalidAccessTokenID      = "Invalid access token ID"
 
 	// Validation messages
-	ErrEmptyTitle         = "Title can't be empty!"
-	ErrEmptyLanguage      = "Language can't be empty!"
-	ErrEmptyContent       = "Content can't be empty!"
-	ErrPasswordTooShort   = "Password must be at least 8 characters long"
-	ErrPasswordTooLong    = "Password must be at most 20 characters long"
-	ErrInvalidPassword    = "Password must contain at least one %s"
-	ErrEmptyUsername      = "Username can't be empty"
-	ErrUsernameTooShort   = "Username must be at least 3 characters long"
-	ErrUsernameTooLong    = "Username must be at most 30 characters long"
-	ErrEmptyEmail         = "Email can't be empty"
-	ErrEmptyPassword      = "Password can't be empty"
-	ErrInvalidSortOptions = "Sort options are invalid"
+	ErrEmptyTitle        = "Title can't be empty!"
+	ErrEmptyLanguage     = "Language can't be empty!"
+	ErrEmptyContent      = "Content can't be empty!"
+	ErrPasswordTooShort  = "Password must be at least 8 characters long"
+	ErrPasswordTooLong   = "Password must be at most 20 characters long"
+	ErrInvalidPassword   = "Password must contain at least one %s"
+	ErrEmptyUsername     = "Username can't be empty"
+	ErrUsernameTooShort  = "Username must be at least 3 characters long"
+	ErrUsernameTooLong   = "Username must be at most 30 characters long"
+	ErrEmptyEmail        = "Email can't be empty"
+	ErrEmptyPassword     = "Password can't be empty"
+	ErrInvalidTag        = "Tags must be 1-32 characters of lowercase letters, digits, '.', '_' or '-'"
+	ErrTooManyTags       = "A snippet can have at most %d tags"
+	ErrInvalidVisibility = "Visibility must be private, unlisted or public"
+	ErrInvalidExpiry     = "Expiry must be in the future"
+	ErrEmptyOrgName      = "Organization name can't be empty"
+	ErrOrgNameTooLong    = "Organization name must be at most 100 characters long"
+	ErrInvalidRole       = "Role must be owner, editor or viewer"
+	ErrEmptyTokenName    = "Token name can't be empty"
+	ErrTokenNameTooLong  = "Token name must be at most 100 characters long"
+	ErrEmptyScopes       = "A token needs at least one scope"
+	ErrInvalidScope      = "Unknown scope %q, use snippets:read or snippets:write"
 )
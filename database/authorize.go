@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is wrapped by every "doesn't exist" error of the stores, so
+	// callers that don't care what was missing can match on it alone.
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden means the user may not do what they asked with something
+	// that exists.
+	ErrForbidden       = errors.New("forbidden")
+	ErrSnippetNotFound = fmt.Errorf("snippet %w", ErrNotFound)
+)
+
+// Permission is what a user wants to do with a snippet or organization.
+type Permission int
+
+const (
+	// PermissionRead allows viewing a snippet and its history.
+	PermissionRead Permission = iota
+	// PermissionWrite allows creating, editing, sharing and deleting snippets.
+	PermissionWrite
+	// PermissionManage allows inviting and removing organization members.
+	PermissionManage
+)
+
+// authorize is the single place that decides whether userID may act on
+// something owned either personally by ownerID or, when orgID is set, by an
+// organization in which userID has role. role is empty for non-members.
+//
+// Personal snippets are only accessible to their owner. For organization
+// snippets the member's role decides and the creator has no extra rights.
+func authorize(userID, ownerID uuid.UUID, orgID *uuid.UUID, role string, perm Permission) error {
+	if orgID == nil {
+		if ownerID != userID {
+			return ErrForbidden
+		}
+		return nil
+	}
+	if !roleAllows(role, perm) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func roleAllows(role string, perm Permission) bool {
+	switch role {
+	case models.RoleOwner:
+		return true
+	case models.RoleEditor:
+		return perm <= PermissionWrite
+	case models.RoleViewer:
+		return perm == PermissionRead
+	default:
+		return false
+	}
+}
+
+// Conditions that find a snippet by the ID in $1, outside or in the trash.
+// Everything but the trash only ever sees liveSnippet.
+const (
+	liveSnippet    = "snippet_id = $1 AND deleted_at IS NULL"
+	trashedSnippet = "snippet_id = $1 AND deleted_at IS NOT NULL"
+)
+
+// readableSnippets matches the snippets the user in $1 may read, the same
+// ones authorize lets them read: their personal snippets and those of every
+// organization they are a member of.
+const readableSnippets = `(org_id IS NULL AND user_id = $1) OR org_id IN (
+		SELECT org_id FROM org_members WHERE user_id = $1
+	)`
+
+// snippetAccessColumns selects the creator of a snippet and the role of the
+// user in $2 in its organization, which is what authorize needs to know.
+const snippetAccessColumns = `user_id, COALESCE((
+		SELECT role FROM org_members m
+		WHERE m.org_id = snippets.org_id AND m.user_id = $2
+	), '')`
+
+// authorizeSnippet returns ErrSnippetNotFound if the snippet doesn't exist
+// and ErrForbidden if userID lacks perm on it. Otherwise it returns the user
+// who created the snippet, whose tags it uses.
+func authorizeSnippet(
+	ctx context.Context,
+	q execQueryer,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	perm Permission,
+) (uuid.UUID, error) {
+	return checkSnippet(ctx, q, "", snippetID, userID, perm)
+}
+
+// lockSnippet is authorizeSnippet for changes: the snippet stays locked until
+// tx ends, so it can't be deleted or moved between the check and the change.
+func lockSnippet(
+	ctx context.Context,
+	tx *sql.Tx,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	perm Permission,
+) (uuid.UUID, error) {
+	return checkSnippet(ctx, tx, " FOR UPDATE", snippetID, userID, perm)
+}
+
+func checkSnippet(
+	ctx context.Context,
+	q execQueryer,
+	lock string,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	perm Permission,
+) (uuid.UUID, error) {
+	var ownerID uuid.UUID
+	var orgID *uuid.UUID
+	var role string
+	query := "SELECT org_id, " + snippetAccessColumns + " FROM snippets WHERE " + liveSnippet +
+		lock
+	err := q.QueryRowContext(ctx, query, snippetID, userID).Scan(&orgID, &ownerID, &role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrSnippetNotFound
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := authorize(userID, ownerID, orgID, role, perm); err != nil {
+		return uuid.Nil, err
+	}
+	return ownerID, nil
+}
+
+// loadSnippet reads a snippet and checks userID's access to it in one
+// statement, returning the same errors as authorizeSnippet. lock is either
+// empty or " FOR UPDATE" to keep the snippet locked until the transaction
+// ends.
+func loadSnippet(
+	ctx context.Context,
+	q execQueryer,
+	lock string,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	perm Permission,
+) (models.Snippet, uuid.UUID, error) {
+	return selectSnippet(ctx, q, liveSnippet, lock, snippetID, userID, perm)
+}
+
+// loadTrashedSnippet is loadSnippet for a snippet in the trash, locked until
+// tx ends. Restoring and purging it take write access.
+func loadTrashedSnippet(
+	ctx context.Context,
+	tx *sql.Tx,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+) (models.Snippet, uuid.UUID, error) {
+	return selectSnippet(ctx, tx, trashedSnippet, " FOR UPDATE", snippetID, userID, PermissionWrite)
+}
+
+func selectSnippet(
+	ctx context.Context,
+	q execQueryer,
+	where string,
+	lock string,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	perm Permission,
+) (models.Snippet, uuid.UUID, error) {
+	var ownerID uuid.UUID
+	var role string
+	query := "SELECT " + snippetColumns + ", " + snippetAccessColumns +
+		" FROM snippets WHERE " + where + lock
+	snippet, err := scanSnippet(q.QueryRowContext(ctx, query, snippetID, userID), &ownerID, &role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Snippet{}, uuid.Nil, ErrSnippetNotFound
+	}
+	if err != nil {
+		return models.Snippet{}, uuid.Nil, err
+	}
+	if err := authorize(userID, ownerID, snippet.OrgID, role, perm); err != nil {
+		return models.Snippet{}, uuid.Nil, err
+	}
+	return snippet, ownerID, nil
+}
+
+// authorizeOrg returns ErrOrgNotFound if userID isn't a member of the
+// organization and ErrForbidden if their role doesn't allow perm.
+func authorizeOrg(
+	ctx context.Context,
+	q execQueryer,
+	orgID, userID uuid.UUID,
+	perm Permission,
+) error {
+	var role string
+	err := q.QueryRowContext(
+		ctx,
+		"SELECT role FROM org_members WHERE org_id = $1 AND user_id = $2",
+		orgID,
+		userID,
+	).Scan(&role)
+	if err == sql.ErrNoRows {
+		return ErrOrgNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return authorize(userID, uuid.Nil, &orgID, role, perm)
+}
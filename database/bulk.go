@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrBatchAborted is the result of every operation of an atomic batch
+	// that was rolled back or never ran because another operation failed.
+	ErrBatchAborted = errors.New("batch aborted")
+	// ErrUnknownOperation means a batch item isn't a create, update or delete.
+	ErrUnknownOperation = errors.New("unknown batch operation")
+)
+
+// BulkResult is the outcome of one operation of a batch: the snippet as it
+// was created, updated or deleted, or why the operation failed.
+type BulkResult struct {
+	Snippet models.Snippet
+	Err     error
+}
+
+// BulkSnippets runs ops in order in one transaction, checking access to each
+// snippet as UpdateSnippet and DeleteSnippetByID do. When atomic is set the
+// first failure rolls everything back; otherwise failed operations are undone
+// on their own and the rest is committed. The error is only set when the
+// batch as a whole couldn't run.
+func (s *PostgresStore) BulkSnippets(
+	ctx context.Context,
+	userID uuid.UUID,
+	ops []models.BulkOperation,
+	atomic bool,
+) (_ []BulkResult, err error) {
+	ctx, done := s.write(ctx, "BulkSnippets")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]BulkResult, len(ops))
+	for i, op := range ops {
+		if !atomic {
+			if _, err = tx.ExecContext(ctx, "SAVEPOINT bulk_operation"); err != nil {
+				return nil, err
+			}
+		}
+		results[i].Snippet, results[i].Err = bulkOperation(ctx, tx, userID, op)
+		if results[i].Err != nil && ctx.Err() != nil {
+			return nil, results[i].Err
+		}
+		switch {
+		case results[i].Err != nil && atomic:
+			return abortBatch(results, i), nil
+		case results[i].Err != nil:
+			_, err = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_operation")
+		case !atomic:
+			_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT bulk_operation")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func bulkOperation(
+	ctx context.Context,
+	tx *sql.Tx,
+	userID uuid.UUID,
+	op models.BulkOperation,
+) (models.Snippet, error) {
+	switch op.Op {
+	case models.BulkCreate:
+		return createSnippet(
+			ctx,
+			tx,
+			op.Title,
+			op.Language,
+			op.Content,
+			op.Tags,
+			op.Visibility,
+			op.OrgID,
+			userID,
+		)
+	case models.BulkUpdate:
+		return updateSnippet(
+			ctx,
+			tx,
+			op.Title,
+			op.Language,
+			op.Content,
+			op.Tags,
+			op.Visibility,
+			op.SnippetId,
+			userID,
+			op.Version,
+		)
+	case models.BulkDelete:
+		return deleteSnippet(ctx, tx, op.SnippetId, userID, op.Version)
+	default:
+		return models.Snippet{}, fmt.Errorf("%w %q", ErrUnknownOperation, op.Op)
+	}
+}
+
+// abortBatch marks every result but the failed one as ErrBatchAborted.
+func abortBatch(results []BulkResult, failed int) []BulkResult {
+	for i := range results {
+		if i != failed {
+			results[i] = BulkResult{Err: ErrBatchAborted}
+		}
+	}
+	return results
+}
@@ -0,0 +1,1590 @@
+package database
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MemoryStore is an in-memory SnippetStore, UserStore and OrgStore. It
+// mirrors the authorization and not-found behavior of PostgresStore so the
+// handlers can be exercised without a database.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	users     map[uuid.UUID]memoryUser
+	snippets  map[uuid.UUID]memorySnippet
+	revisions map[uuid.UUID][]models.SnippetRevision
+	shares    map[uuid.UUID]memoryShare
+	orgs      map[uuid.UUID]models.Organization
+	// members maps an organization to its members by user ID.
+	members map[uuid.UUID]map[uuid.UUID]models.OrgMember
+	invites map[uuid.UUID]models.OrgInvite
+	// sessions holds sessions by ID and refreshTokens their tokens by hash.
+	sessions      map[uuid.UUID]memorySession
+	refreshTokens map[string]memoryRefreshToken
+	// accessTokens holds personal access tokens by hash.
+	accessTokens map[string]models.AccessToken
+}
+
+type memoryUser struct {
+	user         models.User
+	passwordHash []byte
+	deletedAt    *time.Time
+}
+
+type memoryShare struct {
+	link      models.ShareLink
+	tokenHash string
+}
+
+type memorySession struct {
+	userID  uuid.UUID
+	revoked bool
+}
+
+type memoryRefreshToken struct {
+	sessionID uuid.UUID
+	expiresAt time.Time
+	used      bool
+}
+
+type memorySnippet struct {
+	snippet models.Snippet
+	userID  uuid.UUID
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:     make(map[uuid.UUID]memoryUser),
+		snippets:  make(map[uuid.UUID]memorySnippet),
+		revisions: make(map[uuid.UUID][]models.SnippetRevision),
+		shares:    make(map[uuid.UUID]memoryShare),
+		orgs:      make(map[uuid.UUID]models.Organization),
+		members:   make(map[uuid.UUID]map[uuid.UUID]models.OrgMember),
+		invites:   make(map[uuid.UUID]models.OrgInvite),
+
+		sessions:      make(map[uuid.UUID]memorySession),
+		refreshTokens: make(map[string]memoryRefreshToken),
+		accessTokens:  make(map[string]models.AccessToken),
+	}
+}
+
+func (s *MemoryStore) GetAllSnippets(
+	_ context.Context,
+	userID uuid.UUID,
+	page helper.Page,
+) (models.SnippetPage, error) {
+	return s.listSnippets(page, func(stored memorySnippet) bool {
+		return s.authorizeStored(stored, userID, PermissionRead) == nil
+	})
+}
+
+func (s *MemoryStore) CreateSnippet(
+	_ context.Context,
+	title string,
+	language string,
+	content string,
+	tags []string,
+	visibility string,
+	orgID *uuid.UUID,
+	userID uuid.UUID,
+) (models.Snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createSnippet(title, language, content, tags, visibility, orgID, userID)
+}
+
+// createSnippet is CreateSnippet for callers that already hold s.mu.
+func (s *MemoryStore) createSnippet(
+	title string,
+	language string,
+	content string,
+	tags []string,
+	visibility string,
+	orgID *uuid.UUID,
+	userID uuid.UUID,
+) (models.Snippet, error) {
+	if _, ok := s.users[userID]; !ok {
+		return models.Snippet{}, fmt.Errorf("user with ID %s not found", userID)
+	}
+	if orgID != nil {
+		if err := s.authorizeOrg(*orgID, userID, PermissionWrite); err != nil {
+			return models.Snippet{}, err
+		}
+		id := *orgID
+		orgID = &id
+	}
+	now := time.Now().UTC()
+	snippet := models.Snippet{
+		SnippetId:  uuid.New(),
+		Title:      title,
+		Language:   language,
+		Content:    content,
+		Tags:       append([]string{}, tags...),
+		Visibility: visibility,
+		OrgID:      orgID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Version:    1,
+	}
+	s.snippets[snippet.SnippetId] = memorySnippet{snippet: snippet, userID: userID}
+	s.addRevision(snippet)
+	return snippet, nil
+}
+
+func (s *MemoryStore) UpdateSnippet(
+	_ context.Context,
+	title string,
+	language string,
+	content string,
+	tags []string,
+	visibility string,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	version int,
+) (models.Snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateSnippet(title, language, content, tags, visibility, snippetID, userID, version)
+}
+
+// updateSnippet is UpdateSnippet for callers that already hold s.mu.
+func (s *MemoryStore) updateSnippet(
+	title string,
+	language string,
+	content string,
+	tags []string,
+	visibility string,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	version int,
+) (models.Snippet, error) {
+	stored, err := s.authorizeSnippet(snippetID, userID, PermissionWrite)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	if version != 0 && version != stored.snippet.Version {
+		return models.Snippet{}, ErrVersionMismatch
+	}
+	if unchanged(stored.snippet, title, language, content, tags, visibility) {
+		return stored.snippet, nil
+	}
+	stored.snippet.Title = title
+	stored.snippet.Language = language
+	stored.snippet.Content = content
+	// nil tags leave the existing tags untouched
+	if tags != nil {
+		stored.snippet.Tags = append([]string{}, tags...)
+	}
+	if visibility != "" {
+		stored.snippet.Visibility = visibility
+	}
+	stored.snippet.UpdatedAt = time.Now().UTC()
+	stored.snippet.Version++
+	s.snippets[snippetID] = stored
+	s.addRevision(stored.snippet)
+	return stored.snippet, nil
+}
+
+func (s *MemoryStore) GetSnippetByID(
+	_ context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+) (models.Snippet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, err := s.authorizeSnippet(snippetID, userID, PermissionRead)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	return stored.snippet, nil
+}
+
+func (s *MemoryStore) DeleteSnippetByID(
+	_ context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	version int,
+) (models.Snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteSnippetByID(snippetID, userID, version)
+}
+
+// deleteSnippetByID is DeleteSnippetByID for callers that already hold s.mu.
+func (s *MemoryStore) deleteSnippetByID(
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	version int,
+) (models.Snippet, error) {
+	stored, err := s.authorizeSnippet(snippetID, userID, PermissionWrite)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	if version != 0 && version != stored.snippet.Version {
+		return models.Snippet{}, ErrVersionMismatch
+	}
+	deletedAt := time.Now().UTC()
+	stored.snippet.DeletedAt = &deletedAt
+	s.snippets[snippetID] = stored
+	return stored.snippet, nil
+}
+
+func (s *MemoryStore) ListTrash(
+	_ context.Context,
+	userID uuid.UUID,
+	page helper.Page,
+) (models.SnippetPage, error) {
+	return s.listPage(trashedSnippets, page, func(stored memorySnippet) bool {
+		_, err := s.authorizeTrashed(stored.snippet.SnippetId, userID)
+		return err == nil
+	})
+}
+
+func (s *MemoryStore) RestoreSnippet(
+	_ context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+) (models.Snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.authorizeTrashed(snippetID, userID)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	stored.snippet.DeletedAt = nil
+	s.snippets[snippetID] = stored
+	return stored.snippet, nil
+}
+
+func (s *MemoryStore) PurgeSnippet(_ context.Context, snippetID, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.authorizeTrashed(snippetID, userID); err != nil {
+		return err
+	}
+	s.deleteSnippet(snippetID)
+	return nil
+}
+
+func (s *MemoryStore) PurgeTrash(_ context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for snippetID, stored := range s.snippets {
+		if stored.snippet.DeletedAt != nil && stored.snippet.DeletedAt.Before(before) {
+			s.deleteSnippet(snippetID)
+			purged++
+		}
+	}
+	referenced := map[uuid.UUID]bool{}
+	for _, stored := range s.snippets {
+		referenced[stored.userID] = true
+	}
+	for userID, user := range s.users {
+		if user.deletedAt == nil || !user.deletedAt.Before(before) {
+			continue
+		}
+		if !referenced[userID] {
+			delete(s.users, userID)
+			continue
+		}
+		placeholder := "deleted-" + userID.String()
+		user.user.UserName, user.user.Email = placeholder, placeholder
+		user.passwordHash = nil
+		s.users[userID] = user
+	}
+	return purged, nil
+}
+
+// BulkSnippets mirrors PostgresStore.BulkSnippets. An atomic batch that
+// fails is rolled back by restoring copies of the snippet maps.
+func (s *MemoryStore) BulkSnippets(
+	_ context.Context,
+	userID uuid.UUID,
+	ops []models.BulkOperation,
+	atomic bool,
+) ([]BulkResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snippets, revisions, shares := maps.Clone(s.snippets), maps.Clone(s.revisions),
+		maps.Clone(s.shares)
+	results := make([]BulkResult, len(ops))
+	for i, op := range ops {
+		results[i].Snippet, results[i].Err = s.bulkOperation(userID, op)
+		if results[i].Err != nil && atomic {
+			s.snippets, s.revisions, s.shares = snippets, revisions, shares
+			return abortBatch(results, i), nil
+		}
+	}
+	return results, nil
+}
+
+func (s *MemoryStore) bulkOperation(
+	userID uuid.UUID,
+	op models.BulkOperation,
+) (models.Snippet, error) {
+	switch op.Op {
+	case models.BulkCreate:
+		return s.createSnippet(
+			op.Title,
+			op.Language,
+			op.Content,
+			op.Tags,
+			op.Visibility,
+			op.OrgID,
+			userID,
+		)
+	case models.BulkUpdate:
+		return s.updateSnippet(
+			op.Title,
+			op.Language,
+			op.Content,
+			op.Tags,
+			op.Visibility,
+			op.SnippetId,
+			userID,
+			op.Version,
+		)
+	case models.BulkDelete:
+		return s.deleteSnippetByID(op.SnippetId, userID, op.Version)
+	default:
+		return models.Snippet{}, fmt.Errorf("%w %q", ErrUnknownOperation, op.Op)
+	}
+}
+
+func (s *MemoryStore) GetSnippetsByLanguage(
+	_ context.Context,
+	language string,
+	userID uuid.UUID,
+	page helper.Page,
+) (models.SnippetPage, error) {
+	return s.listSnippets(page, func(stored memorySnippet) bool {
+		return stored.snippet.Language == language &&
+			s.authorizeStored(stored, userID, PermissionRead) == nil
+	})
+}
+
+func (s *MemoryStore) GetSnippetsSorted(
+	_ context.Context,
+	userID uuid.UUID,
+	page helper.Page,
+) (models.SnippetPage, error) {
+	return s.listSnippets(page, func(stored memorySnippet) bool {
+		return s.authorizeStored(stored, userID, PermissionRead) == nil
+	})
+}
+
+// listSnippets returns the page of the snippets that match, ordered and
+// cut the same way as the keyset queries of PostgresStore.
+func (s *MemoryStore) listSnippets(
+	page helper.Page,
+	match func(memorySnippet) bool,
+) (models.SnippetPage, error) {
+	return s.listPage(liveSnippets, page, match)
+}
+
+// listPage mirrors PostgresStore.queryPage.
+func (s *MemoryStore) listPage(
+	scope string,
+	page helper.Page,
+	match func(memorySnippet) bool,
+) (models.SnippetPage, error) {
+	validSort := helper.IsValidSortField
+	if scope == trashedSnippets {
+		validSort = helper.IsValidTrashSortField
+	}
+	if !validSort(page.SortBy) || !helper.IsValidOrder(page.Order) {
+		return models.SnippetPage{}, fmt.Errorf("invalid sort options")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// compare orders snippets by sort key, then ID, in the requested order.
+	compare := func(a, b helper.Cursor) int {
+		c := cmp.Or(strings.Compare(a.Key, b.Key), bytes.Compare(a.ID[:], b.ID[:]))
+		if page.Order == "desc" {
+			return -c
+		}
+		return c
+	}
+	var cursors []helper.Cursor
+	snippets := map[uuid.UUID]models.Snippet{}
+	for _, stored := range s.snippets {
+		if (stored.snippet.DeletedAt != nil) != (scope == trashedSnippets) || !match(stored) {
+			continue
+		}
+		cursor := page.CursorFor(stored.snippet)
+		if page.After != nil && compare(cursor, *page.After) <= 0 {
+			continue
+		}
+		cursors = append(cursors, cursor)
+		snippets[cursor.ID] = stored.snippet
+	}
+	slices.SortFunc(cursors, compare)
+
+	result := models.SnippetPage{Snippets: []models.Snippet{}}
+	if len(cursors) > page.Limit {
+		cursors = cursors[:page.Limit]
+		result.HasMore = true
+	}
+	for _, cursor := range cursors {
+		result.Snippets = append(result.Snippets, snippets[cursor.ID])
+	}
+	return result, nil
+}
+
+// deleteSnippet removes a snippet and everything that references it, like ON
+// DELETE CASCADE does in Postgres. The caller must hold s.mu.
+func (s *MemoryStore) deleteSnippet(snippetID uuid.UUID) {
+	delete(s.snippets, snippetID)
+	delete(s.revisions, snippetID)
+	for shareID, share := range s.shares {
+		if share.link.SnippetID == snippetID {
+			delete(s.shares, shareID)
+		}
+	}
+}
+
+// authorizeSnippet looks up a snippet outside the trash and checks that
+// userID has perm on it. The caller must hold s.mu.
+func (s *MemoryStore) authorizeSnippet(
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	perm Permission,
+) (memorySnippet, error) {
+	stored, ok := s.snippets[snippetID]
+	if !ok || stored.snippet.DeletedAt != nil {
+		return memorySnippet{}, ErrSnippetNotFound
+	}
+	if err := s.authorizeStored(stored, userID, perm); err != nil {
+		return memorySnippet{}, err
+	}
+	return stored, nil
+}
+
+// authorizeTrashed is authorizeSnippet for a snippet in the trash, which
+// takes write access to restore or purge. The caller must hold s.mu.
+func (s *MemoryStore) authorizeTrashed(snippetID, userID uuid.UUID) (memorySnippet, error) {
+	stored, ok := s.snippets[snippetID]
+	if !ok || stored.snippet.DeletedAt == nil {
+		return memorySnippet{}, ErrSnippetNotFound
+	}
+	if err := s.authorizeStored(stored, userID, PermissionWrite); err != nil {
+		return memorySnippet{}, err
+	}
+	return stored, nil
+}
+
+func (s *MemoryStore) authorizeStored(
+	stored memorySnippet,
+	userID uuid.UUID,
+	perm Permission,
+) error {
+	var role string
+	if orgID := stored.snippet.OrgID; orgID != nil {
+		role = s.members[*orgID][userID].Role
+	}
+	return authorize(userID, stored.userID, stored.snippet.OrgID, role, perm)
+}
+
+// authorizeOrg is the in-memory counterpart of the Postgres authorizeOrg.
+// The caller must hold s.mu.
+func (s *MemoryStore) authorizeOrg(orgID, userID uuid.UUID, perm Permission) error {
+	member, ok := s.members[orgID][userID]
+	if !ok {
+		return ErrOrgNotFound
+	}
+	return authorize(userID, uuid.Nil, &orgID, member.Role, perm)
+}
+
+func (s *MemoryStore) CreateUser(_ context.Context, user models.User) (uuid.UUID, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return uuid.UUID{}, fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.user.UserName == user.UserName {
+			return uuid.UUID{}, ErrUsernameTaken
+		}
+		if existing.user.Email == user.Email {
+			return uuid.UUID{}, ErrEmailTaken
+		}
+	}
+
+	userID := uuid.New()
+	s.users[userID] = memoryUser{
+		user: models.User{
+			UserID:    userID,
+			UserName:  user.UserName,
+			Email:     user.Email,
+			CreatedAt: time.Now().UTC(),
+		},
+		passwordHash: hashedPassword,
+	}
+	return userID, nil
+}
+
+func (s *MemoryStore) CheckUserCredentials(
+	_ context.Context,
+	email, password string,
+) (uuid.UUID, error) {
+	s.mu.RLock()
+	var found *memoryUser
+	for _, existing := range s.users {
+		if existing.user.Email == email && existing.deletedAt == nil {
+			found = &existing
+			break
+		}
+	}
+	s.mu.RUnlock()
+
+	if found == nil {
+		return uuid.UUID{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword(found.passwordHash, []byte(password)); err != nil {
+		return uuid.UUID{}, ErrInvalidCredentials
+	}
+	return found.user.UserID, nil
+}
+
+// DeleteUser mirrors PostgresStore.DeleteUser.
+func (s *MemoryStore) DeleteUser(_ context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.users[userID]
+	if !ok || existing.deletedAt != nil {
+		return uuid.Nil, fmt.Errorf("user with ID %s not found", userID)
+	}
+	now := time.Now().UTC()
+	existing.deletedAt = &now
+	s.users[userID] = existing
+	for _, members := range s.members {
+		delete(members, userID)
+	}
+	for sessionID, session := range s.sessions {
+		if session.userID == userID {
+			s.deleteSession(sessionID)
+		}
+	}
+	for tokenHash, token := range s.accessTokens {
+		if token.UserID == userID {
+			delete(s.accessTokens, tokenHash)
+		}
+	}
+	for snippetID, stored := range s.snippets {
+		if stored.userID == userID && stored.snippet.OrgID == nil &&
+			stored.snippet.DeletedAt == nil {
+			stored.snippet.DeletedAt = &now
+			s.snippets[snippetID] = stored
+		}
+	}
+	return userID, nil
+}
+
+// RestoreUser mirrors PostgresStore.RestoreUser.
+func (s *MemoryStore) RestoreUser(_ context.Context, email, password string) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, existing := range s.users {
+		if existing.user.Email != email || existing.deletedAt == nil {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword(existing.passwordHash, []byte(password)) != nil {
+			return uuid.Nil, ErrInvalidCredentials
+		}
+		for snippetID, stored := range s.snippets {
+			deletedAt := stored.snippet.DeletedAt
+			if stored.userID == userID && stored.snippet.OrgID == nil &&
+				deletedAt != nil && deletedAt.Equal(*existing.deletedAt) {
+				stored.snippet.DeletedAt = nil
+				s.snippets[snippetID] = stored
+			}
+		}
+		existing.deletedAt = nil
+		s.users[userID] = existing
+		return userID, nil
+	}
+	return uuid.Nil, ErrInvalidCredentials
+}
+
+func (s *MemoryStore) ChangePassword(_ context.Context, userID uuid.UUID, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.users[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	existing.passwordHash = hashedPassword
+	s.users[userID] = existing
+	for sessionID, session := range s.sessions {
+		if session.userID == userID {
+			s.revokeSession(sessionID)
+		}
+	}
+	now := time.Now().UTC()
+	for tokenHash, token := range s.accessTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			s.accessTokens[tokenHash] = token
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateSession(
+	_ context.Context,
+	userID uuid.UUID,
+	ttl time.Duration,
+) (models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return models.Session{}, fmt.Errorf("user with ID %s not found", userID)
+	}
+	session := models.Session{SessionID: uuid.New(), UserID: userID}
+	s.sessions[session.SessionID] = memorySession{userID: userID}
+	if err := s.issueRefreshToken(&session, ttl); err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+func (s *MemoryStore) RefreshSession(
+	_ context.Context,
+	refreshToken string,
+	ttl time.Duration,
+) (models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokenHash := helper.HashToken(refreshToken)
+	token, ok := s.refreshTokens[tokenHash]
+	if !ok {
+		return models.Session{}, ErrInvalidRefreshToken
+	}
+	if token.used {
+		s.revokeSession(token.sessionID)
+		return models.Session{}, ErrRefreshTokenReused
+	}
+	stored := s.sessions[token.sessionID]
+	if stored.revoked || !token.expiresAt.After(time.Now()) {
+		return models.Session{}, ErrInvalidRefreshToken
+	}
+
+	token.used = true
+	s.refreshTokens[tokenHash] = token
+	session := models.Session{SessionID: token.sessionID, UserID: stored.userID}
+	if err := s.issueRefreshToken(&session, ttl); err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+func (s *MemoryStore) RevokeSession(_ context.Context, sessionID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokeSession(sessionID)
+	return nil
+}
+
+func (s *MemoryStore) SessionActive(_ context.Context, sessionID uuid.UUID) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[sessionID]
+	return ok && !session.revoked, nil
+}
+
+func (s *MemoryStore) issueRefreshToken(session *models.Session, ttl time.Duration) error {
+	token, err := helper.GenerateToken()
+	if err != nil {
+		return err
+	}
+	session.RefreshToken = token
+	session.ExpiresAt = time.Now().UTC().Add(ttl)
+	s.refreshTokens[helper.HashToken(token)] = memoryRefreshToken{
+		sessionID: session.SessionID,
+		expiresAt: session.ExpiresAt,
+	}
+	return nil
+}
+
+func (s *MemoryStore) revokeSession(sessionID uuid.UUID) {
+	if session, ok := s.sessions[sessionID]; ok {
+		session.revoked = true
+		s.sessions[sessionID] = session
+	}
+}
+
+func (s *MemoryStore) CreateAccessToken(
+	_ context.Context,
+	userID uuid.UUID,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (models.AccessToken, error) {
+	plain, tokenHash, err := newAccessTokenSecret()
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return models.AccessToken{}, fmt.Errorf("user with ID %s not found", userID)
+	}
+	token := models.AccessToken{
+		TokenID:   uuid.New(),
+		UserID:    userID,
+		Name:      name,
+		Scopes:    slices.Clone(scopes),
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	}
+	s.accessTokens[tokenHash] = token
+	token.Token = plain
+	return token, nil
+}
+
+func (s *MemoryStore) ListAccessTokens(
+	_ context.Context,
+	userID uuid.UUID,
+) ([]models.AccessToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := []models.AccessToken{}
+	for _, token := range s.accessTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+func (s *MemoryStore) RotateAccessToken(
+	_ context.Context,
+	tokenID, userID uuid.UUID,
+) (models.AccessToken, error) {
+	plain, newHash, err := newAccessTokenSecret()
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokenHash, token, ok := s.accessToken(tokenID, userID)
+	if !ok {
+		return models.AccessToken{}, ErrAccessTokenNotFound
+	}
+	delete(s.accessTokens, tokenHash)
+	token.LastUsedAt = nil
+	s.accessTokens[newHash] = token
+	token.Token = plain
+	return token, nil
+}
+
+func (s *MemoryStore) RevokeAccessToken(_ context.Context, tokenID, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokenHash, token, ok := s.accessToken(tokenID, userID)
+	if !ok {
+		return ErrAccessTokenNotFound
+	}
+	now := time.Now().UTC()
+	token.RevokedAt = &now
+	s.accessTokens[tokenHash] = token
+	return nil
+}
+
+func (s *MemoryStore) AuthenticateAccessToken(
+	_ context.Context,
+	plain string,
+) (models.AccessToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokenHash := helper.HashToken(plain)
+	token, ok := s.accessTokens[tokenHash]
+	if !ok || token.RevokedAt != nil {
+		return models.AccessToken{}, false, nil
+	}
+	now := time.Now().UTC()
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+		return models.AccessToken{}, false, nil
+	}
+	token.LastUsedAt = &now
+	s.accessTokens[tokenHash] = token
+	return token, true, nil
+}
+
+// accessToken finds a live token of userID by ID.
+func (s *MemoryStore) accessToken(
+	tokenID, userID uuid.UUID,
+) (string, models.AccessToken, bool) {
+	for tokenHash, token := range s.accessTokens {
+		if token.TokenID == tokenID && token.UserID == userID && token.RevokedAt == nil {
+			return tokenHash, token, true
+		}
+	}
+	return "", models.AccessToken{}, false
+}
+
+// deleteSession mirrors ON DELETE CASCADE on refresh_tokens.session_id.
+func (s *MemoryStore) deleteSession(sessionID uuid.UUID) {
+	delete(s.sessions, sessionID)
+	for tokenHash, token := range s.refreshTokens {
+		if token.sessionID == sessionID {
+			delete(s.refreshTokens, tokenHash)
+		}
+	}
+}
+
+func (s *MemoryStore) SearchSnippets(
+	_ context.Context,
+	userID uuid.UUID,
+	terms []helper.SearchTerm,
+	page helper.Page,
+) (models.SnippetSearchPage, error) {
+	if !helper.IsValidSearchSortField(page.SortBy) || !helper.IsValidOrder(page.Order) {
+		return models.SnippetSearchPage{}, fmt.Errorf("invalid sort options")
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := []models.SnippetSearchResult{}
+	for _, stored := range s.snippets {
+		if stored.snippet.DeletedAt != nil ||
+			s.authorizeStored(stored, userID, PermissionRead) != nil {
+			continue
+		}
+		titleSpans := wordSpans(stored.snippet.Title)
+		contentSpans := wordSpans(stored.snippet.Content)
+		titleHits := map[int]bool{}
+		contentHits := map[int]bool{}
+		matched := true
+		for _, term := range terms {
+			inTitle := matchTerm(titleSpans, term, titleHits)
+			inContent := matchTerm(contentSpans, term, contentHits)
+			if !inTitle && !inContent {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		results = append(results, models.SnippetSearchResult{
+			Snippet: stored.snippet,
+			// Title matches weigh more, like setweight 'A' vs 'B' in Postgres.
+			Rank:             float64(len(titleHits)) + 0.4*float64(len(contentHits)),
+			TitleHighlight:   highlight(stored.snippet.Title, titleSpans, titleHits, 0, len(titleSpans)),
+			ContentHighlight: contentFragment(stored.snippet.Content, contentSpans, contentHits),
+		})
+	}
+
+	// compare orders results by rank, then ID, in the requested order.
+	compare := func(aRank float64, aID uuid.UUID, bRank float64, bID uuid.UUID) int {
+		c := cmp.Or(cmp.Compare(aRank, bRank), bytes.Compare(aID[:], bID[:]))
+		if page.Order == "desc" {
+			return -c
+		}
+		return c
+	}
+	if page.After != nil {
+		afterRank := page.After.SortValue().(float64)
+		results = slices.DeleteFunc(results, func(result models.SnippetSearchResult) bool {
+			return compare(result.Rank, result.SnippetId, afterRank, page.After.ID) <= 0
+		})
+	}
+	slices.SortFunc(results, func(a, b models.SnippetSearchResult) int {
+		return compare(a.Rank, a.SnippetId, b.Rank, b.SnippetId)
+	})
+
+	result := models.SnippetSearchPage{Results: results}
+	if len(results) > page.Limit {
+		result.Results = results[:page.Limit]
+		result.HasMore = true
+	}
+	return result, nil
+}
+
+type wordSpan struct {
+	word       string
+	start, end int
+}
+
+// wordSpans splits text into words the same way helper.SearchWords does,
+// keeping their byte offsets so matches can be highlighted.
+func wordSpans(text string) []wordSpan {
+	var spans []wordSpan
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			spans = append(spans, wordSpan{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, wordSpan{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return spans
+}
+
+// matchTerm records the index of every word that is part of a match of term
+// in hits and reports whether there was any match.
+func matchTerm(spans []wordSpan, term helper.SearchTerm, hits map[int]bool) bool {
+	found := false
+	last := len(term.Words) - 1
+	for i := 0; i+last < len(spans); i++ {
+		ok := true
+		for j, word := range term.Words {
+			candidate := spans[i+j].word
+			if j == last && term.Prefix {
+				ok = strings.HasPrefix(candidate, word)
+			} else {
+				ok = candidate == word
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			found = true
+			for j := range term.Words {
+				hits[i+j] = true
+			}
+		}
+	}
+	return found
+}
+
+// highlight returns the text covered by spans[from:to], HTML-escaped, with
+// hit words marked.
+func highlight(text string, spans []wordSpan, hits map[int]bool, from, to int) string {
+	if from >= to {
+		return html.EscapeString(text)
+	}
+	var b strings.Builder
+	pos := spans[from].start
+	if from == 0 {
+		pos = 0
+	}
+	for i := from; i < to; i++ {
+		if !hits[i] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:spans[i].start]))
+		b.WriteString(highlightStart + html.EscapeString(text[spans[i].start:spans[i].end]) + highlightStop)
+		pos = spans[i].end
+	}
+	end := spans[to-1].end
+	if to == len(spans) {
+		end = len(text)
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	return b.String()
+}
+
+// contentFragment returns a short window of content around its first match.
+func contentFragment(content string, spans []wordSpan, hits map[int]bool) string {
+	const before, maxWords = 5, 20
+	first := -1
+	for i := range spans {
+		if hits[i] {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		first = 0
+	}
+	from := max(first-before, 0)
+	to := min(from+maxWords, len(spans))
+	return highlight(content, spans, hits, from, to)
+}
+
+func (s *MemoryStore) GetSnippetsByTags(
+	_ context.Context,
+	userID uuid.UUID,
+	tags []string,
+	matchAll bool,
+	page helper.Page,
+) (models.SnippetPage, error) {
+	required := 1
+	if matchAll {
+		required = len(tags)
+	}
+	return s.listSnippets(page, func(stored memorySnippet) bool {
+		if s.authorizeStored(stored, userID, PermissionRead) != nil {
+			return false
+		}
+		matches := 0
+		for _, tag := range tags {
+			if slices.Contains(stored.snippet.Tags, tag) {
+				matches++
+			}
+		}
+		return matches >= required
+	})
+}
+
+func (s *MemoryStore) ListTags(_ context.Context, userID uuid.UUID) ([]models.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := map[string]int{}
+	for _, stored := range s.snippets {
+		if stored.userID != userID || stored.snippet.DeletedAt != nil {
+			continue
+		}
+		for _, tag := range stored.snippet.Tags {
+			counts[tag]++
+		}
+	}
+	tags := []models.Tag{}
+	for name, count := range counts {
+		tags = append(tags, models.Tag{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+func (s *MemoryStore) RenameTag(
+	_ context.Context,
+	userID uuid.UUID,
+	oldName, newName string,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.tagInUse(userID, oldName) {
+		return ErrTagNotFound
+	}
+	if s.tagInUse(userID, newName) {
+		return ErrTagExists
+	}
+	s.replaceTag(userID, oldName, newName)
+	return nil
+}
+
+func (s *MemoryStore) MergeTags(_ context.Context, userID uuid.UUID, source, target string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.tagInUse(userID, source) {
+		return ErrTagNotFound
+	}
+	s.replaceTag(userID, source, target)
+	return nil
+}
+
+// tagInUse reports whether any of the user's snippets has tag. The caller
+// must hold s.mu.
+func (s *MemoryStore) tagInUse(userID uuid.UUID, tag string) bool {
+	for _, stored := range s.snippets {
+		if stored.userID == userID && slices.Contains(stored.snippet.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceTag swaps from for to on every one of the user's snippets. The
+// caller must hold s.mu.
+func (s *MemoryStore) replaceTag(userID uuid.UUID, from, to string) {
+	for snippetID, stored := range s.snippets {
+		if stored.userID != userID || !slices.Contains(stored.snippet.Tags, from) {
+			continue
+		}
+		tags := []string{to}
+		for _, tag := range stored.snippet.Tags {
+			if tag != from && tag != to {
+				tags = append(tags, tag)
+			}
+		}
+		sort.Strings(tags)
+		stored.snippet.Tags = tags
+		s.snippets[snippetID] = stored
+		s.recordVersion(snippetID)
+	}
+}
+
+// addRevision appends the current state of snippet to its history. The
+// caller must hold s.mu.
+func (s *MemoryStore) addRevision(snippet models.Snippet) {
+	history := s.revisions[snippet.SnippetId]
+	s.revisions[snippet.SnippetId] = append(history, models.SnippetRevision{
+		SnippetId: snippet.SnippetId,
+		Revision:  len(history) + 1,
+		Title:     snippet.Title,
+		Language:  snippet.Language,
+		Content:   snippet.Content,
+		Tags:      append([]string{}, snippet.Tags...),
+		CreatedAt: snippet.UpdatedAt,
+	})
+}
+
+func (s *MemoryStore) ListRevisions(
+	_ context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+) ([]models.SnippetRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.authorizeSnippet(snippetID, userID, PermissionRead); err != nil {
+		return nil, err
+	}
+	return append([]models.SnippetRevision{}, s.revisions[snippetID]...), nil
+}
+
+func (s *MemoryStore) GetRevision(
+	_ context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	revision int,
+) (models.SnippetRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.authorizeSnippet(snippetID, userID, PermissionRead); err != nil {
+		return models.SnippetRevision{}, err
+	}
+	return s.revision(snippetID, revision)
+}
+
+func (s *MemoryStore) RestoreRevision(
+	_ context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	revision int,
+) (models.Snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.authorizeSnippet(snippetID, userID, PermissionWrite)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	old, err := s.revision(snippetID, revision)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	snippet, err := s.updateSnippet(
+		old.Title,
+		old.Language,
+		old.Content,
+		append([]string{}, old.Tags...),
+		"",
+		snippetID,
+		userID,
+		0,
+	)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	if snippet.Version == current.snippet.Version {
+		return s.recordVersion(snippetID), nil
+	}
+	return snippet, nil
+}
+
+// recordVersion mirrors the function of the same name of PostgresStore. The
+// caller must hold s.mu.
+func (s *MemoryStore) recordVersion(snippetID uuid.UUID) models.Snippet {
+	stored := s.snippets[snippetID]
+	stored.snippet.UpdatedAt = time.Now().UTC()
+	stored.snippet.Version++
+	s.snippets[snippetID] = stored
+	s.addRevision(stored.snippet)
+	return stored.snippet
+}
+
+// revision looks up one revision of a snippet. The caller must hold s.mu.
+func (s *MemoryStore) revision(snippetID uuid.UUID, revision int) (models.SnippetRevision, error) {
+	history := s.revisions[snippetID]
+	if revision < 1 || revision > len(history) {
+		return models.SnippetRevision{}, ErrRevisionNotFound
+	}
+	return history[revision-1], nil
+}
+
+func (s *MemoryStore) CreateShareLink(
+	_ context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	expiresAt *time.Time,
+) (models.ShareLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.authorizeSnippet(snippetID, userID, PermissionWrite)
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	if stored.snippet.Visibility == models.VisibilityPrivate {
+		return models.ShareLink{}, ErrSnippetPrivate
+	}
+
+	token, err := helper.GenerateToken()
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	link := models.ShareLink{
+		ShareID:   uuid.New(),
+		SnippetID: snippetID,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	}
+	s.shares[link.ShareID] = memoryShare{link: link, tokenHash: helper.HashToken(token)}
+	link.Token = token
+	return link, nil
+}
+
+func (s *MemoryStore) ListShareLinks(
+	_ context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+) ([]models.ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.authorizeSnippet(snippetID, userID, PermissionWrite); err != nil {
+		return nil, err
+	}
+	links := []models.ShareLink{}
+	for _, share := range s.shares {
+		if share.link.SnippetID == snippetID {
+			links = append(links, share.link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].CreatedAt.Before(links[j].CreatedAt)
+	})
+	return links, nil
+}
+
+func (s *MemoryStore) RevokeShareLink(
+	_ context.Context,
+	snippetID, userID, shareID uuid.UUID,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.authorizeSnippet(snippetID, userID, PermissionWrite); err != nil {
+		return err
+	}
+	share, ok := s.shares[shareID]
+	if !ok || share.link.SnippetID != snippetID || share.link.RevokedAt != nil {
+		return ErrShareLinkNotFound
+	}
+	now := time.Now().UTC()
+	share.link.RevokedAt = &now
+	s.shares[shareID] = share
+	return nil
+}
+
+func (s *MemoryStore) GetSharedSnippet(_ context.Context, token string) (models.Snippet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokenHash := helper.HashToken(token)
+	now := time.Now()
+	for _, share := range s.shares {
+		if share.tokenHash != tokenHash || share.link.RevokedAt != nil {
+			continue
+		}
+		if share.link.ExpiresAt != nil && !share.link.ExpiresAt.After(now) {
+			continue
+		}
+		stored, ok := s.snippets[share.link.SnippetID]
+		if ok && stored.snippet.Visibility != models.VisibilityPrivate &&
+			stored.snippet.DeletedAt == nil {
+			return stored.snippet, nil
+		}
+	}
+	return models.Snippet{}, ErrShareLinkNotFound
+}
+
+func (s *MemoryStore) GetPublicSnippets(
+	_ context.Context,
+	language string,
+	page helper.Page,
+) (models.SnippetPage, error) {
+	return s.listSnippets(page, func(stored memorySnippet) bool {
+		return stored.snippet.Visibility == models.VisibilityPublic &&
+			(language == "" || stored.snippet.Language == language)
+	})
+}
+
+func (s *MemoryStore) CreateOrg(
+	_ context.Context,
+	name string,
+	userID uuid.UUID,
+) (models.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return models.Organization{}, fmt.Errorf("user with ID %s not found", userID)
+	}
+	now := time.Now().UTC()
+	org := models.Organization{OrgID: uuid.New(), Name: name, CreatedAt: now}
+	s.orgs[org.OrgID] = org
+	s.members[org.OrgID] = map[uuid.UUID]models.OrgMember{
+		userID: {
+			UserID:   userID,
+			UserName: user.user.UserName,
+			Role:     models.RoleOwner,
+			JoinedAt: now,
+		},
+	}
+	org.Role = models.RoleOwner
+	return org, nil
+}
+
+func (s *MemoryStore) ListOrgs(_ context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orgs := []models.Organization{}
+	for orgID, members := range s.members {
+		if member, ok := members[userID]; ok {
+			org := s.orgs[orgID]
+			org.Role = member.Role
+			orgs = append(orgs, org)
+		}
+	}
+	sort.Slice(orgs, func(i, j int) bool {
+		return orgs[i].Name < orgs[j].Name
+	})
+	return orgs, nil
+}
+
+func (s *MemoryStore) ListOrgMembers(
+	_ context.Context,
+	orgID, userID uuid.UUID,
+) ([]models.OrgMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.authorizeOrg(orgID, userID, PermissionRead); err != nil {
+		return nil, err
+	}
+	members := []models.OrgMember{}
+	for _, member := range s.members[orgID] {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].JoinedAt.Before(members[j].JoinedAt)
+	})
+	return members, nil
+}
+
+func (s *MemoryStore) RemoveOrgMember(_ context.Context, orgID, userID, memberID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	perm := PermissionManage
+	if memberID == userID {
+		perm = PermissionRead
+	}
+	if err := s.authorizeOrg(orgID, userID, perm); err != nil {
+		return err
+	}
+	member, ok := s.members[orgID][memberID]
+	if !ok {
+		return ErrMemberNotFound
+	}
+	if member.Role == models.RoleOwner {
+		owners := 0
+		for _, other := range s.members[orgID] {
+			if other.Role == models.RoleOwner {
+				owners++
+			}
+		}
+		if owners <= 1 {
+			return ErrLastOwner
+		}
+	}
+	delete(s.members[orgID], memberID)
+	return nil
+}
+
+func (s *MemoryStore) CreateOrgInvite(
+	_ context.Context,
+	orgID uuid.UUID,
+	userID uuid.UUID,
+	email string,
+	role string,
+) (models.OrgInvite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.authorizeOrg(orgID, userID, PermissionManage); err != nil {
+		return models.OrgInvite{}, err
+	}
+	for memberID := range s.members[orgID] {
+		if strings.EqualFold(s.users[memberID].user.Email, email) {
+			return models.OrgInvite{}, ErrAlreadyMember
+		}
+	}
+	for _, invite := range s.invites {
+		if invite.OrgID == orgID && strings.EqualFold(invite.Email, email) {
+			return models.OrgInvite{}, ErrInviteExists
+		}
+	}
+	invite := models.OrgInvite{
+		InviteID:  uuid.New(),
+		OrgID:     orgID,
+		OrgName:   s.orgs[orgID].Name,
+		Email:     email,
+		Role:      role,
+		CreatedAt: time.Now().UTC(),
+	}
+	s.invites[invite.InviteID] = invite
+	return invite, nil
+}
+
+func (s *MemoryStore) ListOrgInvites(
+	_ context.Context,
+	orgID, userID uuid.UUID,
+) ([]models.OrgInvite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.authorizeOrg(orgID, userID, PermissionManage); err != nil {
+		return nil, err
+	}
+	return s.pendingInvites(func(invite models.OrgInvite) bool {
+		return invite.OrgID == orgID
+	}), nil
+}
+
+func (s *MemoryStore) ListUserInvites(
+	_ context.Context,
+	userID uuid.UUID,
+) ([]models.OrgInvite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	email := s.users[userID].user.Email
+	return s.pendingInvites(func(invite models.OrgInvite) bool {
+		return email != "" && strings.EqualFold(invite.Email, email)
+	}), nil
+}
+
+// pendingInvites returns the invites that match, oldest first. Accepted
+// invites are deleted so every stored invite is pending. The caller must
+// hold s.mu.
+func (s *MemoryStore) pendingInvites(match func(models.OrgInvite) bool) []models.OrgInvite {
+	invites := []models.OrgInvite{}
+	for _, invite := range s.invites {
+		if match(invite) {
+			invites = append(invites, invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+	})
+	return invites
+}
+
+func (s *MemoryStore) AcceptOrgInvite(
+	_ context.Context,
+	inviteID uuid.UUID,
+	userID uuid.UUID,
+) (models.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.invites[inviteID]
+	user, found := s.users[userID]
+	if !ok || !found || !strings.EqualFold(invite.Email, user.user.Email) {
+		return models.Organization{}, ErrInviteNotFound
+	}
+	delete(s.invites, inviteID)
+	if _, ok := s.members[invite.OrgID][userID]; ok {
+		return models.Organization{}, ErrAlreadyMember
+	}
+	s.members[invite.OrgID][userID] = models.OrgMember{
+		UserID:   userID,
+		UserName: user.user.UserName,
+		Role:     invite.Role,
+		JoinedAt: time.Now().UTC(),
+	}
+	org := s.orgs[invite.OrgID]
+	org.Role = invite.Role
+	return org, nil
+}
+
+func (s *MemoryStore) GetOrgSnippets(
+	_ context.Context,
+	orgID uuid.UUID,
+	userID uuid.UUID,
+	page helper.Page,
+) (models.SnippetPage, error) {
+	s.mu.RLock()
+	err := s.authorizeOrg(orgID, userID, PermissionRead)
+	s.mu.RUnlock()
+	if err != nil {
+		return models.SnippetPage{}, err
+	}
+	return s.listSnippets(page, func(stored memorySnippet) bool {
+		return stored.snippet.OrgID != nil && *stored.snippet.OrgID == orgID
+	})
+}
@@ -0,0 +1,404 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/database"
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+func TestMemoryStoreOwnership(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	owner, err := store.CreateUser(ctx, models.User{
+		UserName: "owner",
+		Email:    "owner@test.com",
+		Password: "Password@123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := store.CreateUser(ctx, models.User{
+		UserName: "other",
+		Email:    "other@test.com",
+		Password: "Password@123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snippet, err := store.CreateSnippet(ctx, "title", "Go", "content", nil, "", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.GetSnippetByID(ctx, snippet.SnippetId, other); err != database.ErrForbidden {
+		t.Errorf("expected access denied for another user's snippet")
+	}
+	_, err = store.UpdateSnippet(ctx, "t", "Go", "c", nil, "", snippet.SnippetId, other, 0)
+	if !errors.Is(err, database.ErrForbidden) {
+		t.Errorf("got %v want access denied when updating another user's snippet", err)
+	}
+	if _, err := store.DeleteSnippetByID(ctx, snippet.SnippetId, other, 0); err == nil {
+		t.Errorf("expected access denied when deleting another user's snippet")
+	}
+	_, err = store.GetSnippetByID(ctx, uuid.New(), owner)
+	if !errors.Is(err, database.ErrSnippetNotFound) || !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("got %v want ErrSnippetNotFound for a missing snippet", err)
+	}
+
+	updated, err := store.UpdateSnippet(ctx, "t", "Go", "c", nil, "", snippet.SnippetId, owner, 1)
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("got version %d, %v want 2", updated.Version, err)
+	}
+	_, err = store.UpdateSnippet(ctx, "t", "Go", "c", nil, "", snippet.SnippetId, owner, 1)
+	if err != database.ErrVersionMismatch {
+		t.Errorf("got %v want ErrVersionMismatch when updating a stale version", err)
+	}
+	_, err = store.DeleteSnippetByID(ctx, snippet.SnippetId, owner, 1)
+	if err != database.ErrVersionMismatch {
+		t.Errorf("got %v want ErrVersionMismatch when deleting a stale version", err)
+	}
+
+	if _, err := store.DeleteUser(ctx, owner); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetSnippetByID(ctx, snippet.SnippetId, owner); err != database.ErrSnippetNotFound {
+		t.Errorf("got %v want snippets removed along with their owner", err)
+	}
+}
+
+func TestMemoryStoreOrgRoles(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	users := map[string]uuid.UUID{}
+	for _, name := range []string{"owner", "editor", "viewer", "outsider"} {
+		userID, err := store.CreateUser(ctx, models.User{
+			UserName: name,
+			Email:    name + "@test.com",
+			Password: "Password@123",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = userID
+	}
+
+	org, err := store.CreateOrg(ctx, "platform", users["owner"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles := map[string]string{"editor": models.RoleEditor, "viewer": models.RoleViewer}
+	for name, role := range roles {
+		invite, err := store.CreateOrgInvite(ctx, org.OrgID, users["owner"], name+"@test.com", role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.AcceptOrgInvite(ctx, invite.InviteID, users["outsider"]); err == nil {
+			t.Errorf("%s's invite was accepted by someone else", name)
+		}
+		if _, err := store.AcceptOrgInvite(ctx, invite.InviteID, users[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	orgID := org.OrgID
+	_, err = store.CreateSnippet(ctx, "t", "Go", "c", nil, "", &orgID, users["viewer"])
+	if !errors.Is(err, database.ErrForbidden) {
+		t.Errorf("viewer created an org snippet: %v", err)
+	}
+	snippet, err := store.CreateSnippet(
+		ctx,
+		"t",
+		"Go",
+		"c",
+		[]string{"infra"},
+		"",
+		&orgID,
+		users["editor"],
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		user          string
+		read, write   bool
+		manageMembers bool
+	}{
+		{"owner", true, true, true},
+		{"editor", true, true, false},
+		{"viewer", true, false, false},
+		{"outsider", false, false, false},
+	} {
+		userID := users[tc.user]
+		_, err := store.GetSnippetByID(ctx, snippet.SnippetId, userID)
+		if (err == nil) != tc.read {
+			t.Errorf("%s read: got %v", tc.user, err)
+		}
+		_, err = store.UpdateSnippet(ctx, "t", "Go", "c", nil, "", snippet.SnippetId, userID, 0)
+		if (err == nil) != tc.write {
+			t.Errorf("%s write: got %v", tc.user, err)
+		}
+		_, err = store.ListOrgInvites(ctx, orgID, userID)
+		if (err == nil) != tc.manageMembers {
+			t.Errorf("%s manage members: got %v", tc.user, err)
+		}
+	}
+
+	err = store.RemoveOrgMember(ctx, orgID, users["owner"], users["owner"])
+	if err != database.ErrLastOwner {
+		t.Errorf("got %v want the last owner kept", err)
+	}
+	err = store.RemoveOrgMember(ctx, orgID, users["viewer"], users["editor"])
+	if err != database.ErrForbidden {
+		t.Errorf("got %v want viewers unable to remove members", err)
+	}
+	if err := store.RemoveOrgMember(ctx, orgID, users["owner"], users["editor"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetSnippetByID(ctx, snippet.SnippetId, users["owner"]); err != nil {
+		t.Errorf("org snippet should outlive its creator's membership: %v", err)
+	}
+	if _, err := store.GetSnippetByID(ctx, snippet.SnippetId, users["editor"]); err == nil {
+		t.Errorf("removed member can still read org snippets")
+	}
+
+	// Every listing shows the org snippet to members and hides it from the
+	// member who created it once they are removed.
+	page := helper.Page{SortBy: "created_at", Order: "asc", Limit: 10}
+	listings := map[string]func(uuid.UUID) (models.SnippetPage, error){
+		"all": func(userID uuid.UUID) (models.SnippetPage, error) {
+			return store.GetAllSnippets(ctx, userID, page)
+		},
+		"language": func(userID uuid.UUID) (models.SnippetPage, error) {
+			return store.GetSnippetsByLanguage(ctx, "Go", userID, page)
+		},
+		"sorted": func(userID uuid.UUID) (models.SnippetPage, error) {
+			return store.GetSnippetsSorted(ctx, userID, page)
+		},
+		"tags": func(userID uuid.UUID) (models.SnippetPage, error) {
+			return store.GetSnippetsByTags(ctx, userID, []string{"infra"}, true, page)
+		},
+	}
+	for name, list := range listings {
+		for user, want := range map[string]int{"owner": 1, "viewer": 1, "editor": 0} {
+			result, err := list(users[user])
+			if err != nil || len(result.Snippets) != want {
+				t.Errorf("%s listing for %s: got %d snippets, %v want %d",
+					name, user, len(result.Snippets), err, want)
+			}
+		}
+	}
+	terms := []helper.SearchTerm{{Words: []string{"t"}}}
+	for user, want := range map[string]int{"owner": 1, "viewer": 1, "editor": 0} {
+		searchPage := helper.Page{SortBy: "rank", Order: "desc", Limit: 10}
+		result, err := store.SearchSnippets(ctx, users[user], terms, searchPage)
+		if err != nil || len(result.Results) != want {
+			t.Errorf("search for %s: got %d results, %v want %d",
+				user, len(result.Results), err, want)
+		}
+	}
+}
+
+// trashPage asks for the whole trash, most recently deleted first.
+var trashPage = helper.Page{SortBy: "deleted_at", Order: "desc", Limit: 100}
+
+func TestMemoryStoreTrash(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	owner, err := store.CreateUser(ctx, models.User{
+		UserName: "owner",
+		Email:    "owner@test.com",
+		Password: "Password@123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snippet, err := store.CreateSnippet(ctx, "t", "Go", "c", []string{"kept"}, "", nil, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.RestoreSnippet(ctx, snippet.SnippetId, owner)
+	if err != database.ErrSnippetNotFound {
+		t.Errorf("got %v want live snippets missing from the trash", err)
+	}
+	deleted, err := store.DeleteSnippetByID(ctx, snippet.SnippetId, owner, 0)
+	if err != nil || deleted.DeletedAt == nil {
+		t.Fatalf("got deleted_at %v, %v want it set", deleted.DeletedAt, err)
+	}
+	_, err = store.GetSnippetByID(ctx, snippet.SnippetId, owner)
+	if err != database.ErrSnippetNotFound {
+		t.Errorf("got %v want trashed snippets hidden", err)
+	}
+	if _, err := store.DeleteSnippetByID(ctx, snippet.SnippetId, owner, 0); err == nil {
+		t.Errorf("trashed snippet was deleted again")
+	}
+	trash, err := store.ListTrash(ctx, owner, trashPage)
+	if err != nil || len(trash.Snippets) != 1 {
+		t.Fatalf("got %d trashed snippets, %v want 1", len(trash.Snippets), err)
+	}
+
+	restored, err := store.RestoreSnippet(ctx, snippet.SnippetId, owner)
+	if err != nil || restored.DeletedAt != nil || len(restored.Tags) != 1 {
+		t.Fatalf("got %+v, %v want the snippet back with its tags", restored, err)
+	}
+	store.DeleteSnippetByID(ctx, snippet.SnippetId, owner, 0)
+
+	purged, err := store.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+	if err != nil || purged != 0 {
+		t.Errorf("got %d purged, %v want recently trashed snippets kept", purged, err)
+	}
+	purged, err = store.PurgeTrash(ctx, time.Now().Add(time.Second))
+	if err != nil || purged != 1 {
+		t.Errorf("got %d purged, %v want 1", purged, err)
+	}
+	err = store.PurgeSnippet(ctx, snippet.SnippetId, owner)
+	if err != database.ErrSnippetNotFound {
+		t.Errorf("got %v want purged snippets gone", err)
+	}
+	if tags, _ := store.ListTags(ctx, owner); len(tags) != 0 {
+		t.Errorf("got tags %v want those of purged snippets pruned", tags)
+	}
+}
+
+func TestMemoryStoreDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	users := map[string]uuid.UUID{}
+	for _, name := range []string{"leaver", "owner"} {
+		userID, err := store.CreateUser(ctx, models.User{
+			UserName: name,
+			Email:    name + "@test.com",
+			Password: "Password@123",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = userID
+	}
+	leaver := users["leaver"]
+	org, err := store.CreateOrg(ctx, "platform", users["owner"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	invite, err := store.CreateOrgInvite(
+		ctx,
+		org.OrgID,
+		users["owner"],
+		"leaver@test.com",
+		models.RoleEditor,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AcceptOrgInvite(ctx, invite.InviteID, leaver); err != nil {
+		t.Fatal(err)
+	}
+	earlier, err := store.CreateSnippet(ctx, "old", "Go", "c", nil, "", nil, leaver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.DeleteSnippetByID(ctx, earlier.SnippetId, leaver, 0); err != nil {
+		t.Fatal(err)
+	}
+	personal, err := store.CreateSnippet(ctx, "mine", "Go", "c", nil, "", nil, leaver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orgID := org.OrgID
+	shared, err := store.CreateSnippet(ctx, "ours", "Go", "c", nil, "", &orgID, leaver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.DeleteUser(ctx, leaver); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CheckUserCredentials(ctx, "leaver@test.com", "Password@123"); err == nil {
+		t.Errorf("deleted user can still log in")
+	}
+	trash, err := store.ListTrash(ctx, leaver, trashPage)
+	if err != nil || len(trash.Snippets) != 2 || trash.Snippets[0].SnippetId != personal.SnippetId {
+		t.Errorf("got trash %v, %v want the personal snippet in it", trash, err)
+	}
+	if _, err := store.GetSnippetByID(ctx, shared.SnippetId, users["owner"]); err != nil {
+		t.Errorf("got %v want the organization to keep its snippet", err)
+	}
+
+	_, err = store.CreateUser(ctx, models.User{
+		UserName: "returner",
+		Email:    "leaver@test.com",
+		Password: "Password@123",
+	})
+	if !errors.Is(err, database.ErrEmailTaken) {
+		t.Errorf("got %v want the email of deleted users kept until purge", err)
+	}
+
+	// Until the trash is purged the account comes back with what it trashed.
+	if _, err := store.RestoreUser(ctx, "leaver@test.com", "wrong"); err == nil {
+		t.Errorf("restored an account with the wrong password")
+	}
+	if _, err := store.RestoreUser(ctx, "leaver@test.com", "Password@123"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CheckUserCredentials(ctx, "leaver@test.com", "Password@123"); err != nil {
+		t.Errorf("got %v want restored users able to log in", err)
+	}
+	if _, err := store.GetSnippetByID(ctx, personal.SnippetId, leaver); err != nil {
+		t.Errorf("got %v want the personal snippet restored", err)
+	}
+	trash, err = store.ListTrash(ctx, leaver, trashPage)
+	if err != nil || len(trash.Snippets) != 1 || trash.Snippets[0].SnippetId != earlier.SnippetId {
+		t.Errorf("got trash %v, %v want only the snippet trashed earlier", trash, err)
+	}
+
+	if _, err := store.DeleteUser(ctx, leaver); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := store.PurgeTrash(ctx, time.Now().Add(time.Second))
+	if err != nil || purged != 2 {
+		t.Errorf("got %d purged, %v want the personal snippets purged", purged, err)
+	}
+	if _, err := store.RestoreUser(ctx, "leaver@test.com", "Password@123"); err == nil {
+		t.Errorf("restored an account after the trash was purged")
+	}
+	_, err = store.CreateUser(ctx, models.User{
+		UserName: "leaver",
+		Email:    "leaver@test.com",
+		Password: "Password@123",
+	})
+	if err != nil {
+		t.Errorf("got %v want the name and email of purged users free", err)
+	}
+}
+
+func TestMemoryStoreChangePasswordRevokesAccessTokens(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	userID, err := store.CreateUser(ctx, models.User{
+		UserName: "rotator",
+		Email:    "rotator@test.com",
+		Password: "Password@123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := store.CreateAccessToken(ctx, userID, "ci", []string{models.ScopeSnippetsRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.ChangePassword(ctx, userID, "NewPassword@123"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := store.AuthenticateAccessToken(ctx, token.Token); ok || err != nil {
+		t.Errorf("got %v, %v want the token revoked with the old password", ok, err)
+	}
+}
@@ -0,0 +1,308 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the pg_advisory_lock key held while migrating so that
+// two replicas starting at the same time don't both apply migrations.
+const migrationLockID = 727_401_337
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// LoadMigrations returns the embedded migrations ordered by version. Every
+// version must have both an up and a down file and versions must start at 1
+// with no gaps.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(migrationFiles, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf(
+				"migration %d has mismatched names %q and %q",
+				version,
+				m.Name,
+				match[2],
+			)
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf(
+				"migration %d_%s is missing its up or down file",
+				m.Version,
+				m.Name,
+			)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf(
+				"migration versions must be contiguous, expected %d got %d",
+				i+1,
+				m.Version,
+			)
+		}
+	}
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration.
+func MigrateUp(db *sql.DB) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		current, err := currentVersion(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if m.Version <= current {
+				continue
+			}
+			err := runMigration(conn, m.Up, func(tx *sql.Tx) error {
+				_, err := tx.Exec(
+					"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+					m.Version,
+					m.Name,
+				)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown rolls back the latest steps applied migrations.
+func MigrateDown(db *sql.DB, steps int) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		current, err := currentVersion(conn)
+		if err != nil {
+			return err
+		}
+		if current > len(migrations) {
+			return fmt.Errorf(
+				"database is at version %d but only %d migrations are known",
+				current,
+				len(migrations),
+			)
+		}
+		for i := 0; i < steps && current > 0; i++ {
+			m := migrations[current-1]
+			err := runMigration(conn, m.Down, func(tx *sql.Tx) error {
+				_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", m.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+			current--
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// GetMigrationStatus lists every known migration along with when it was
+// applied, if at all.
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := appliedAt[m.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CheckSchema returns an error when the database has pending migrations.
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return err
+	}
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	if latest := len(migrations); current < latest {
+		return fmt.Errorf(
+			"database schema is at version %d, expected %d: run `migrate up`",
+			current,
+			latest,
+		)
+	}
+	return nil
+}
+
+// SchemaVersion returns the latest applied migration, or 0 if the database
+// has never been migrated.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").
+		Scan(&exists)
+	if err != nil || !exists {
+		return 0, err
+	}
+	var version int
+	err = db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").
+		Scan(&version)
+	return version, err
+}
+
+func withMigrationLock(db *sql.DB, fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	// Advisory locks belong to a session, so lock, migrate and unlock on the
+	// same connection.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, migrationsTableQuery); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func runMigration(conn *sql.Conn, query string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(query); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func currentVersion(conn *sql.Conn) (int, error) {
+	var version int
+	err := conn.QueryRowContext(
+		context.Background(),
+		"SELECT COALESCE(MAX(version), 0) FROM schema_migrations",
+	).Scan(&version)
+	return version, err
+}
+
+const migrationsTableQuery = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+)`
+
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(migrationsTableQuery)
+	return err
+}
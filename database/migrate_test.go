@@ -0,0 +1,25 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/Jitesh117/snippet-manager-backend/database"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := database.LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected at least one embedded migration")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d", i, m.Version)
+		}
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("migration %d_%s is missing SQL", m.Version, m.Name)
+		}
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS snippets;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS snippets_search_vector_idx;
+ALTER TABLE snippets DROP COLUMN IF EXISTS search_vector;
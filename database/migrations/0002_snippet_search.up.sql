@@ -0,0 +1,7 @@
+-- Full-text search over title and content, with title matches ranked higher
+ALTER TABLE snippets ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('english', content), 'B')
+) STORED;
+
+CREATE INDEX snippets_search_vector_idx ON snippets USING GIN (search_vector);
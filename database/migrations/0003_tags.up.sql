@@ -0,0 +1,15 @@
+-- Tags are per user and shared by all of that user's snippets
+CREATE TABLE tags (
+    tag_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE snippet_tags (
+    snippet_id UUID NOT NULL REFERENCES snippets(snippet_id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (snippet_id, tag_id)
+);
+
+CREATE INDEX snippet_tags_tag_id_idx ON snippet_tags (tag_id);
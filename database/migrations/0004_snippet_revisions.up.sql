@@ -0,0 +1,22 @@
+-- Every version of a snippet, numbered from 1. Rows are never updated.
+CREATE TABLE snippet_revisions (
+    snippet_id UUID NOT NULL REFERENCES snippets(snippet_id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    language TEXT NOT NULL,
+    content TEXT NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    PRIMARY KEY (snippet_id, revision)
+);
+
+-- Existing snippets start their history at their current state
+INSERT INTO snippet_revisions (snippet_id, revision, title, language, content, tags, created_at)
+SELECT s.snippet_id, 1, s.title, s.language, s.content,
+    COALESCE((
+        SELECT array_agg(t.name ORDER BY t.name)
+        FROM snippet_tags st JOIN tags t ON t.tag_id = st.tag_id
+        WHERE st.snippet_id = s.snippet_id
+    ), '{}'),
+    COALESCE(s.updated_at, CURRENT_TIMESTAMP)
+FROM snippets s;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS share_links;
+DROP INDEX IF EXISTS snippets_public_idx;
+ALTER TABLE snippets DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,16 @@
+ALTER TABLE snippets ADD COLUMN visibility TEXT NOT NULL DEFAULT 'private'
+    CHECK (visibility IN ('private', 'unlisted', 'public'));
+
+CREATE INDEX snippets_public_idx ON snippets (created_at DESC) WHERE visibility = 'public';
+
+-- Share links for unlisted snippets. Only a hash of the token is stored.
+CREATE TABLE share_links (
+    share_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    snippet_id UUID NOT NULL REFERENCES snippets(snippet_id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX share_links_snippet_id_idx ON share_links (snippet_id);
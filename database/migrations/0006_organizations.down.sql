@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS snippets_org_id_idx;
+ALTER TABLE snippets DROP COLUMN IF EXISTS org_id;
+DROP TABLE IF EXISTS org_invites;
+DROP TABLE IF EXISTS org_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,34 @@
+CREATE TABLE organizations (
+    org_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
+);
+
+CREATE TABLE org_members (
+    org_id UUID NOT NULL REFERENCES organizations(org_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX org_members_user_id_idx ON org_members (user_id);
+
+-- Invites are addressed to an email and accepted by the user who has it.
+CREATE TABLE org_invites (
+    invite_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL REFERENCES organizations(org_id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    accepted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX org_invites_pending_idx ON org_invites (org_id, lower(email))
+    WHERE accepted_at IS NULL;
+
+-- Snippets with an org_id belong to the organization; user_id is then only
+-- the member who created them.
+ALTER TABLE snippets ADD COLUMN org_id UUID REFERENCES organizations(org_id) ON DELETE CASCADE;
+
+CREATE INDEX snippets_org_id_idx ON snippets (org_id) WHERE org_id IS NOT NULL;
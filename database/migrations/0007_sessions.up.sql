@@ -0,0 +1,22 @@
+-- A session is one login. Access tokens name their session so revoking it
+-- (logout, password change, refresh token reuse) cuts them off immediately.
+CREATE TABLE sessions (
+    session_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+-- Refresh tokens rotate on every use. All tokens of a session form one
+-- family, and presenting an already used token revokes the whole session.
+CREATE TABLE refresh_tokens (
+    token_hash TEXT PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES sessions(session_id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX refresh_tokens_session_id_idx ON refresh_tokens (session_id);
@@ -0,0 +1,3 @@
+-- Every change to a snippet bumps its version, which clients echo back in
+-- If-Match so concurrent edits can't silently overwrite each other.
+ALTER TABLE snippets ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
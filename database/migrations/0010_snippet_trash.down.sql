@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS snippets_trash_idx;
+ALTER TABLE snippets DROP COLUMN IF EXISTS deleted_at;
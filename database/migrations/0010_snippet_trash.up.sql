@@ -0,0 +1,6 @@
+-- Deleting a snippet moves it to the trash by setting deleted_at, so it can
+-- be restored until it's purged. Trashed snippets are left out everywhere
+-- but the trash.
+ALTER TABLE snippets ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX snippets_trash_idx ON snippets (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS users_deleted_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,8 @@
+-- Deleting an account sets deleted_at and moves its personal snippets to the
+-- trash instead of cascading them away, so the account can be restored with
+-- its snippets until the trash is purged. After that the user is deleted,
+-- or scrubbed of its name, email and password while organizations keep
+-- snippets it created.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX users_deleted_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
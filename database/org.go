@@ -0,0 +1,373 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrOrgNotFound    = fmt.Errorf("organization %w", ErrNotFound)
+	ErrMemberNotFound = fmt.Errorf("member %w", ErrNotFound)
+	ErrAlreadyMember  = errors.New("user is already a member")
+	ErrInviteExists   = errors.New("an invite is already pending for this email")
+	ErrInviteNotFound = fmt.Errorf("invite %w", ErrNotFound)
+	ErrLastOwner      = errors.New("an organization must keep at least one owner")
+)
+
+// CreateOrg creates an organization with userID as its only owner.
+func (s *PostgresStore) CreateOrg(
+	ctx context.Context,
+	name string,
+	userID uuid.UUID,
+) (_ models.Organization, err error) {
+	ctx, done := s.write(ctx, "CreateOrg")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Organization{}, err
+	}
+	defer tx.Rollback()
+
+	org := models.Organization{Name: name, Role: models.RoleOwner}
+	err = tx.QueryRowContext(
+		ctx,
+		"INSERT INTO organizations (name) VALUES ($1) RETURNING org_id, created_at",
+		name,
+	).Scan(&org.OrgID, &org.CreatedAt)
+	if err != nil {
+		return models.Organization{}, err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		"INSERT INTO org_members (org_id, user_id, role) VALUES ($1, $2, $3)",
+		org.OrgID,
+		userID,
+		models.RoleOwner,
+	)
+	if err != nil {
+		return models.Organization{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Organization{}, err
+	}
+	return org, nil
+}
+
+// ListOrgs returns the organizations userID belongs to with their role in each.
+func (s *PostgresStore) ListOrgs(
+	ctx context.Context,
+	userID uuid.UUID,
+) (_ []models.Organization, err error) {
+	ctx, done := s.read(ctx, "ListOrgs")
+	defer done(&err)
+	query := `
+		SELECT o.org_id, o.name, m.role, o.created_at
+		FROM organizations o JOIN org_members m ON m.org_id = o.org_id
+		WHERE m.user_id = $1
+		ORDER BY o.name
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		var org models.Organization
+		if err := rows.Scan(&org.OrgID, &org.Name, &org.Role, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tracing.SetRows(ctx, len(orgs))
+	return orgs, nil
+}
+
+// ListOrgMembers returns the organization's members, longest-standing first.
+func (s *PostgresStore) ListOrgMembers(
+	ctx context.Context,
+	orgID, userID uuid.UUID,
+) (_ []models.OrgMember, err error) {
+	ctx, done := s.read(ctx, "ListOrgMembers")
+	defer done(&err)
+	if err := authorizeOrg(ctx, s.db, orgID, userID, PermissionRead); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT m.user_id, u.username, m.role, m.joined_at
+		FROM org_members m JOIN users u ON u.user_id = m.user_id
+		WHERE m.org_id = $1
+		ORDER BY m.joined_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.OrgMember{}
+	for rows.Next() {
+		var member models.OrgMember
+		err := rows.Scan(&member.UserID, &member.UserName, &member.Role, &member.JoinedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tracing.SetRows(ctx, len(members))
+	return members, nil
+}
+
+// RemoveOrgMember removes memberID from the organization. Owners can remove
+// anyone and every member can remove themselves, but the last owner can't
+// leave.
+func (s *PostgresStore) RemoveOrgMember(
+	ctx context.Context,
+	orgID, userID, memberID uuid.UUID,
+) (err error) {
+	ctx, done := s.write(ctx, "RemoveOrgMember")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	perm := PermissionManage
+	if memberID == userID {
+		perm = PermissionRead
+	}
+	if err = authorizeOrg(ctx, tx, orgID, userID, perm); err != nil {
+		return err
+	}
+
+	// Lock the owner rows so two owners can't remove each other concurrently.
+	var owners int
+	err = tx.QueryRowContext(ctx, `
+		SELECT count(*) FROM (
+			SELECT 1 FROM org_members WHERE org_id = $1 AND role = 'owner' FOR UPDATE
+		) o
+	`, orgID).Scan(&owners)
+	if err != nil {
+		return err
+	}
+	var role string
+	err = tx.QueryRowContext(
+		ctx,
+		"DELETE FROM org_members WHERE org_id = $1 AND user_id = $2 RETURNING role",
+		orgID,
+		memberID,
+	).Scan(&role)
+	if err == sql.ErrNoRows {
+		return ErrMemberNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if role == models.RoleOwner && owners <= 1 {
+		return ErrLastOwner
+	}
+	return tx.Commit()
+}
+
+// CreateOrgInvite invites email to the organization with role.
+func (s *PostgresStore) CreateOrgInvite(
+	ctx context.Context,
+	orgID uuid.UUID,
+	userID uuid.UUID,
+	email string,
+	role string,
+) (_ models.OrgInvite, err error) {
+	ctx, done := s.write(ctx, "CreateOrgInvite")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.OrgInvite{}, err
+	}
+	defer tx.Rollback()
+
+	if err = authorizeOrg(ctx, tx, orgID, userID, PermissionManage); err != nil {
+		return models.OrgInvite{}, err
+	}
+	var member bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM org_members m JOIN users u ON u.user_id = m.user_id
+			WHERE m.org_id = $1 AND lower(u.email) = lower($2)
+		)
+	`, orgID, email).Scan(&member)
+	if err != nil {
+		return models.OrgInvite{}, err
+	}
+	if member {
+		return models.OrgInvite{}, ErrAlreadyMember
+	}
+
+	invite := models.OrgInvite{OrgID: orgID, Email: email, Role: role}
+	query := `
+		INSERT INTO org_invites (org_id, email, role)
+		VALUES ($1, $2, $3)
+		RETURNING invite_id, created_at, (SELECT name FROM organizations WHERE org_id = $1)
+	`
+	err = tx.QueryRowContext(ctx, query, orgID, email, role).
+		Scan(&invite.InviteID, &invite.CreatedAt, &invite.OrgName)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return models.OrgInvite{}, ErrInviteExists
+		}
+		return models.OrgInvite{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.OrgInvite{}, err
+	}
+	return invite, nil
+}
+
+// ListOrgInvites returns the organization's pending invites.
+func (s *PostgresStore) ListOrgInvites(
+	ctx context.Context,
+	orgID, userID uuid.UUID,
+) (_ []models.OrgInvite, err error) {
+	ctx, done := s.read(ctx, "ListOrgInvites")
+	defer done(&err)
+	if err := authorizeOrg(ctx, s.db, orgID, userID, PermissionManage); err != nil {
+		return nil, err
+	}
+	return queryInvites(ctx, s.db, "i.org_id = $1", orgID)
+}
+
+// ListUserInvites returns the pending invites addressed to userID's email.
+func (s *PostgresStore) ListUserInvites(
+	ctx context.Context,
+	userID uuid.UUID,
+) (_ []models.OrgInvite, err error) {
+	ctx, done := s.read(ctx, "ListUserInvites")
+	defer done(&err)
+	return queryInvites(
+		ctx,
+		s.db,
+		"lower(i.email) = (SELECT lower(email) FROM users WHERE user_id = $1)",
+		userID,
+	)
+}
+
+func queryInvites(
+	ctx context.Context,
+	q execQueryer,
+	where string,
+	arg any,
+) ([]models.OrgInvite, error) {
+	query := `
+		SELECT i.invite_id, i.org_id, o.name, i.email, i.role, i.created_at
+		FROM org_invites i JOIN organizations o ON o.org_id = i.org_id
+		WHERE i.accepted_at IS NULL AND ` + where + `
+		ORDER BY i.created_at
+	`
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []models.OrgInvite{}
+	for rows.Next() {
+		var invite models.OrgInvite
+		err := rows.Scan(
+			&invite.InviteID,
+			&invite.OrgID,
+			&invite.OrgName,
+			&invite.Email,
+			&invite.Role,
+			&invite.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tracing.SetRows(ctx, len(invites))
+	return invites, nil
+}
+
+// AcceptOrgInvite makes userID a member with the invited role. Only the user
+// whose email the invite was sent to can accept it.
+func (s *PostgresStore) AcceptOrgInvite(
+	ctx context.Context,
+	inviteID uuid.UUID,
+	userID uuid.UUID,
+) (_ models.Organization, err error) {
+	ctx, done := s.write(ctx, "AcceptOrgInvite")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Organization{}, err
+	}
+	defer tx.Rollback()
+
+	var org models.Organization
+	err = tx.QueryRowContext(ctx, `
+		UPDATE org_invites i SET accepted_at = NOW() AT TIME ZONE 'UTC'
+		FROM organizations o, users u
+		WHERE i.invite_id = $1 AND i.accepted_at IS NULL
+		AND o.org_id = i.org_id
+		AND u.user_id = $2 AND lower(u.email) = lower(i.email)
+		RETURNING o.org_id, o.name, i.role, o.created_at
+	`, inviteID, userID).Scan(&org.OrgID, &org.Name, &org.Role, &org.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.Organization{}, ErrInviteNotFound
+	}
+	if err != nil {
+		return models.Organization{}, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO org_members (org_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (org_id, user_id) DO NOTHING
+	`, org.OrgID, userID, org.Role)
+	if err != nil {
+		return models.Organization{}, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return models.Organization{}, err
+	} else if n == 0 {
+		return models.Organization{}, ErrAlreadyMember
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Organization{}, err
+	}
+	return org, nil
+}
+
+// GetOrgSnippets returns a page of the organization's snippet library.
+func (s *PostgresStore) GetOrgSnippets(
+	ctx context.Context,
+	orgID uuid.UUID,
+	userID uuid.UUID,
+	page helper.Page,
+) (_ models.SnippetPage, err error) {
+	ctx, done := s.read(ctx, "GetOrgSnippets")
+	defer done(&err)
+	if err := authorizeOrg(ctx, s.db, orgID, userID, PermissionRead); err != nil {
+		return models.SnippetPage{}, err
+	}
+	return s.querySnippetPage(ctx, "org_id = $1", []any{orgID}, page)
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
+)
+
+// Scopes of a snippet listing: the live snippets or those in the trash.
+const (
+	liveSnippets    = "deleted_at IS NULL"
+	trashedSnippets = "deleted_at IS NOT NULL"
+)
+
+// pageQuery builds a keyset-paginated listing of the snippets in scope
+// filtered by where, whose placeholders are args. Rows are ordered by
+// page's sort column with snippet_id breaking ties, which is also what the
+// cursor encodes, so pages never skip or repeat rows when snippets share a
+// sort value.
+func pageQuery(scope, where string, args []any, page helper.Page) (string, []any, error) {
+	validSort := helper.IsValidSortField
+	if scope == trashedSnippets {
+		validSort = helper.IsValidTrashSortField
+	}
+	if !validSort(page.SortBy) || !helper.IsValidOrder(page.Order) {
+		return "", nil, fmt.Errorf("invalid sort options")
+	}
+	comparison := ">"
+	if page.Order == "desc" {
+		comparison = "<"
+	}
+
+	query := "SELECT " + snippetColumns + " FROM snippets WHERE " + scope + " AND (" +
+		where + ")"
+	if page.After != nil {
+		args = append(args, page.After.SortValue(), page.After.ID)
+		query += fmt.Sprintf(
+			" AND (%s, snippet_id) %s ($%d, $%d)",
+			page.SortBy,
+			comparison,
+			len(args)-1,
+			len(args),
+		)
+	}
+	// One extra row tells whether there is another page.
+	args = append(args, page.Limit+1)
+	query += fmt.Sprintf(
+		" ORDER BY %s %s, snippet_id %s LIMIT $%d",
+		page.SortBy,
+		page.Order,
+		page.Order,
+		len(args),
+	)
+	return query, args, nil
+}
+
+func (s *PostgresStore) querySnippetPage(
+	ctx context.Context,
+	where string,
+	args []any,
+	page helper.Page,
+) (models.SnippetPage, error) {
+	return s.queryPage(ctx, liveSnippets, where, args, page)
+}
+
+func (s *PostgresStore) queryPage(
+	ctx context.Context,
+	scope string,
+	where string,
+	args []any,
+	page helper.Page,
+) (models.SnippetPage, error) {
+	query, args, err := pageQuery(scope, where, args, page)
+	if err != nil {
+		return models.SnippetPage{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.SnippetPage{}, err
+	}
+	defer rows.Close()
+	result, err := scanSnippetPage(rows, page.Limit)
+	tracing.SetRows(ctx, len(result.Snippets))
+	return result, err
+}
+
+func scanSnippetPage(rows *sql.Rows, limit int) (models.SnippetPage, error) {
+	result := models.SnippetPage{Snippets: []models.Snippet{}}
+	for rows.Next() {
+		snippet, err := scanSnippet(rows)
+		if err != nil {
+			return models.SnippetPage{}, err
+		}
+		result.Snippets = append(result.Snippets, snippet)
+	}
+	if err := rows.Err(); err != nil {
+		return models.SnippetPage{}, err
+	}
+	if len(result.Snippets) > limit {
+		result.Snippets = result.Snippets[:limit]
+		result.HasMore = true
+	}
+	return result, nil
+}
@@ -1,62 +1,134 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"time"
 
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	"github.com/Jitesh117/snippet-manager-backend/metrics"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
 	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
-func InitDB() {
-	connStr := "host=localhost port=5432 user=postgres password=mysecretpassword dbname=snippet_manager sslmode=disable"
+// PoolConfig tunes the connection pool. Zero values keep the database/sql
+// defaults.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func InitDB(connStr string, pool PoolConfig) {
 	var err error
 	DB, err = sql.Open("postgres", connStr)
 	if err != nil {
 		log.Fatal("Failed to connect to database: ", err)
 	}
+	DB.SetMaxOpenConns(pool.MaxOpenConns)
+	DB.SetMaxIdleConns(pool.MaxIdleConns)
+	DB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	DB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
-	initQuery := `
-    CREATE EXTENSION IF NOT EXISTS "pgcrypto";
-
-    -- Create the users table
-    CREATE TABLE IF NOT EXISTS users (
-        user_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-        username TEXT NOT NULL UNIQUE,
-        email TEXT NOT NULL UNIQUE,
-        password_hash TEXT NOT NULL,
-        created_at TIMESTAMP WITH TIME ZONE DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
-    );
-
-    -- Create the snippets table with a foreign key referencing users
-    CREATE TABLE IF NOT EXISTS snippets (
-        snippet_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-        title TEXT NOT NULL,
-        language TEXT NOT NULL,
-        content TEXT NOT NULL,
-        user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
-        created_at TIMESTAMP WITH TIME ZONE DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
-        updated_at TIMESTAMP WITH TIME ZONE DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')
-    );
-  `
-	_, err = DB.Exec(initQuery)
-	if err != nil {
-		log.Fatal("Failed to create tables: ", err)
-	}
+	metrics.RegisterDB(DB)
 
 	err = DB.Ping()
 	if err != nil {
 		log.Fatal("Failed to ping database: ", err)
 	}
-	log.Println("Connected to database!")
+	slog.Info("connected to database")
+}
+
+// Timeouts bound every store operation on top of whatever deadline the
+// caller's context already has. Reads are lookups and listings, writes are
+// everything that changes data. Zero means no extra deadline.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+// PostgresStore implements SnippetStore and UserStore on top of a Postgres
+// connection pool.
+type PostgresStore struct {
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+func NewPostgresStore(db *sql.DB, timeouts Timeouts) *PostgresStore {
+	return &PostgresStore{db: db, timeouts: timeouts}
+}
+
+func (s *PostgresStore) read(
+	ctx context.Context,
+	operation string,
+) (context.Context, func(*error)) {
+	return withTimeout(ctx, operation, s.timeouts.Read)
+}
+
+func (s *PostgresStore) write(
+	ctx context.Context,
+	operation string,
+) (context.Context, func(*error)) {
+	return withTimeout(ctx, operation, s.timeouts.Write)
+}
+
+// withTimeout derives the context of one operation, which runs in a span of
+// its own. The returned func must be deferred with the operation's error: it
+// releases the deadline, ends the span, records and logs how long the
+// operation took under its name and, when the deadline passed or the caller
+// gave up, reports context.DeadlineExceeded or context.Canceled instead of
+// whatever the driver made of the cancellation.
+func withTimeout(
+	ctx context.Context,
+	operation string,
+	timeout time.Duration,
+) (context.Context, func(*error)) {
+	start := time.Now()
+	ctx, span := tracing.StartQuery(ctx, operation)
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func(err *error) {
+		if *err != nil && ctx.Err() != nil && !errors.Is(*err, ctx.Err()) {
+			*err = fmt.Errorf("%w: %w", ctx.Err(), *err)
+		}
+		cancel()
+		metrics.ObserveQuery(operation, start, *err)
+		tracing.End(span, *err)
+		logging.FromContext(ctx).Debug(
+			"store operation",
+			"operation", operation,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"error", *err,
+		)
+	}
 }
 
 func CloseDB() {
 	if DB != nil {
 		err := DB.Close()
 		if err != nil {
-			log.Println("Failed to close the database connection: ", err)
+			slog.Error("failed to close the database connection", "error", err)
 		}
 	}
 }
+
+// Ready reports whether the store can serve requests: the database answers
+// and has every migration applied.
+func (s *PostgresStore) Ready(ctx context.Context) (err error) {
+	ctx, done := s.read(ctx, "Ready")
+	defer done(&err)
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("database is unreachable: %w", err)
+	}
+	return CheckSchema(ctx, s.db)
+}
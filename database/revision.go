@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrRevisionNotFound = fmt.Errorf("revision %w", ErrNotFound)
+
+// addRevision appends the current state of snippet to its history and
+// returns the new revision number.
+func addRevision(ctx context.Context, q execQueryer, snippet models.Snippet) (int, error) {
+	query := `
+		INSERT INTO snippet_revisions (snippet_id, revision, title, language, content, tags)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5
+		FROM snippet_revisions WHERE snippet_id = $1
+		RETURNING revision
+	`
+	var revision int
+	err := q.QueryRowContext(
+		ctx,
+		query,
+		snippet.SnippetId,
+		snippet.Title,
+		snippet.Language,
+		snippet.Content,
+		pq.Array(append([]string{}, snippet.Tags...)),
+	).Scan(&revision)
+	return revision, err
+}
+
+// recordVersion moves a snippet to a new version and records its current
+// state as a revision, for changes that don't go through updateSnippet and
+// for restores that leave the snippet as it is.
+func recordVersion(ctx context.Context, tx *sql.Tx, snippetID uuid.UUID) (models.Snippet, error) {
+	query := `
+		UPDATE snippets
+		SET updated_at = NOW() AT TIME ZONE 'UTC', version = version + 1
+		WHERE snippet_id = $1
+		RETURNING ` + snippetColumns
+	snippet, err := scanSnippet(tx.QueryRowContext(ctx, query, snippetID))
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	if _, err = addRevision(ctx, tx, snippet); err != nil {
+		return models.Snippet{}, err
+	}
+	return snippet, nil
+}
+
+func (s *PostgresStore) ListRevisions(
+	ctx context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+) (_ []models.SnippetRevision, err error) {
+	ctx, done := s.read(ctx, "ListRevisions")
+	defer done(&err)
+	if _, err := authorizeSnippet(ctx, s.db, snippetID, userID, PermissionRead); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT snippet_id, revision, title, language, content, tags, created_at
+		FROM snippet_revisions
+		WHERE snippet_id = $1
+		ORDER BY revision
+	`
+	rows, err := s.db.QueryContext(ctx, query, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.SnippetRevision{}
+	for rows.Next() {
+		var revision models.SnippetRevision
+		err := rows.Scan(
+			&revision.SnippetId,
+			&revision.Revision,
+			&revision.Title,
+			&revision.Language,
+			&revision.Content,
+			pq.Array(&revision.Tags),
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tracing.SetRows(ctx, len(revisions))
+	return revisions, nil
+}
+
+func (s *PostgresStore) GetRevision(
+	ctx context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	revision int,
+) (_ models.SnippetRevision, err error) {
+	ctx, done := s.read(ctx, "GetRevision")
+	defer done(&err)
+	if _, err := authorizeSnippet(ctx, s.db, snippetID, userID, PermissionRead); err != nil {
+		return models.SnippetRevision{}, err
+	}
+	return getRevision(ctx, s.db, snippetID, revision)
+}
+
+func getRevision(
+	ctx context.Context,
+	q execQueryer,
+	snippetID uuid.UUID,
+	revision int,
+) (models.SnippetRevision, error) {
+	query := `
+		SELECT snippet_id, revision, title, language, content, tags, created_at
+		FROM snippet_revisions
+		WHERE snippet_id = $1 AND revision = $2
+	`
+	var result models.SnippetRevision
+	err := q.QueryRowContext(ctx, query, snippetID, revision).Scan(
+		&result.SnippetId,
+		&result.Revision,
+		&result.Title,
+		&result.Language,
+		&result.Content,
+		pq.Array(&result.Tags),
+		&result.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return models.SnippetRevision{}, ErrRevisionNotFound
+	}
+	if err != nil {
+		return models.SnippetRevision{}, err
+	}
+	return result, nil
+}
+
+// RestoreRevision makes an old revision current again. The restore is itself
+// recorded as a new revision so history is never rewritten.
+func (s *PostgresStore) RestoreRevision(
+	ctx context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	revision int,
+) (_ models.Snippet, err error) {
+	ctx, done := s.write(ctx, "RestoreRevision")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	defer tx.Rollback()
+
+	current, _, err := loadSnippet(ctx, tx, " FOR UPDATE", snippetID, userID, PermissionWrite)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	old, err := getRevision(ctx, tx, snippetID, revision)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	snippet, err := updateSnippet(
+		ctx,
+		tx,
+		old.Title,
+		old.Language,
+		old.Content,
+		append([]string{}, old.Tags...),
+		"",
+		snippetID,
+		userID,
+		0,
+	)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	// Restoring the current state changes nothing but is still recorded.
+	if snippet.Version == current.Version {
+		if snippet, err = recordVersion(ctx, tx, snippetID); err != nil {
+			return models.Snippet{}, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Snippet{}, err
+	}
+	return snippet, nil
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+)
+
+// ts_headline marks matches with these private-use characters, which are
+// taken out of the text beforehand. The headline is HTML-escaped and only
+// then are they turned into highlightStart and highlightStop, so the text of
+// a snippet can never come back as markup.
+const (
+	matchStart = "\ue000"
+	matchStop  = "\ue001"
+)
+
+var markMatches = strings.NewReplacer(matchStart, highlightStart, matchStop, highlightStop)
+
+// headlineHTML turns a headline of ts_headline into HTML.
+func headlineHTML(headline string) string {
+	return markMatches.Replace(html.EscapeString(headline))
+}
+
+// SearchSnippets returns a page of the snippets the user can read that match
+// every term, most relevant first unless page asks otherwise. Pages are
+// keyed by rank with snippet_id breaking ties, like pageQuery.
+func (s *PostgresStore) SearchSnippets(
+	ctx context.Context,
+	userID uuid.UUID,
+	terms []helper.SearchTerm,
+	page helper.Page,
+) (_ models.SnippetSearchPage, err error) {
+	ctx, done := s.read(ctx, "SearchSnippets")
+	defer done(&err)
+	if !helper.IsValidSearchSortField(page.SortBy) || !helper.IsValidOrder(page.Order) {
+		return models.SnippetSearchPage{}, fmt.Errorf("invalid sort options")
+	}
+	args := []any{userID, toTSQuery(terms), matchStart + matchStop}
+	after := ""
+	if page.After != nil {
+		comparison := ">"
+		if page.Order == "desc" {
+			comparison = "<"
+		}
+		args = append(args, page.After.SortValue(), page.After.ID)
+		after = fmt.Sprintf(
+			" AND (ts_rank_cd(search_vector, query), snippet_id) %s ($%d::real, $%d)",
+			comparison,
+			len(args)-1,
+			len(args),
+		)
+	}
+	// One extra row tells whether there is another page.
+	args = append(args, page.Limit+1)
+	query := `
+		SELECT ` + snippetColumns + `,
+			ts_rank_cd(search_vector, query) AS rank,
+			ts_headline('english', translate(title, $3, ''), query,
+				'HighlightAll=true, StartSel=` + matchStart + `, StopSel=` + matchStop + `'),
+			ts_headline('english', translate(content, $3, ''), query,
+				'StartSel=` + matchStart + `, StopSel=` + matchStop + `, MaxFragments=3, MinWords=5, MaxWords=20, FragmentDelimiter=" ... "')
+		FROM snippets, to_tsquery('english', $2) AS query
+		WHERE (` + readableSnippets + `) AND deleted_at IS NULL AND search_vector @@ query` +
+		after + fmt.Sprintf(`
+		ORDER BY rank %s, snippet_id %s
+		LIMIT $%d
+	`, page.Order, page.Order, len(args))
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return models.SnippetSearchPage{}, err
+	}
+	defer rows.Close()
+
+	result := models.SnippetSearchPage{Results: []models.SnippetSearchResult{}}
+	for rows.Next() {
+		var match models.SnippetSearchResult
+		err := rows.Scan(
+			&match.SnippetId,
+			&match.Title,
+			&match.Language,
+			&match.Content,
+			&match.Visibility,
+			&match.OrgID,
+			&match.CreatedAt,
+			&match.UpdatedAt,
+			&match.Version,
+			&match.DeletedAt,
+			pq.Array(&match.Tags),
+			&match.Rank,
+			&match.TitleHighlight,
+			&match.ContentHighlight,
+		)
+		if err != nil {
+			return models.SnippetSearchPage{}, err
+		}
+		match.TitleHighlight = headlineHTML(match.TitleHighlight)
+		match.ContentHighlight = headlineHTML(match.ContentHighlight)
+		result.Results = append(result.Results, match)
+	}
+	if err := rows.Err(); err != nil {
+		return models.SnippetSearchPage{}, err
+	}
+	if len(result.Results) > page.Limit {
+		result.Results = result.Results[:page.Limit]
+		result.HasMore = true
+	}
+	tracing.SetRows(ctx, len(result.Results))
+	return result, nil
+}
+
+// toTSQuery renders parsed search terms as to_tsquery input. Words only
+// contain letters, digits and underscores so they can be quoted as-is.
+func toTSQuery(terms []helper.SearchTerm) string {
+	clauses := make([]string, 0, len(terms))
+	for _, term := range terms {
+		lexemes := make([]string, len(term.Words))
+		for i, word := range term.Words {
+			lexemes[i] = "'" + word + "'"
+		}
+		if term.Prefix {
+			lexemes[len(lexemes)-1] += ":*"
+		}
+		clauses = append(clauses, "("+strings.Join(lexemes, " <-> ")+")")
+	}
+	return strings.Join(clauses, " & ")
+}
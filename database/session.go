@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused means an already rotated refresh token was
+	// presented again, so it has probably been stolen. Its session is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// CreateSession starts a session for userID with a refresh token valid for
+// ttl.
+func (s *PostgresStore) CreateSession(
+	ctx context.Context,
+	userID uuid.UUID,
+	ttl time.Duration,
+) (_ models.Session, err error) {
+	ctx, done := s.write(ctx, "CreateSession")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Session{}, err
+	}
+	defer tx.Rollback()
+
+	session := models.Session{UserID: userID}
+	err = tx.QueryRowContext(
+		ctx,
+		"INSERT INTO sessions (user_id) VALUES ($1) RETURNING session_id",
+		userID,
+	).Scan(&session.SessionID)
+	if err != nil {
+		return models.Session{}, err
+	}
+	if err = issueRefreshToken(ctx, tx, &session, ttl); err != nil {
+		return models.Session{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+// RefreshSession trades refreshToken for a new one in the same session. Each
+// refresh token works once; presenting a used one revokes the session.
+func (s *PostgresStore) RefreshSession(
+	ctx context.Context,
+	refreshToken string,
+	ttl time.Duration,
+) (_ models.Session, err error) {
+	ctx, done := s.write(ctx, "RefreshSession")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Session{}, err
+	}
+	defer tx.Rollback()
+
+	tokenHash := helper.HashToken(refreshToken)
+	var session models.Session
+	var expiresAt time.Time
+	var usedAt, revokedAt *time.Time
+	// Locking the token makes concurrent refreshes with it take turns, so only
+	// the first one succeeds and the others count as reuse.
+	query := `
+		SELECT r.session_id, s.user_id, r.expires_at, r.used_at, s.revoked_at
+		FROM refresh_tokens r JOIN sessions s ON s.session_id = r.session_id
+		WHERE r.token_hash = $1
+		FOR UPDATE OF r
+	`
+	err = tx.QueryRowContext(ctx, query, tokenHash).
+		Scan(&session.SessionID, &session.UserID, &expiresAt, &usedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Session{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	if usedAt != nil {
+		if err = revokeSessions(ctx, tx, "session_id = $1", session.SessionID); err != nil {
+			return models.Session{}, err
+		}
+		if err = tx.Commit(); err != nil {
+			return models.Session{}, err
+		}
+		return models.Session{}, ErrRefreshTokenReused
+	}
+	if revokedAt != nil || !expiresAt.After(time.Now()) {
+		return models.Session{}, ErrInvalidRefreshToken
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE refresh_tokens SET used_at = NOW() WHERE token_hash = $1",
+		tokenHash,
+	)
+	if err != nil {
+		return models.Session{}, err
+	}
+	if err = issueRefreshToken(ctx, tx, &session, ttl); err != nil {
+		return models.Session{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+// RevokeSession ends a session. Revoking an ended session is not an error.
+func (s *PostgresStore) RevokeSession(ctx context.Context, sessionID uuid.UUID) (err error) {
+	ctx, done := s.write(ctx, "RevokeSession")
+	defer done(&err)
+	return revokeSessions(ctx, s.db, "session_id = $1", sessionID)
+}
+
+// SessionActive reports whether access tokens of the session are still good.
+// Sessions of deleted users are gone along with them.
+func (s *PostgresStore) SessionActive(
+	ctx context.Context,
+	sessionID uuid.UUID,
+) (_ bool, err error) {
+	ctx, done := s.read(ctx, "SessionActive")
+	defer done(&err)
+	var active bool
+	err = s.db.QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM sessions WHERE session_id = $1 AND revoked_at IS NULL)",
+		sessionID,
+	).Scan(&active)
+	return active, err
+}
+
+func issueRefreshToken(
+	ctx context.Context,
+	q execQueryer,
+	session *models.Session,
+	ttl time.Duration,
+) error {
+	token, err := helper.GenerateToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().UTC().Add(ttl)
+	_, err = q.ExecContext(
+		ctx,
+		"INSERT INTO refresh_tokens (token_hash, session_id, expires_at) VALUES ($1, $2, $3)",
+		helper.HashToken(token),
+		session.SessionID,
+		expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	session.RefreshToken = token
+	session.ExpiresAt = expiresAt
+	return nil
+}
+
+func revokeSessions(ctx context.Context, q execQueryer, where string, arg any) error {
+	_, err := q.ExecContext(
+		ctx,
+		"UPDATE sessions SET revoked_at = NOW() WHERE revoked_at IS NULL AND "+where,
+		arg,
+	)
+	return err
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrShareLinkNotFound = fmt.Errorf("share link %w", ErrNotFound)
+	ErrSnippetPrivate    = errors.New("private snippets can't be shared")
+)
+
+// CreateShareLink creates a link to an unlisted or public snippet. The
+// returned link is the only place the plain token is ever available.
+func (s *PostgresStore) CreateShareLink(
+	ctx context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	expiresAt *time.Time,
+) (_ models.ShareLink, err error) {
+	ctx, done := s.write(ctx, "CreateShareLink")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	defer tx.Rollback()
+
+	// The lock keeps the snippet from being made private until the link exists.
+	snippet, _, err := loadSnippet(ctx, tx, " FOR UPDATE", snippetID, userID, PermissionWrite)
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	if snippet.Visibility == models.VisibilityPrivate {
+		return models.ShareLink{}, ErrSnippetPrivate
+	}
+
+	token, err := helper.GenerateToken()
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	link := models.ShareLink{SnippetID: snippetID, Token: token, ExpiresAt: expiresAt}
+	query := `
+		INSERT INTO share_links (snippet_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING share_id, created_at
+	`
+	err = tx.QueryRowContext(ctx, query, snippetID, helper.HashToken(token), expiresAt).
+		Scan(&link.ShareID, &link.CreatedAt)
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.ShareLink{}, err
+	}
+	return link, nil
+}
+
+func (s *PostgresStore) ListShareLinks(
+	ctx context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+) (_ []models.ShareLink, err error) {
+	ctx, done := s.read(ctx, "ListShareLinks")
+	defer done(&err)
+	if _, err := authorizeSnippet(ctx, s.db, snippetID, userID, PermissionWrite); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT share_id, snippet_id, created_at, expires_at, revoked_at
+		FROM share_links
+		WHERE snippet_id = $1
+		ORDER BY created_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.ShareLink{}
+	for rows.Next() {
+		var link models.ShareLink
+		err := rows.Scan(
+			&link.ShareID,
+			&link.SnippetID,
+			&link.CreatedAt,
+			&link.ExpiresAt,
+			&link.RevokedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tracing.SetRows(ctx, len(links))
+	return links, nil
+}
+
+func (s *PostgresStore) RevokeShareLink(
+	ctx context.Context,
+	snippetID, userID, shareID uuid.UUID,
+) (err error) {
+	ctx, done := s.write(ctx, "RevokeShareLink")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = lockSnippet(ctx, tx, snippetID, userID, PermissionWrite); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, `
+		UPDATE share_links SET revoked_at = NOW() AT TIME ZONE 'UTC'
+		WHERE share_id = $1 AND snippet_id = $2 AND revoked_at IS NULL
+	`, shareID, snippetID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrShareLinkNotFound
+	}
+	return tx.Commit()
+}
+
+// GetSharedSnippet resolves a share token. Revoked and expired links, and
+// links to snippets that have since been made private, are not found.
+func (s *PostgresStore) GetSharedSnippet(
+	ctx context.Context,
+	token string,
+) (_ models.Snippet, err error) {
+	ctx, done := s.read(ctx, "GetSharedSnippet")
+	defer done(&err)
+	query := `
+		SELECT ` + snippetColumns + `
+		FROM snippets
+		WHERE visibility <> 'private' AND deleted_at IS NULL AND snippet_id = (
+			SELECT snippet_id FROM share_links
+			WHERE token_hash = $1
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+		)
+	`
+	snippet, err := scanSnippet(s.db.QueryRowContext(ctx, query, helper.HashToken(token)))
+	if err == sql.ErrNoRows {
+		return models.Snippet{}, ErrShareLinkNotFound
+	}
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	return snippet, nil
+}
+
+// GetPublicSnippets returns a page of public snippets from every user,
+// optionally only those in one language.
+func (s *PostgresStore) GetPublicSnippets(
+	ctx context.Context,
+	language string,
+	page helper.Page,
+) (_ models.SnippetPage, err error) {
+	ctx, done := s.read(ctx, "GetPublicSnippets")
+	defer done(&err)
+	where := "visibility = 'public' AND ($1 = '' OR language = $1)"
+	return s.querySnippetPage(ctx, where, []any{language}, page)
+}
@@ -1,243 +1,364 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"errors"
+	"slices"
 
-	"github.com/Jitesh117/snippet-manager-backend/constants"
 	"github.com/Jitesh117/snippet-manager-backend/helper"
 	"github.com/Jitesh117/snippet-manager-backend/models"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-func GetAllSnippets() ([]models.Snippet, error) {
-	query := "SELECT snippet_id, title, language, content, created_at, updated_at FROM snippets"
-	rows, err := DB.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// ErrVersionMismatch means a snippet was changed since the caller read the
+// version they based their change on.
+var ErrVersionMismatch = errors.New("snippet version mismatch")
 
-	snippets := []models.Snippet{}
-	for rows.Next() {
-		var tempSnippet models.Snippet
-		if err = rows.Scan(&tempSnippet.SnippetId, &tempSnippet.Title, &tempSnippet.Language, &tempSnippet.Content, &tempSnippet.CreatedAt, &tempSnippet.UpdatedAt); err != nil {
-			return nil, err
-		}
-		snippets = append(snippets, tempSnippet)
-	}
-	return snippets, nil
+// snippetColumns is the select list read by scanSnippet.
+const snippetColumns = "snippet_id, title, language, content, visibility, org_id, created_at, " +
+	"updated_at, version, deleted_at, " + snippetTagsColumn
+
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-func CreateSnippet(
-	title string,
-	language string,
-	content string,
-	userID uuid.UUID,
-) (models.Snippet, error) {
+// scanSnippet reads the snippetColumns of row and then any extra columns
+// into extra.
+func scanSnippet(row rowScanner, extra ...any) (models.Snippet, error) {
 	var snippet models.Snippet
-	query := `
-		INSERT INTO snippets (title, language, content, user_id) 
-		VALUES ($1, $2, $3, $4) 
-		RETURNING snippet_id, title, language, content, created_at, updated_at
-	`
-	err := DB.QueryRow(query, title, language, content, userID).Scan(
+	dest := []any{
 		&snippet.SnippetId,
 		&snippet.Title,
 		&snippet.Language,
 		&snippet.Content,
+		&snippet.Visibility,
+		&snippet.OrgID,
 		&snippet.CreatedAt,
 		&snippet.UpdatedAt,
+		&snippet.Version,
+		&snippet.DeletedAt,
+		pq.Array(&snippet.Tags),
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return snippet, err
+}
+
+// GetAllSnippets lists every snippet userID may read.
+func (s *PostgresStore) GetAllSnippets(
+	ctx context.Context,
+	userID uuid.UUID,
+	page helper.Page,
+) (_ models.SnippetPage, err error) {
+	ctx, done := s.read(ctx, "GetAllSnippets")
+	defer done(&err)
+	return s.querySnippetPage(ctx, readableSnippets, []any{userID}, page)
+}
+
+func (s *PostgresStore) CreateSnippet(
+	ctx context.Context,
+	title string,
+	language string,
+	content string,
+	tags []string,
+	visibility string,
+	orgID *uuid.UUID,
+	userID uuid.UUID,
+) (_ models.Snippet, err error) {
+	ctx, done := s.write(ctx, "CreateSnippet")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	defer tx.Rollback()
+
+	snippet, err := createSnippet(
+		ctx,
+		tx,
+		title,
+		language,
+		content,
+		tags,
+		visibility,
+		orgID,
+		userID,
 	)
 	if err != nil {
 		return models.Snippet{}, err
 	}
+	if err = tx.Commit(); err != nil {
+		return models.Snippet{}, err
+	}
 	return snippet, nil
 }
 
-func UpdateSnippet(
+// createSnippet inserts a snippet inside tx along with its first revision.
+func createSnippet(
+	ctx context.Context,
+	tx *sql.Tx,
 	title string,
 	language string,
 	content string,
-	snippetID uuid.UUID,
+	tags []string,
+	visibility string,
+	orgID *uuid.UUID,
 	userID uuid.UUID,
 ) (models.Snippet, error) {
-	var realUserID uuid.UUID
-	verifyQuery := "SELECT user_id FROM snippets WHERE snippet_id = $1"
-	err := DB.QueryRow(verifyQuery, snippetID).Scan(&realUserID)
-	if err != nil {
-		return models.Snippet{}, err
-	}
-	if realUserID != userID {
-		return models.Snippet{}, fmt.Errorf("access denied")
+	if orgID != nil {
+		if err := authorizeOrg(ctx, tx, *orgID, userID, PermissionWrite); err != nil {
+			return models.Snippet{}, err
+		}
 	}
 	var snippet models.Snippet
 	query := `
-		UPDATE snippets 
-		SET title = $1, language = $2, content = $3, updated_at = NOW() AT TIME ZONE 'UTC'
-		WHERE snippet_id = $4 
-		RETURNING snippet_id, title, language, content, created_at, updated_at
+		INSERT INTO snippets (title, language, content, visibility, org_id, user_id) 
+		VALUES ($1, $2, $3, $4, $5, $6) 
+		RETURNING snippet_id, title, language, content, visibility, org_id, created_at, updated_at,
+			version
 	`
-
-	err = DB.QueryRow(query, title, language, content, snippetID).Scan(
-		&snippet.SnippetId,
-		&snippet.Title,
-		&snippet.Language,
-		&snippet.Content,
-		&snippet.CreatedAt,
-		&snippet.UpdatedAt,
-	)
+	err := tx.QueryRowContext(ctx, query, title, language, content, visibility, orgID, userID).
+		Scan(
+			&snippet.SnippetId,
+			&snippet.Title,
+			&snippet.Language,
+			&snippet.Content,
+			&snippet.Visibility,
+			&snippet.OrgID,
+			&snippet.CreatedAt,
+			&snippet.UpdatedAt,
+			&snippet.Version,
+		)
 	if err != nil {
 		return models.Snippet{}, err
 	}
-
+	if len(tags) > 0 {
+		if err = setSnippetTags(ctx, tx, snippet.SnippetId, userID, tags); err != nil {
+			return models.Snippet{}, err
+		}
+	}
+	snippet.Tags = append([]string{}, tags...)
+	if _, err = addRevision(ctx, tx, snippet); err != nil {
+		return models.Snippet{}, err
+	}
 	return snippet, nil
 }
 
-func GetSnippetByID(snippetID uuid.UUID, userID uuid.UUID) (models.Snippet, error) {
-	var realUserID uuid.UUID
-	verifyQuery := "SELECT user_id FROM snippets WHERE snippet_id = $1"
-	err := DB.QueryRow(verifyQuery, snippetID).Scan(&realUserID)
+func (s *PostgresStore) UpdateSnippet(
+	ctx context.Context,
+	title string,
+	language string,
+	content string,
+	tags []string,
+	visibility string,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	version int,
+) (_ models.Snippet, err error) {
+	ctx, done := s.write(ctx, "UpdateSnippet")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return models.Snippet{}, err
 	}
-	if realUserID != userID {
-		return models.Snippet{}, fmt.Errorf("access denied")
-	}
-	var snippet models.Snippet
-	query := "SELECT snippet_id, title, language, content, created_at, updated_at FROM snippets WHERE snippet_id = $1"
+	defer tx.Rollback()
 
-	err = DB.QueryRow(query, snippetID).Scan(
-		&snippet.SnippetId,
-		&snippet.Title,
-		&snippet.Language,
-		&snippet.Content,
-		&snippet.CreatedAt,
-		&snippet.UpdatedAt,
+	snippet, err := updateSnippet(
+		ctx,
+		tx,
+		title,
+		language,
+		content,
+		tags,
+		visibility,
+		snippetID,
+		userID,
+		version,
 	)
 	if err != nil {
 		return models.Snippet{}, err
 	}
-
+	if err = tx.Commit(); err != nil {
+		return models.Snippet{}, err
+	}
 	return snippet, nil
 }
 
-func DeleteSnippetByID(snippetID uuid.UUID, userID uuid.UUID) (models.Snippet, error) {
-	var realUserID uuid.UUID
-	verifyQuery := "SELECT user_id FROM snippets WHERE snippet_id = $1"
-	err := DB.QueryRow(verifyQuery, snippetID).Scan(&realUserID)
+// updateSnippet applies an authorized update inside tx and records it as a
+// new revision. It fails with ErrVersionMismatch unless version is the
+// current version of the snippet or 0, which skips the check. An update that
+// changes nothing returns the snippet as it is, without a new version.
+func updateSnippet(
+	ctx context.Context,
+	tx *sql.Tx,
+	title string,
+	language string,
+	content string,
+	tags []string,
+	visibility string,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	version int,
+) (models.Snippet, error) {
+	current, ownerID, err := loadSnippet(
+		ctx,
+		tx,
+		" FOR UPDATE",
+		snippetID,
+		userID,
+		PermissionWrite,
+	)
 	if err != nil {
 		return models.Snippet{}, err
 	}
-	if realUserID != userID {
-		return models.Snippet{}, fmt.Errorf("access denied")
+	if version != 0 && version != current.Version {
+		return models.Snippet{}, ErrVersionMismatch
+	}
+	if unchanged(current, title, language, content, tags, visibility) {
+		return current, nil
 	}
 	var snippet models.Snippet
-	selectQuery := `SELECT snippet_id, title, language, content, created_at, updated_at 
-                    FROM snippets 
-                    WHERE snippet_id = $1`
-	err = DB.QueryRow(selectQuery, snippetID).Scan(
+	query := `
+		UPDATE snippets 
+		SET title = $1, language = $2, content = $3,
+			visibility = COALESCE(NULLIF($4, ''), visibility),
+			updated_at = NOW() AT TIME ZONE 'UTC',
+			version = version + 1
+		WHERE snippet_id = $5 
+		RETURNING snippet_id, title, language, content, visibility, org_id, created_at, updated_at,
+			version
+	`
+
+	err = tx.QueryRowContext(ctx, query, title, language, content, visibility, snippetID).Scan(
 		&snippet.SnippetId,
 		&snippet.Title,
 		&snippet.Language,
 		&snippet.Content,
+		&snippet.Visibility,
+		&snippet.OrgID,
 		&snippet.CreatedAt,
 		&snippet.UpdatedAt,
+		&snippet.Version,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return models.Snippet{}, fmt.Errorf("snippet with ID %s not found", snippetID)
-		}
 		return models.Snippet{}, err
 	}
-	deleteQuery := "DELETE FROM snippets where snippet_id = $1"
-	_, err = DB.Exec(deleteQuery, snippetID)
+	// nil tags leave the existing tags untouched
+	if tags != nil {
+		if err = setSnippetTags(ctx, tx, snippetID, ownerID, tags); err != nil {
+			return models.Snippet{}, err
+		}
+	}
+	snippet.Tags, err = snippetTags(ctx, tx, snippetID)
 	if err != nil {
 		return models.Snippet{}, err
 	}
+	if _, err = addRevision(ctx, tx, snippet); err != nil {
+		return models.Snippet{}, err
+	}
 	return snippet, nil
 }
 
-func GetSnippetsByLanguage(language string, userID uuid.UUID) ([]models.Snippet, error) {
-	var snippets []models.Snippet
-	query := `
-    SELECT snippet_id, title, content, language, created_at, updated_at 
-    FROM snippets 
-    WHERE language = $1 AND user_id = $2
-    ORDER BY updated_at DESC
-    `
-	rows, err := DB.Query(query, language, userID)
+// unchanged reports whether updating snippet with the arguments of
+// UpdateSnippet would leave it as it is. tags must be normalized.
+func unchanged(
+	snippet models.Snippet,
+	title, language, content string,
+	tags []string,
+	visibility string,
+) bool {
+	return snippet.Title == title &&
+		snippet.Language == language &&
+		snippet.Content == content &&
+		(tags == nil || slices.Equal(tags, snippet.Tags)) &&
+		(visibility == "" || visibility == snippet.Visibility)
+}
+
+// GetSnippetByID reads the snippet and checks access to it in a single
+// statement.
+func (s *PostgresStore) GetSnippetByID(
+	ctx context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+) (_ models.Snippet, err error) {
+	ctx, done := s.read(ctx, "GetSnippetByID")
+	defer done(&err)
+	snippet, _, err := loadSnippet(ctx, s.db, "", snippetID, userID, PermissionRead)
+	return snippet, err
+}
+
+// DeleteSnippetByID moves the snippet to the trash in one transaction that
+// holds it locked from the access and version checks on. version works as in
+// UpdateSnippet.
+func (s *PostgresStore) DeleteSnippetByID(
+	ctx context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	version int,
+) (_ models.Snippet, err error) {
+	ctx, done := s.write(ctx, "DeleteSnippetByID")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return models.Snippet{}, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	for rows.Next() {
-		var snippet models.Snippet
-		err := rows.Scan(
-			&snippet.SnippetId,
-			&snippet.Title,
-			&snippet.Content,
-			&snippet.Language,
-			&snippet.CreatedAt,
-			&snippet.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		snippets = append(snippets, snippet)
+	snippet, err := deleteSnippet(ctx, tx, snippetID, userID, version)
+	if err != nil {
+		return models.Snippet{}, err
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if err = tx.Commit(); err != nil {
+		return models.Snippet{}, err
 	}
-
-	return snippets, nil
+	return snippet, nil
 }
 
-func GetSnippetsSorted(userID uuid.UUID, sortBy, order string) ([]models.Snippet, error) {
-	if !helper.IsValidSortField(sortBy) || !helper.IsValidOrder(order) {
-		return nil, fmt.Errorf("invalid sort options")
-	}
-
-	query := `
-		SELECT snippet_id, title, content, language, created_at, updated_at 
-		FROM snippets 
-		WHERE user_id = $1
-		ORDER BY %s %s
-	`
-
-	query = fmt.Sprintf(query, sortBy, order)
-
-	rows, err := DB.Query(query, userID)
+// deleteSnippet is DeleteSnippetByID inside tx.
+func deleteSnippet(
+	ctx context.Context,
+	tx *sql.Tx,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+	version int,
+) (models.Snippet, error) {
+	snippet, _, err := loadSnippet(ctx, tx, " FOR UPDATE", snippetID, userID, PermissionWrite)
 	if err != nil {
-		return nil, err
+		return models.Snippet{}, err
 	}
-	defer rows.Close()
-
-	var snippets []models.Snippet
-	for rows.Next() {
-		var snippet models.Snippet
-		err := rows.Scan(
-			&snippet.SnippetId,
-			&snippet.Title,
-			&snippet.Content,
-			&snippet.Language,
-			&snippet.CreatedAt,
-			&snippet.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		snippets = append(snippets, snippet)
+	if version != 0 && version != snippet.Version {
+		return models.Snippet{}, ErrVersionMismatch
 	}
-	if len(snippets) == 0 {
-		return nil, fmt.Errorf(constants.ErrSnippetNotFound)
+	err = tx.QueryRowContext(
+		ctx,
+		"UPDATE snippets SET deleted_at = NOW() WHERE snippet_id = $1 RETURNING deleted_at",
+		snippetID,
+	).Scan(&snippet.DeletedAt)
+	if err != nil {
+		return models.Snippet{}, err
 	}
+	return snippet, nil
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
+func (s *PostgresStore) GetSnippetsByLanguage(
+	ctx context.Context,
+	language string,
+	userID uuid.UUID,
+	page helper.Page,
+) (_ models.SnippetPage, err error) {
+	ctx, done := s.read(ctx, "GetSnippetsByLanguage")
+	defer done(&err)
+	where := "language = $2 AND (" + readableSnippets + ")"
+	return s.querySnippetPage(ctx, where, []any{userID, language}, page)
+}
 
-	return snippets, nil
+func (s *PostgresStore) GetSnippetsSorted(
+	ctx context.Context,
+	userID uuid.UUID,
+	page helper.Page,
+) (_ models.SnippetPage, err error) {
+	ctx, done := s.read(ctx, "GetSnippetsSorted")
+	defer done(&err)
+	return s.querySnippetPage(ctx, readableSnippets, []any{userID}, page)
 }
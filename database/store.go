@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+// SnippetStore is the storage used by the snippet handlers.
+type SnippetStore interface {
+	GetAllSnippets(
+		ctx context.Context,
+		userID uuid.UUID,
+		page helper.Page,
+	) (models.SnippetPage, error)
+	CreateSnippet(
+		ctx context.Context,
+		title, language, content string,
+		tags []string,
+		visibility string,
+		orgID *uuid.UUID,
+		userID uuid.UUID,
+	) (models.Snippet, error)
+	// UpdateSnippet and DeleteSnippetByID fail with ErrVersionMismatch unless
+	// version is the current version of the snippet. Version 0 matches any.
+	UpdateSnippet(
+		ctx context.Context,
+		title, language, content string,
+		tags []string,
+		visibility string,
+		snippetID uuid.UUID,
+		userID uuid.UUID,
+		version int,
+	) (models.Snippet, error)
+	BulkSnippets(
+		ctx context.Context,
+		userID uuid.UUID,
+		ops []models.BulkOperation,
+		atomic bool,
+	) ([]BulkResult, error)
+	GetSnippetByID(
+		ctx context.Context,
+		snippetID uuid.UUID,
+		userID uuid.UUID,
+	) (models.Snippet, error)
+	DeleteSnippetByID(
+		ctx context.Context,
+		snippetID uuid.UUID,
+		userID uuid.UUID,
+		version int,
+	) (models.Snippet, error)
+	// Deleted snippets go to the trash, where they can be restored or purged.
+	ListTrash(ctx context.Context, userID uuid.UUID, page helper.Page) (models.SnippetPage, error)
+	RestoreSnippet(
+		ctx context.Context,
+		snippetID uuid.UUID,
+		userID uuid.UUID,
+	) (models.Snippet, error)
+	PurgeSnippet(ctx context.Context, snippetID, userID uuid.UUID) error
+	GetSnippetsByLanguage(
+		ctx context.Context,
+		language string,
+		userID uuid.UUID,
+		page helper.Page,
+	) (models.SnippetPage, error)
+	GetSnippetsSorted(
+		ctx context.Context,
+		userID uuid.UUID,
+		page helper.Page,
+	) (models.SnippetPage, error)
+	SearchSnippets(
+		ctx context.Context,
+		userID uuid.UUID,
+		terms []helper.SearchTerm,
+		page helper.Page,
+	) (models.SnippetSearchPage, error)
+	GetSnippetsByTags(
+		ctx context.Context,
+		userID uuid.UUID,
+		tags []string,
+		matchAll bool,
+		page helper.Page,
+	) (models.SnippetPage, error)
+	ListTags(ctx context.Context, userID uuid.UUID) ([]models.Tag, error)
+	RenameTag(ctx context.Context, userID uuid.UUID, oldName, newName string) error
+	MergeTags(ctx context.Context, userID uuid.UUID, source, target string) error
+	ListRevisions(
+		ctx context.Context,
+		snippetID uuid.UUID,
+		userID uuid.UUID,
+	) ([]models.SnippetRevision, error)
+	GetRevision(
+		ctx context.Context,
+		snippetID uuid.UUID,
+		userID uuid.UUID,
+		revision int,
+	) (models.SnippetRevision, error)
+	RestoreRevision(
+		ctx context.Context,
+		snippetID uuid.UUID,
+		userID uuid.UUID,
+		revision int,
+	) (models.Snippet, error)
+	CreateShareLink(
+		ctx context.Context,
+		snippetID uuid.UUID,
+		userID uuid.UUID,
+		expiresAt *time.Time,
+	) (models.ShareLink, error)
+	ListShareLinks(
+		ctx context.Context,
+		snippetID uuid.UUID,
+		userID uuid.UUID,
+	) ([]models.ShareLink, error)
+	RevokeShareLink(ctx context.Context, snippetID, userID, shareID uuid.UUID) error
+	GetSharedSnippet(ctx context.Context, token string) (models.Snippet, error)
+	GetPublicSnippets(
+		ctx context.Context,
+		language string,
+		page helper.Page,
+	) (models.SnippetPage, error)
+	GetOrgSnippets(
+		ctx context.Context,
+		orgID, userID uuid.UUID,
+		page helper.Page,
+	) (models.SnippetPage, error)
+}
+
+// OrgStore is the storage used by the organization handlers.
+type OrgStore interface {
+	CreateOrg(ctx context.Context, name string, userID uuid.UUID) (models.Organization, error)
+	ListOrgs(ctx context.Context, userID uuid.UUID) ([]models.Organization, error)
+	ListOrgMembers(ctx context.Context, orgID, userID uuid.UUID) ([]models.OrgMember, error)
+	RemoveOrgMember(ctx context.Context, orgID, userID, memberID uuid.UUID) error
+	CreateOrgInvite(
+		ctx context.Context,
+		orgID uuid.UUID,
+		userID uuid.UUID,
+		email string,
+		role string,
+	) (models.OrgInvite, error)
+	ListOrgInvites(ctx context.Context, orgID, userID uuid.UUID) ([]models.OrgInvite, error)
+	ListUserInvites(ctx context.Context, userID uuid.UUID) ([]models.OrgInvite, error)
+	AcceptOrgInvite(ctx context.Context, inviteID, userID uuid.UUID) (models.Organization, error)
+}
+
+// UserStore is the storage used by the user handlers.
+type UserStore interface {
+	CreateUser(ctx context.Context, user models.User) (uuid.UUID, error)
+	CheckUserCredentials(ctx context.Context, email, password string) (uuid.UUID, error)
+	DeleteUser(ctx context.Context, userID uuid.UUID) (uuid.UUID, error)
+	RestoreUser(ctx context.Context, email, password string) (uuid.UUID, error)
+	ChangePassword(ctx context.Context, userID uuid.UUID, password string) error
+	CreateSession(ctx context.Context, userID uuid.UUID, ttl time.Duration) (models.Session, error)
+	RefreshSession(
+		ctx context.Context,
+		refreshToken string,
+		ttl time.Duration,
+	) (models.Session, error)
+	RevokeSession(ctx context.Context, sessionID uuid.UUID) error
+	SessionActive(ctx context.Context, sessionID uuid.UUID) (bool, error)
+	CreateAccessToken(
+		ctx context.Context,
+		userID uuid.UUID,
+		name string,
+		scopes []string,
+		expiresAt *time.Time,
+	) (models.AccessToken, error)
+	ListAccessTokens(ctx context.Context, userID uuid.UUID) ([]models.AccessToken, error)
+	RotateAccessToken(ctx context.Context, tokenID, userID uuid.UUID) (models.AccessToken, error)
+	RevokeAccessToken(ctx context.Context, tokenID, userID uuid.UUID) error
+	AuthenticateAccessToken(ctx context.Context, token string) (models.AccessToken, bool, error)
+}
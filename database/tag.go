@@ -0,0 +1,274 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTagNotFound = fmt.Errorf("tag %w", ErrNotFound)
+	ErrTagExists   = errors.New("tag already exists")
+)
+
+// snippetTagsColumn selects a snippet's tag names, sorted, as a text array.
+const snippetTagsColumn = `COALESCE((
+		SELECT array_agg(t.name ORDER BY t.name)
+		FROM snippet_tags st JOIN tags t ON t.tag_id = st.tag_id
+		WHERE st.snippet_id = snippets.snippet_id
+	), '{}')`
+
+// execQueryer is satisfied by both *sql.DB and *sql.Tx.
+type execQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// setSnippetTags replaces the tags of a snippet, creating any of the user's
+// tags that don't exist yet.
+func setSnippetTags(
+	ctx context.Context,
+	q execQueryer,
+	snippetID, userID uuid.UUID,
+	tags []string,
+) error {
+	if _, err := q.ExecContext(ctx, "DELETE FROM snippet_tags WHERE snippet_id = $1", snippetID); err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO tags (user_id, name)
+			SELECT $1, unnest($2::text[])
+			ON CONFLICT (user_id, name) DO NOTHING
+		`, userID, pq.Array(tags))
+		if err != nil {
+			return err
+		}
+		_, err = q.ExecContext(ctx, `
+			INSERT INTO snippet_tags (snippet_id, tag_id)
+			SELECT $1, tag_id FROM tags WHERE user_id = $2 AND name = ANY($3)
+		`, snippetID, userID, pq.Array(tags))
+		if err != nil {
+			return err
+		}
+	}
+	return pruneTags(ctx, q, userID)
+}
+
+// pruneTags deletes the user's tags that are no longer on any snippet, so a
+// tag exists exactly as long as something is tagged with it.
+func pruneTags(ctx context.Context, q execQueryer, userID uuid.UUID) error {
+	_, err := q.ExecContext(ctx, `
+		DELETE FROM tags
+		WHERE user_id = $1
+		AND NOT EXISTS (SELECT 1 FROM snippet_tags st WHERE st.tag_id = tags.tag_id)
+	`, userID)
+	return err
+}
+
+func snippetTags(ctx context.Context, q execQueryer, snippetID uuid.UUID) ([]string, error) {
+	query := "SELECT " + snippetTagsColumn + " FROM snippets WHERE snippet_id = $1"
+	tags := []string{}
+	err := q.QueryRowContext(ctx, query, snippetID).Scan(pq.Array(&tags))
+	return tags, err
+}
+
+// GetSnippetsByTags returns the snippets the user can read tagged with every
+// one of tags when matchAll is set, or with at least one of them otherwise.
+func (s *PostgresStore) GetSnippetsByTags(
+	ctx context.Context,
+	userID uuid.UUID,
+	tags []string,
+	matchAll bool,
+	page helper.Page,
+) (_ models.SnippetPage, err error) {
+	ctx, done := s.read(ctx, "GetSnippetsByTags")
+	defer done(&err)
+	required := 1
+	if matchAll {
+		required = len(tags)
+	}
+	where := `(` + readableSnippets + `) AND (
+		SELECT count(*)
+		FROM snippet_tags st JOIN tags t ON t.tag_id = st.tag_id
+		WHERE st.snippet_id = snippets.snippet_id AND t.name = ANY($2)
+	) >= $3`
+	return s.querySnippetPage(ctx, where, []any{userID, pq.Array(tags), required}, page)
+}
+
+func (s *PostgresStore) ListTags(
+	ctx context.Context,
+	userID uuid.UUID,
+) (_ []models.Tag, err error) {
+	ctx, done := s.read(ctx, "ListTags")
+	defer done(&err)
+	query := `
+		SELECT t.name, count(*)
+		FROM tags t
+		JOIN snippet_tags st ON st.tag_id = t.tag_id
+		JOIN snippets s ON s.snippet_id = st.snippet_id AND s.deleted_at IS NULL
+		WHERE t.user_id = $1
+		GROUP BY t.name
+		ORDER BY t.name
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tracing.SetRows(ctx, len(tags))
+	return tags, nil
+}
+
+// taggedSnippets returns the snippets tagged with tagID.
+func taggedSnippets(ctx context.Context, tx *sql.Tx, tagID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT snippet_id FROM snippet_tags WHERE tag_id = $1", tagID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var snippetIDs []uuid.UUID
+	for rows.Next() {
+		var snippetID uuid.UUID
+		if err := rows.Scan(&snippetID); err != nil {
+			return nil, err
+		}
+		snippetIDs = append(snippetIDs, snippetID)
+	}
+	return snippetIDs, rows.Err()
+}
+
+// recordVersions records a new version of every one of snippetIDs, since
+// renaming or merging a tag they have changes how they read.
+func recordVersions(ctx context.Context, tx *sql.Tx, snippetIDs []uuid.UUID) error {
+	for _, snippetID := range snippetIDs {
+		if _, err := recordVersion(ctx, tx, snippetID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenameTag renames one of the user's tags. Renaming onto an existing tag
+// fails with ErrTagExists; use MergeTags for that.
+func (s *PostgresStore) RenameTag(
+	ctx context.Context,
+	userID uuid.UUID,
+	oldName, newName string,
+) (err error) {
+	ctx, done := s.write(ctx, "RenameTag")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var tagID uuid.UUID
+	err = tx.QueryRowContext(
+		ctx,
+		"UPDATE tags SET name = $3 WHERE user_id = $1 AND name = $2 RETURNING tag_id",
+		userID,
+		oldName,
+		newName,
+	).Scan(&tagID)
+	if err == sql.ErrNoRows {
+		return ErrTagNotFound
+	}
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrTagExists
+		}
+		return err
+	}
+	snippetIDs, err := taggedSnippets(ctx, tx, tagID)
+	if err != nil {
+		return err
+	}
+	if err = recordVersions(ctx, tx, snippetIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MergeTags moves every snippet tagged source onto target, creating target
+// if needed, and removes source.
+func (s *PostgresStore) MergeTags(
+	ctx context.Context,
+	userID uuid.UUID,
+	source, target string,
+) (err error) {
+	ctx, done := s.write(ctx, "MergeTags")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var sourceID uuid.UUID
+	err = tx.QueryRowContext(
+		ctx,
+		"SELECT tag_id FROM tags WHERE user_id = $1 AND name = $2",
+		userID,
+		source,
+	).Scan(&sourceID)
+	if err == sql.ErrNoRows {
+		return ErrTagNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var targetID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO tags (user_id, name) VALUES ($1, $2)
+		ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING tag_id
+	`, userID, target).Scan(&targetID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO snippet_tags (snippet_id, tag_id)
+		SELECT snippet_id, $2 FROM snippet_tags WHERE tag_id = $1
+		ON CONFLICT DO NOTHING
+	`, sourceID, targetID)
+	if err != nil {
+		return err
+	}
+	// The revisions are recorded once source is gone, as the snippets now
+	// read.
+	snippetIDs, err := taggedSnippets(ctx, tx, sourceID)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM tags WHERE tag_id = $1", sourceID); err != nil {
+		return err
+	}
+	if err = recordVersions(ctx, tx, snippetIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrAccessTokenNotFound = fmt.Errorf("access token %w", ErrNotFound)
+
+const accessTokenColumns = "token_id, user_id, name, scopes, created_at, expires_at, " +
+	"last_used_at, revoked_at"
+
+func scanAccessToken(row rowScanner) (models.AccessToken, error) {
+	var token models.AccessToken
+	err := row.Scan(
+		&token.TokenID,
+		&token.UserID,
+		&token.Name,
+		pq.Array(&token.Scopes),
+		&token.CreatedAt,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.RevokedAt,
+	)
+	return token, err
+}
+
+// newAccessTokenSecret returns a fresh personal access token and its hash.
+func newAccessTokenSecret() (string, string, error) {
+	secret, err := helper.GenerateToken()
+	if err != nil {
+		return "", "", err
+	}
+	token := models.AccessTokenPrefix + secret
+	return token, helper.HashToken(token), nil
+}
+
+// CreateAccessToken creates a personal access token. The returned token is
+// the only place the plain token is ever available.
+func (s *PostgresStore) CreateAccessToken(
+	ctx context.Context,
+	userID uuid.UUID,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (_ models.AccessToken, err error) {
+	ctx, done := s.write(ctx, "CreateAccessToken")
+	defer done(&err)
+	plain, tokenHash, err := newAccessTokenSecret()
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+	query := `
+		INSERT INTO access_tokens (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + accessTokenColumns
+	token, err := scanAccessToken(
+		s.db.QueryRowContext(ctx, query, userID, name, tokenHash, pq.Array(scopes), expiresAt),
+	)
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+	token.Token = plain
+	return token, nil
+}
+
+func (s *PostgresStore) ListAccessTokens(
+	ctx context.Context,
+	userID uuid.UUID,
+) (_ []models.AccessToken, err error) {
+	ctx, done := s.read(ctx, "ListAccessTokens")
+	defer done(&err)
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+accessTokenColumns+" FROM access_tokens WHERE user_id = $1 ORDER BY created_at",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.AccessToken{}
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	tracing.SetRows(ctx, len(tokens))
+	return tokens, rows.Err()
+}
+
+// RotateAccessToken replaces the secret of a token, keeping its name, scopes
+// and expiry. The old secret stops working immediately.
+func (s *PostgresStore) RotateAccessToken(
+	ctx context.Context,
+	tokenID, userID uuid.UUID,
+) (_ models.AccessToken, err error) {
+	ctx, done := s.write(ctx, "RotateAccessToken")
+	defer done(&err)
+	plain, tokenHash, err := newAccessTokenSecret()
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+	query := `
+		UPDATE access_tokens SET token_hash = $1, last_used_at = NULL
+		WHERE token_id = $2 AND user_id = $3 AND revoked_at IS NULL
+		RETURNING ` + accessTokenColumns
+	token, err := scanAccessToken(s.db.QueryRowContext(ctx, query, tokenHash, tokenID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AccessToken{}, ErrAccessTokenNotFound
+	}
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+	token.Token = plain
+	return token, nil
+}
+
+func (s *PostgresStore) RevokeAccessToken(
+	ctx context.Context,
+	tokenID, userID uuid.UUID,
+) (err error) {
+	ctx, done := s.write(ctx, "RevokeAccessToken")
+	defer done(&err)
+	result, err := s.db.ExecContext(
+		ctx,
+		`UPDATE access_tokens SET revoked_at = NOW()
+		WHERE token_id = $1 AND user_id = $2 AND revoked_at IS NULL`,
+		tokenID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateAccessToken looks up a live token by its plain value and
+// records that it was used. ok is false for unknown, revoked and expired
+// tokens.
+func (s *PostgresStore) AuthenticateAccessToken(
+	ctx context.Context,
+	plain string,
+) (token models.AccessToken, ok bool, err error) {
+	ctx, done := s.write(ctx, "AuthenticateAccessToken")
+	defer done(&err)
+	query := `
+		UPDATE access_tokens SET last_used_at = NOW()
+		WHERE token_hash = $1
+			AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING ` + accessTokenColumns
+	token, err = scanAccessToken(s.db.QueryRowContext(ctx, query, helper.HashToken(plain)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AccessToken{}, false, nil
+	}
+	if err != nil {
+		return models.AccessToken{}, false, err
+	}
+	return token, true, nil
+}
@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ListTrash returns a page of the trashed snippets the user could restore:
+// their own and those of organizations where their role allows writing.
+func (s *PostgresStore) ListTrash(
+	ctx context.Context,
+	userID uuid.UUID,
+	page helper.Page,
+) (_ models.SnippetPage, err error) {
+	ctx, done := s.read(ctx, "ListTrash")
+	defer done(&err)
+	where := `(org_id IS NULL AND user_id = $1)
+		OR org_id IN (SELECT org_id FROM org_members WHERE user_id = $1 AND role = ANY($2))`
+	writers := pq.Array([]string{models.RoleOwner, models.RoleEditor})
+	return s.queryPage(ctx, trashedSnippets, where, []any{userID, writers}, page)
+}
+
+// RestoreSnippet takes a snippet out of the trash, as it was when deleted.
+func (s *PostgresStore) RestoreSnippet(
+	ctx context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+) (_ models.Snippet, err error) {
+	ctx, done := s.write(ctx, "RestoreSnippet")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	defer tx.Rollback()
+
+	snippet, _, err := loadTrashedSnippet(ctx, tx, snippetID, userID)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE snippets SET deleted_at = NULL WHERE snippet_id = $1",
+		snippetID,
+	)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Snippet{}, err
+	}
+	snippet.DeletedAt = nil
+	return snippet, nil
+}
+
+// PurgeSnippet deletes a snippet in the trash for good, along with its
+// history, share links and any tags nothing else uses.
+func (s *PostgresStore) PurgeSnippet(
+	ctx context.Context,
+	snippetID uuid.UUID,
+	userID uuid.UUID,
+) (err error) {
+	ctx, done := s.write(ctx, "PurgeSnippet")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, ownerID, err := loadTrashedSnippet(ctx, tx, snippetID, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM snippets WHERE snippet_id = $1", snippetID)
+	if err != nil {
+		return err
+	}
+	if err = pruneTags(ctx, tx, ownerID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PurgeTrash deletes every snippet that went to the trash before the given
+// time, like PurgeSnippet, and returns how many there were. Accounts closed
+// before then can no longer be restored: they are deleted, or scrubbed of
+// their name, email and password while organizations still keep snippets
+// they created.
+func (s *PostgresStore) PurgeTrash(ctx context.Context, before time.Time) (_ int, err error) {
+	ctx, done := s.write(ctx, "PurgeTrash")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"DELETE FROM snippets WHERE deleted_at < $1 RETURNING user_id",
+		before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	owners := map[uuid.UUID]bool{}
+	for rows.Next() {
+		var ownerID uuid.UUID
+		if err := rows.Scan(&ownerID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		owners[ownerID] = true
+		purged++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for ownerID := range owners {
+		if err = pruneTags(ctx, tx, ownerID); err != nil {
+			return 0, err
+		}
+	}
+	for _, query := range []string{
+		`DELETE FROM users
+			WHERE deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM snippets WHERE snippets.user_id = users.user_id)`,
+		`UPDATE users SET
+				username = 'deleted-' || user_id,
+				email = 'deleted-' || user_id,
+				password_hash = ''
+			WHERE deleted_at < $1 AND password_hash <> ''`,
+	} {
+		if _, err = tx.ExecContext(ctx, query, before); err != nil {
+			return 0, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	tracing.SetRows(ctx, purged)
+	return purged, nil
+}
+
+// TrashPurger is a store whose trash can be emptied of old snippets.
+type TrashPurger interface {
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+}
+
+// PurgeTrashEvery purges the snippets that have been in the trash for longer
+// than retention right away and then every interval, until ctx is done.
+func PurgeTrashEvery(
+	ctx context.Context,
+	store TrashPurger,
+	retention time.Duration,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := store.PurgeTrash(ctx, time.Now().Add(-retention))
+		if err != nil {
+			slog.Error("failed to purge the trash", "error", err)
+		} else if purged > 0 {
+			slog.Info("purged snippets from the trash", "count", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,18 +1,44 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func CreateUser(user models.User) (uuid.UUID, error) {
+var (
+	ErrUsernameTaken      = errors.New("username already exists")
+	ErrEmailTaken         = errors.New("email already exists")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// hashPassword and checkPassword run bcrypt in spans of their own, since it
+// is slow on purpose and easily outweighs the queries around it.
+func hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := tracing.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer span.End()
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+func checkPassword(ctx context.Context, hash []byte, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+	return bcrypt.CompareHashAndPassword(hash, []byte(password))
+}
+
+func (s *PostgresStore) CreateUser(ctx context.Context, user models.User) (_ uuid.UUID, err error) {
+	ctx, done := s.write(ctx, "CreateUser")
+	defer done(&err)
 	// hash the password before using it in the db
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(ctx, user.Password)
 	if err != nil {
 		return uuid.UUID{}, fmt.Errorf("failed to hash password: %v", err)
 	}
@@ -25,8 +51,16 @@ func CreateUser(user models.User) (uuid.UUID, error) {
 
 	var userID uuid.UUID
 
-	err = DB.QueryRow(query, user.UserName, user.Email, hashedPassword, time.Now().UTC()).
+	err = s.db.QueryRowContext(ctx, query, user.UserName, user.Email, hashedPassword, time.Now().UTC()).
 		Scan(&userID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		switch pqErr.Constraint {
+		case "users_username_key":
+			return uuid.UUID{}, ErrUsernameTaken
+		case "users_email_key":
+			return uuid.UUID{}, ErrEmailTaken
+		}
+	}
 	if err != nil {
 		return uuid.UUID{}, err
 	}
@@ -34,54 +68,151 @@ func CreateUser(user models.User) (uuid.UUID, error) {
 	return userID, nil
 }
 
-func CheckUserCredentials(email, password string) (uuid.UUID, error) {
+func (s *PostgresStore) CheckUserCredentials(
+	ctx context.Context,
+	email, password string,
+) (_ uuid.UUID, err error) {
+	ctx, done := s.read(ctx, "CheckUserCredentials")
+	defer done(&err)
 	query := `
   SELECT user_id, password_hash
   FROM users
-  WHERE email = $1
+  WHERE email = $1 AND deleted_at IS NULL
   `
 
 	var userID uuid.UUID
 	var passwordHash string
 
-	err := DB.QueryRow(query, email).Scan(&userID, &passwordHash)
+	err = s.db.QueryRowContext(ctx, query, email).Scan(&userID, &passwordHash)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return uuid.UUID{}, fmt.Errorf("invalid credentials")
+			return uuid.UUID{}, ErrInvalidCredentials
 		}
-		return uuid.UUID{}, fmt.Errorf("failed to retreive user: %v", err)
+		return uuid.UUID{}, fmt.Errorf("failed to retreive user: %w", err)
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
+	err = checkPassword(ctx, []byte(passwordHash), password)
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("invalid credentials")
+		return uuid.UUID{}, ErrInvalidCredentials
 	}
 	return userID, nil
 }
 
-func DeleteUser(userID uuid.UUID) (uuid.UUID, error) {
-	query := `
-  DELETE FROM users
-  where user_id = $1
-  RETURNING user_id;
-  `
+// DeleteUser closes an account: the user can no longer log in, their
+// sessions, access tokens and memberships end and their personal snippets
+// move to the trash. Snippets they created in organizations stay with the
+// organization. Until PurgeTrash reaches the account, RestoreUser brings it
+// back along with the snippets it trashed, so its name and email stay taken
+// until then.
+func (s *PostgresStore) DeleteUser(ctx context.Context, userID uuid.UUID) (_ uuid.UUID, err error) {
+	ctx, done := s.write(ctx, "DeleteUser")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
 
+	query := `
+		UPDATE users SET deleted_at = NOW()
+		WHERE user_id = $1 AND deleted_at IS NULL
+		RETURNING user_id
+	`
 	var deletedUserID uuid.UUID
-
-	err := DB.QueryRow(query, userID).Scan(&deletedUserID)
+	err = tx.QueryRowContext(ctx, query, userID).Scan(&deletedUserID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return uuid.Nil, fmt.Errorf("user with ID %s not found", userID)
 		}
 		return uuid.Nil, err
 	}
+	for _, query := range []string{
+		`UPDATE snippets SET deleted_at = NOW()
+			WHERE user_id = $1 AND org_id IS NULL AND deleted_at IS NULL`,
+		"DELETE FROM sessions WHERE user_id = $1",
+		"DELETE FROM access_tokens WHERE user_id = $1",
+		"DELETE FROM org_members WHERE user_id = $1",
+	} {
+		if _, err = tx.ExecContext(ctx, query, userID); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
 	return deletedUserID, nil
 }
 
-func ChangePassword(userID uuid.UUID, password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// RestoreUser reopens an account closed by DeleteUser given its credentials,
+// and takes the personal snippets that went to the trash with it back out.
+// Snippets trashed before the account was closed stay in the trash.
+func (s *PostgresStore) RestoreUser(
+	ctx context.Context,
+	email, password string,
+) (_ uuid.UUID, err error) {
+	ctx, done := s.write(ctx, "RestoreUser")
+	defer done(&err)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT user_id, password_hash, deleted_at
+		FROM users
+		WHERE email = $1 AND deleted_at IS NOT NULL
+		FOR UPDATE
+	`
+	var userID uuid.UUID
+	var passwordHash string
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, query, email).Scan(&userID, &passwordHash, &deletedAt)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err = checkPassword(ctx, []byte(passwordHash), password); err != nil {
+		return uuid.Nil, ErrInvalidCredentials
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE users SET deleted_at = NULL WHERE user_id = $1", userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	// The snippets trashed along with the account share its deleted_at.
+	_, err = tx.ExecContext(ctx, `
+		UPDATE snippets SET deleted_at = NULL
+		WHERE user_id = $1 AND org_id IS NULL AND deleted_at = $2
+	`, userID, deletedAt)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// ChangePassword also revokes every session and personal access token of the
+// user, so anyone holding an old token has to log in with the new password.
+func (s *PostgresStore) ChangePassword(
+	ctx context.Context,
+	userID uuid.UUID,
+	password string,
+) (err error) {
+	ctx, done := s.write(ctx, "ChangePassword")
+	defer done(&err)
+	hashedPassword, err := hashPassword(ctx, password)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %v", err)
 	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `UPDATE users
   SET password_hash = $1
   WHERE user_id = $2
@@ -89,9 +220,20 @@ func ChangePassword(userID uuid.UUID, password string) error {
   `
 	var updatedUserId uuid.UUID
 
-	err = DB.QueryRow(query, hashedPassword, userID).Scan(&updatedUserId)
+	err = tx.QueryRowContext(ctx, query, hashedPassword, userID).Scan(&updatedUserId)
+	if err != nil {
+		return err
+	}
+	if err = revokeSessions(ctx, tx, "user_id = $1", userID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE access_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL",
+		userID,
+	)
 	if err != nil {
 		return err
 	}
-	return nil
+	return tx.Commit()
 }
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+)
+
+// snippetETag is the strong entity tag of the current version of a snippet.
+func snippetETag(snippet models.Snippet) string {
+	return `"` + strconv.Itoa(snippet.Version) + `"`
+}
+
+// ifMatchVersion returns the snippet version a change is conditioned on and
+// false when the request has no If-Match header. "*" matches any version and
+// yields 0. Clients are expected to send back the one ETag they read, so a
+// list or a tag that isn't ours yields -1, which matches no version.
+func ifMatchVersion(r *http.Request) (int, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch header {
+	case "":
+		return 0, false
+	case "*":
+		return 0, true
+	}
+	tag, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return -1, true
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	version, err := strconv.Atoi(tag)
+	if !ok || err != nil || version < 1 {
+		return -1, true
+	}
+	return version, true
+}
+
+// ifNoneMatch reports whether If-None-Match lists etag, using the weak
+// comparison RFC 9110 prescribes for it.
+func ifNoneMatch(r *http.Request, etag string) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Jitesh117/snippet-manager-backend/database"
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/google/uuid"
+)
+
+// maxBulkOperations bounds a batch, which runs in a single transaction.
+const maxBulkOperations = 100
+
+// Batch modes. An atomic batch applies every operation or none of them; a
+// best effort batch applies whichever operations succeed.
+const (
+	bulkAtomic     = "atomic"
+	bulkBestEffort = "best_effort"
+)
+
+type bulkRequest struct {
+	Mode       string                 `json:"mode"`
+	Operations []models.BulkOperation `json:"operations"`
+}
+
+type bulkResponse struct {
+	Mode    string       `json:"mode"`
+	Results []bulkResult `json:"results"`
+}
+
+// bulkResult reports one operation of a batch. Status is what the operation
+// would have been answered with as a request of its own.
+type bulkResult struct {
+	Index   int              `json:"index"`
+	Op      string           `json:"op"`
+	Status  int              `json:"status"`
+	Snippet *models.Snippet  `json:"snippet,omitempty"`
+	Error   *problem.Details `json:"error,omitempty"`
+}
+
+func (r *bulkResult) fail(details problem.Details) {
+	r.Status = details.Status
+	r.Error = &details
+}
+
+// BulkSnippets serves POST /snippets/bulk. The response is 200 when every
+// operation succeeded and 207 otherwise, with the outcome of each operation in
+// request order.
+func (h *Handler) BulkSnippets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+	var request bulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		problem.Write(w, problem.InvalidPayload)
+		return
+	}
+	switch {
+	case request.Mode == "":
+		request.Mode = bulkAtomic
+	case request.Mode != bulkAtomic && request.Mode != bulkBestEffort:
+		problem.Write(w, problem.InvalidBatchMode)
+		return
+	}
+	if len(request.Operations) == 0 {
+		problem.Write(w, problem.EmptyBatch)
+		return
+	}
+	if len(request.Operations) > maxBulkOperations {
+		problem.Write(w, problem.TooManyOperations.Withf(maxBulkOperations))
+		return
+	}
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	// Invalid operations are answered without reaching the store. In an
+	// atomic batch they abort all the others.
+	results := make([]bulkResult, len(request.Operations))
+	var valid []int
+	for i := range request.Operations {
+		op := &request.Operations[i]
+		results[i] = bulkResult{Index: i, Op: op.Op}
+		err := prepareBulkOperation(op)
+		var kind problem.Kind
+		switch {
+		case errors.As(err, &kind):
+			results[i].fail(kind.Details())
+		case err != nil:
+			results[i].fail(problem.Validation(err))
+		default:
+			valid = append(valid, i)
+		}
+	}
+	if request.Mode == bulkAtomic && len(valid) < len(results) {
+		for _, i := range valid {
+			results[i].fail(problem.BatchAborted.Details())
+		}
+		writeBulkResponse(w, request.Mode, results)
+		return
+	}
+
+	ops := make([]models.BulkOperation, len(valid))
+	for i, index := range valid {
+		ops[i] = request.Operations[index]
+	}
+	outcomes, err := h.Snippets.BulkSnippets(
+		r.Context(),
+		userID,
+		ops,
+		request.Mode == bulkAtomic,
+	)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToRunBatch)
+		return
+	}
+	for i, outcome := range outcomes {
+		result := &results[valid[i]]
+		switch {
+		case errors.Is(outcome.Err, database.ErrBatchAborted):
+			result.fail(problem.BatchAborted.Details())
+		case outcome.Err != nil:
+			logging.FromContext(r.Context()).Warn(
+				"batch operation failed",
+				"index", valid[i],
+				"error", outcome.Err,
+			)
+			result.fail(snippetProblem(outcome.Err, problem.FailedToRunBatch).Details())
+		default:
+			result.Status = http.StatusOK
+			if result.Op == models.BulkCreate {
+				result.Status = http.StatusCreated
+			}
+			result.Snippet = &outcome.Snippet
+		}
+	}
+	logging.FromContext(r.Context()).Info("ran snippet batch", "operations", len(ops))
+	writeBulkResponse(w, request.Mode, results)
+}
+
+// prepareBulkOperation normalizes op and checks it the way the single
+// snippet endpoints check their requests.
+func prepareBulkOperation(op *models.BulkOperation) error {
+	switch op.Op {
+	case models.BulkCreate:
+		if op.Visibility == "" {
+			op.Visibility = models.VisibilityPrivate
+		}
+	case models.BulkUpdate, models.BulkDelete:
+		if op.SnippetId == uuid.Nil {
+			return problem.InvalidSnippetID
+		}
+		if op.Version == 0 {
+			return problem.VersionRequired
+		}
+	default:
+		return problem.InvalidOperation
+	}
+	if op.Op == models.BulkDelete {
+		return nil
+	}
+	op.Tags = helper.NormalizeTags(op.Tags)
+	return helper.ValidateSnippet(op.Snippet)
+}
+
+func writeBulkResponse(w http.ResponseWriter, mode string, results []bulkResult) {
+	status := http.StatusOK
+	for _, result := range results {
+		if result.Error != nil {
+			status = http.StatusMultiStatus
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(bulkResponse{Mode: mode, Results: results})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+)
+
+type probeStatus struct {
+	Status string `json:"status"`
+}
+
+// Drain fails the readiness probe from now on, so load balancers stop
+// routing requests here while the server shuts down.
+func (h *Handler) Drain() {
+	h.draining.Store(true)
+}
+
+// Healthz serves GET /healthz, the liveness probe. It succeeds as long as
+// the process can serve requests at all and checks no dependencies, so a
+// database outage doesn't get the instance restarted.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+	writeProbeStatus(w)
+}
+
+// Readyz serves GET /readyz, the readiness probe. It fails once shutdown
+// has begun and while Ready reports a problem, such as the database being
+// unreachable or missing migrations. What went wrong is logged rather than
+// shown to the caller.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+	if h.draining.Load() {
+		problem.Write(w, problem.ShuttingDown)
+		return
+	}
+	if h.Ready != nil {
+		if err := h.Ready(r.Context()); err != nil {
+			logging.SetError(w, err)
+			problem.Write(w, problem.NotReady)
+			return
+		}
+	}
+	writeProbeStatus(w)
+}
+
+func writeProbeStatus(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(probeStatus{Status: "ok"})
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/database"
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/library"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/google/uuid"
+)
+
+// maxImportBytes bounds the body of an import, which is read whole.
+// library.Read bounds its snippets, which are applied in a single transaction.
+const maxImportBytes = 32 << 20
+
+// importItem reports what an import did, or would do on a dry run, with one
+// snippet of the library. SnippetID is the snippet that was created or
+// overwritten and ConflictsWith the one the imported snippet collided with.
+type importItem struct {
+	Index         int              `json:"index"`
+	Title         string           `json:"title"`
+	Language      string           `json:"language"`
+	Action        string           `json:"action"`
+	SnippetID     *uuid.UUID       `json:"snippet_id,omitempty"`
+	ConflictsWith *uuid.UUID       `json:"conflicts_with,omitempty"`
+	RenamedTo     string           `json:"renamed_to,omitempty"`
+	Error         *problem.Details `json:"error,omitempty"`
+}
+
+// importReport is the response of POST /import. Applied is false on a dry
+// run and when the import failed as a whole; Summary counts items by action.
+type importReport struct {
+	Format     string         `json:"format"`
+	OnConflict string         `json:"on_conflict"`
+	DryRun     bool           `json:"dry_run"`
+	Applied    bool           `json:"applied"`
+	Summary    map[string]int `json:"summary"`
+	Items      []importItem   `json:"items"`
+}
+
+// What the import report says of snippets that never got to be planned or
+// applied, next to the library.Action values.
+const (
+	importInvalid = "invalid"
+	importFailed  = "failed"
+)
+
+// Export serves GET /export, streaming the caller's personal snippets, or
+// those of the organization in ?org_id=, in the format given by ?format=,
+// NDJSON by default, or as a snippet file of one of the editors library
+// knows.
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = library.FormatNDJSON
+	}
+	writer, err := library.NewWriter(w, format)
+	if err != nil {
+		problem.Write(w, unknownFormat())
+		return
+	}
+	orgID, err := libraryScope(r)
+	if err != nil {
+		problem.Write(w, problem.InvalidOrgID)
+		return
+	}
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	name := "snippets-" + time.Now().UTC().Format("2006-01-02") + library.Extension(format)
+	w.Header().Set("Content-Type", library.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	count := 0
+	err = h.eachSnippet(r.Context(), userID, orgID, func(snippet models.Snippet) error {
+		count++
+		return writer.Write(snippet)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil && count == 0 {
+		// Nothing was sent yet, so the client can still be told.
+		w.Header().Del("Content-Disposition")
+		writeOrgError(w, err, problem.FailedToExport)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("export cut short", "error", err)
+		return
+	}
+	logging.FromContext(r.Context()).Info("exported snippets")
+}
+
+// Import serves POST /import. The library's format comes from ?format=, which
+// also takes editor formats, or else the Content-Type. Snippets are imported
+// as the caller's personal snippets, or into the organization in ?org_id=,
+// and only collide with snippets of the same library. ?on_conflict= picks
+// what happens to snippets that collide with existing ones and ?dry_run=true
+// only reports what would happen. Everything that is imported is imported in
+// one transaction.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	report := importReport{
+		Format:     query.Get("format"),
+		OnConflict: query.Get("on_conflict"),
+		Summary:    map[string]int{},
+		Items:      []importItem{},
+	}
+	if report.Format == "" {
+		report.Format = library.FormatOf(r.Header.Get("Content-Type"))
+	}
+	if report.Format == "" {
+		report.Format = library.FormatNDJSON
+	}
+	if report.OnConflict == "" {
+		report.OnConflict = library.ConflictSkip
+	}
+	if !library.IsValidConflictPolicy(report.OnConflict) {
+		problem.Write(w, problem.InvalidConflictPolicy)
+		return
+	}
+	if dryRun := query.Get("dry_run"); dryRun != "" {
+		var err error
+		if report.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			problem.Write(w, problem.InvalidDryRun)
+			return
+		}
+	}
+	orgID, err := libraryScope(r)
+	if err != nil {
+		problem.Write(w, problem.InvalidOrgID)
+		return
+	}
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	snippets, err := library.Read(http.MaxBytesReader(w, r.Body, maxImportBytes), report.Format)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge), errors.Is(err, library.ErrTooLarge):
+		problem.Write(w, problem.ImportTooLarge)
+		return
+	case errors.Is(err, library.ErrUnknownFormat):
+		problem.Write(w, unknownFormat())
+		return
+	case errors.Is(err, library.ErrInvalidLibrary):
+		detail := strings.TrimPrefix(err.Error(), library.ErrInvalidLibrary.Error()+": ")
+		problem.Write(w, problem.InvalidLibrary.WithDetail(detail))
+		return
+	case err != nil:
+		problem.WriteError(w, err, problem.FailedToImport)
+		return
+	}
+
+	// Invalid snippets are reported and left out, the rest is planned against
+	// the library they are imported into.
+	var valid []models.Snippet
+	var planned []int
+	for i, snippet := range snippets {
+		report.Items = append(report.Items, importItem{
+			Index:    i,
+			Title:    snippet.Title,
+			Language: snippet.Language,
+		})
+		snippet.Tags = helper.NormalizeTags(snippet.Tags)
+		if snippet.Tags == nil {
+			snippet.Tags = []string{}
+		}
+		if snippet.Visibility == "" {
+			snippet.Visibility = models.VisibilityPrivate
+		}
+		if err := helper.ValidateSnippet(snippet); err != nil {
+			details := problem.Validation(err)
+			report.Items[i].Action = importInvalid
+			report.Items[i].Error = &details
+			continue
+		}
+		valid = append(valid, snippet)
+		planned = append(planned, i)
+	}
+	var existing []models.Snippet
+	err = h.eachSnippet(r.Context(), userID, orgID, func(snippet models.Snippet) error {
+		existing = append(existing, snippet)
+		return nil
+	})
+	if !writeOrgError(w, err, problem.FailedToImport) {
+		return
+	}
+	steps := library.Plan(existing, valid, report.OnConflict)
+
+	var ops []models.BulkOperation
+	var applied []int
+	for i, step := range steps {
+		item := &report.Items[planned[i]]
+		item.Action = step.Action
+		if step.Existing != nil && step.Existing.SnippetId != uuid.Nil {
+			item.ConflictsWith = &step.Existing.SnippetId
+		}
+		op := models.BulkOperation{Snippet: step.Snippet}
+		switch step.Action {
+		case library.ActionCreate, library.ActionRename:
+			op.Op = models.BulkCreate
+			op.OrgID = orgID
+		case library.ActionOverwrite:
+			op.Op = models.BulkUpdate
+			op.Version = step.Existing.Version
+		default:
+			continue
+		}
+		if step.Action == library.ActionRename {
+			item.RenamedTo = step.Snippet.Title
+		}
+		ops = append(ops, op)
+		applied = append(applied, planned[i])
+	}
+
+	if !report.DryRun && len(ops) > 0 {
+		results, err := h.Snippets.BulkSnippets(r.Context(), userID, ops, true)
+		if err != nil {
+			problem.WriteError(w, err, problem.FailedToImport)
+			return
+		}
+		report.Applied = true
+		for i, result := range results {
+			item := &report.Items[applied[i]]
+			switch {
+			case errors.Is(result.Err, database.ErrBatchAborted):
+				report.Applied = false
+			case result.Err != nil:
+				logging.FromContext(r.Context()).Warn(
+					"import failed",
+					"index", applied[i],
+					"error", result.Err,
+				)
+				details := snippetProblem(result.Err, problem.FailedToImport).Details()
+				item.Action = importFailed
+				item.Error = &details
+				report.Applied = false
+			default:
+				item.SnippetID = &result.Snippet.SnippetId
+			}
+		}
+	}
+	writeImportReport(w, r, report)
+}
+
+// unknownFormat says which formats there are.
+func unknownFormat() problem.Kind {
+	return problem.UnknownFormat.WithDetail("use one of " + strings.Join(library.Formats, ", "))
+}
+
+func writeImportReport(w http.ResponseWriter, r *http.Request, report importReport) {
+	status := http.StatusOK
+	for _, item := range report.Items {
+		report.Summary[item.Action]++
+		if item.Error != nil {
+			status = http.StatusMultiStatus
+		}
+	}
+	if report.Applied {
+		logging.FromContext(r.Context()).Info("imported snippets", "summary", report.Summary)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// libraryScope returns the organization in ?org_id= whose library an export
+// or import is about, or nil for the caller's personal snippets.
+func libraryScope(r *http.Request) (*uuid.UUID, error) {
+	idStr := r.URL.Query().Get("org_id")
+	if idStr == "" {
+		return nil, nil
+	}
+	orgID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, err
+	}
+	return &orgID, nil
+}
+
+// eachSnippet calls visit with every snippet of the organization when orgID
+// is set and with every personal snippet of the user otherwise, oldest
+// first, reading them a page at a time.
+func (h *Handler) eachSnippet(
+	ctx context.Context,
+	userID uuid.UUID,
+	orgID *uuid.UUID,
+	visit func(models.Snippet) error,
+) error {
+	page := helper.Page{SortBy: "created_at", Order: "asc", Limit: maxPageLimit}
+	for {
+		var result models.SnippetPage
+		var err error
+		if orgID != nil {
+			result, err = h.Snippets.GetOrgSnippets(ctx, *orgID, userID, page)
+		} else {
+			result, err = h.Snippets.GetSnippetsSorted(ctx, userID, page)
+		}
+		if err != nil {
+			return err
+		}
+		for _, snippet := range result.Snippets {
+			// The user can read organization snippets too, which belong to
+			// the libraries of their organizations.
+			if snippet.OrgID != nil && orgID == nil {
+				continue
+			}
+			if err := visit(snippet); err != nil {
+				return err
+			}
+		}
+		if !result.HasMore {
+			return nil
+		}
+		cursor := page.CursorFor(result.Snippets[len(result.Snippets)-1])
+		page.After = &cursor
+	}
+}
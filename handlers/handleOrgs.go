@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Jitesh117/snippet-manager-backend/database"
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/google/uuid"
+)
+
+// HandleOrgs serves GET /orgs, the caller's organizations, and POST /orgs to
+// create one with the caller as owner.
+func (h *Handler) HandleOrgs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listOrgs(w, r)
+	case http.MethodPost:
+		h.createOrg(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		problem.Write(w, problem.MethodNotAllowed)
+	}
+}
+
+// HandleOrg serves everything under /orgs/{id}:
+//
+//	GET    /orgs/{id}/snippets
+//	GET    /orgs/{id}/members
+//	DELETE /orgs/{id}/members/{userID}
+//	GET    /orgs/{id}/invites
+//	POST   /orgs/{id}/invites
+func (h *Handler) HandleOrg(w http.ResponseWriter, r *http.Request) {
+	idStr, rest, _ := strings.Cut(r.URL.Path[len("/orgs/"):], "/")
+	orgID, err := uuid.Parse(idStr)
+	if err != nil {
+		problem.Write(w, problem.InvalidOrgID)
+		return
+	}
+	resource, memberStr, _ := strings.Cut(rest, "/")
+
+	switch {
+	case resource == "snippets" && memberStr == "":
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			problem.Write(w, problem.MethodNotAllowed)
+			return
+		}
+		h.getOrgSnippets(w, r, orgID)
+	case resource == "members" && memberStr == "":
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			problem.Write(w, problem.MethodNotAllowed)
+			return
+		}
+		h.listOrgMembers(w, r, orgID)
+	case resource == "members":
+		memberID, err := uuid.Parse(memberStr)
+		if err != nil {
+			problem.Write(w, problem.InvalidMemberID)
+			return
+		}
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "DELETE")
+			problem.Write(w, problem.MethodNotAllowed)
+			return
+		}
+		h.removeOrgMember(w, r, orgID, memberID)
+	case resource == "invites" && memberStr == "":
+		switch r.Method {
+		case http.MethodGet:
+			h.listOrgInvites(w, r, orgID)
+		case http.MethodPost:
+			h.createOrgInvite(w, r, orgID)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			problem.Write(w, problem.MethodNotAllowed)
+		}
+	default:
+		problem.Write(w, problem.NotFound)
+	}
+}
+
+func (h *Handler) createOrg(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		problem.Write(w, problem.InvalidPayload)
+		return
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if err := helper.ValidateOrgName(request.Name); err != nil {
+		problem.WriteValidation(w, err)
+		return
+	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	org, err := h.Orgs.CreateOrg(r.Context(), request.Name, userID)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToCreateOrg)
+		return
+	}
+	logging.FromContext(r.Context()).Info("created organization", "org_id", org.OrgID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(org)
+}
+
+func (h *Handler) listOrgs(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	orgs, err := h.Orgs.ListOrgs(r.Context(), userID)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGetOrgs)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orgs)
+}
+
+func (h *Handler) getOrgSnippets(w http.ResponseWriter, r *http.Request, orgID uuid.UUID) {
+	page, err := parsePage(r, "updated_at", "desc")
+	if err != nil {
+		problem.WriteError(w, err, problem.InvalidPayload)
+		return
+	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	result, err := h.Snippets.GetOrgSnippets(r.Context(), orgID, userID, page)
+	if !writeOrgError(w, err, problem.FailedToGetSnippets) {
+		return
+	}
+	writeSnippetPage(w, page, result)
+}
+
+func (h *Handler) listOrgMembers(w http.ResponseWriter, r *http.Request, orgID uuid.UUID) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	members, err := h.Orgs.ListOrgMembers(r.Context(), orgID, userID)
+	if !writeOrgError(w, err, problem.FailedToGetMembers) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// removeOrgMember lets owners remove anyone and every member leave.
+func (h *Handler) removeOrgMember(
+	w http.ResponseWriter,
+	r *http.Request,
+	orgID uuid.UUID,
+	memberID uuid.UUID,
+) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	err = h.Orgs.RemoveOrgMember(r.Context(), orgID, userID, memberID)
+	if !writeOrgError(w, err, problem.FailedToRemoveMember) {
+		return
+	}
+	logging.FromContext(r.Context()).Info(
+		"removed organization member",
+		"org_id", orgID,
+		"member_id", memberID,
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) createOrgInvite(w http.ResponseWriter, r *http.Request, orgID uuid.UUID) {
+	var request struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		problem.Write(w, problem.InvalidPayload)
+		return
+	}
+	request.Email = strings.TrimSpace(request.Email)
+	if err := helper.ValidateInvite(request.Email, request.Role); err != nil {
+		problem.WriteValidation(w, err)
+		return
+	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	invite, err := h.Orgs.CreateOrgInvite(r.Context(), orgID, userID, request.Email, request.Role)
+	if !writeOrgError(w, err, problem.FailedToInvite) {
+		return
+	}
+	logging.FromContext(r.Context()).Info(
+		"created organization invite",
+		"org_id", orgID,
+		"invite_id", invite.InviteID,
+	)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invite)
+}
+
+func (h *Handler) listOrgInvites(w http.ResponseWriter, r *http.Request, orgID uuid.UUID) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	invites, err := h.Orgs.ListOrgInvites(r.Context(), orgID, userID)
+	if !writeOrgError(w, err, problem.FailedToGetInvites) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invites)
+}
+
+// HandleInvites serves GET /invites, the pending invites sent to the caller's
+// email.
+func (h *Handler) HandleInvites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	invites, err := h.Orgs.ListUserInvites(r.Context(), userID)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGetInvites)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invites)
+}
+
+// HandleInvite serves POST /invites/{id}/accept.
+func (h *Handler) HandleInvite(w http.ResponseWriter, r *http.Request) {
+	idStr, action, _ := strings.Cut(r.URL.Path[len("/invites/"):], "/")
+	inviteID, err := uuid.Parse(idStr)
+	if err != nil {
+		problem.Write(w, problem.InvalidInviteID)
+		return
+	}
+	if action != "accept" {
+		problem.Write(w, problem.NotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	org, err := h.Orgs.AcceptOrgInvite(r.Context(), inviteID, userID)
+	if !writeOrgError(w, err, problem.FailedToAcceptInvite) {
+		return
+	}
+	logging.FromContext(r.Context()).Info("accepted organization invite", "org_id", org.OrgID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(org)
+}
+
+// writeOrgError maps an organization store error to a response and reports
+// whether the request can continue.
+func writeOrgError(w http.ResponseWriter, err error, failure problem.Kind) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, database.ErrOrgNotFound):
+		problem.Write(w, problem.OrgNotFound)
+	case errors.Is(err, database.ErrMemberNotFound):
+		problem.Write(w, problem.MemberNotFound)
+	case errors.Is(err, database.ErrInviteNotFound):
+		problem.Write(w, problem.InviteNotFound)
+	case errors.Is(err, database.ErrForbidden):
+		problem.Write(w, problem.AccessDenied)
+	case errors.Is(err, database.ErrAlreadyMember):
+		problem.Write(w, problem.AlreadyMember)
+	case errors.Is(err, database.ErrInviteExists):
+		problem.Write(w, problem.InviteExists)
+	case errors.Is(err, database.ErrLastOwner):
+		problem.Write(w, problem.LastOwner)
+	default:
+		problem.WriteError(w, err, failure)
+	}
+	return false
+}
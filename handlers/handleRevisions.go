@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Jitesh117/snippet-manager-backend/database"
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/google/uuid"
+)
+
+// handleRevisions serves everything under /snippets/{id}/revisions:
+//
+//	GET  /snippets/{id}/revisions
+//	GET  /snippets/{id}/revisions/{n}
+//	GET  /snippets/{id}/revisions/diff?from={a}&to={b}
+//	POST /snippets/{id}/revisions/{n}/restore
+func (h *Handler) handleRevisions(
+	w http.ResponseWriter,
+	r *http.Request,
+	snippetID uuid.UUID,
+	rest string,
+) {
+	numberStr, action, _ := strings.Cut(rest, "/")
+
+	if numberStr == "" || numberStr == "diff" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			problem.Write(w, problem.MethodNotAllowed)
+			return
+		}
+		if numberStr == "" {
+			h.listRevisions(w, r, snippetID)
+		} else {
+			h.diffRevisions(w, r, snippetID)
+		}
+		return
+	}
+
+	revision, err := parseRevision(numberStr)
+	if err != nil {
+		problem.Write(w, problem.InvalidRevision)
+		return
+	}
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.getRevision(w, r, snippetID, revision)
+	case action == "restore" && r.Method == http.MethodPost:
+		h.restoreRevision(w, r, snippetID, revision)
+	case action == "":
+		w.Header().Set("Allow", "GET")
+		problem.Write(w, problem.MethodNotAllowed)
+	case action == "restore":
+		w.Header().Set("Allow", "POST")
+		problem.Write(w, problem.MethodNotAllowed)
+	default:
+		problem.Write(w, problem.NotFound)
+	}
+}
+
+func (h *Handler) listRevisions(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	revisions, err := h.Snippets.ListRevisions(r.Context(), snippetID, userID)
+	if !writeRevisionError(w, err, problem.FailedToGetRevisions) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+func (h *Handler) getRevision(
+	w http.ResponseWriter,
+	r *http.Request,
+	snippetID uuid.UUID,
+	revision int,
+) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	result, err := h.Snippets.GetRevision(r.Context(), snippetID, userID, revision)
+	if !writeRevisionError(w, err, problem.FailedToGetRevisions) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// diffRevisions writes a unified diff between two revisions. Title, language
+// and tags are rendered above the content so changes to them show up too.
+func (h *Handler) diffRevisions(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+	from, fromErr := parseRevision(r.URL.Query().Get("from"))
+	to, toErr := parseRevision(r.URL.Query().Get("to"))
+	if fromErr != nil || toErr != nil {
+		problem.Write(w, problem.InvalidRevision)
+		return
+	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	old, err := h.Snippets.GetRevision(r.Context(), snippetID, userID, from)
+	if !writeRevisionError(w, err, problem.FailedToGetRevisions) {
+		return
+	}
+	updated, err := h.Snippets.GetRevision(r.Context(), snippetID, userID, to)
+	if !writeRevisionError(w, err, problem.FailedToGetRevisions) {
+		return
+	}
+
+	diff, err := helper.UnifiedDiff(
+		fmt.Sprintf("a/%s (revision %d)", old.Title, old.Revision),
+		fmt.Sprintf("b/%s (revision %d)", updated.Title, updated.Revision),
+		renderRevision(old),
+		renderRevision(updated),
+	)
+	if err != nil {
+		problem.Write(w, problem.DiffTooLarge)
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	w.Write([]byte(diff))
+}
+
+func (h *Handler) restoreRevision(
+	w http.ResponseWriter,
+	r *http.Request,
+	snippetID uuid.UUID,
+	revision int,
+) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	snippet, err := h.Snippets.RestoreRevision(r.Context(), snippetID, userID, revision)
+	if !writeRevisionError(w, err, problem.FailedToRestoreRevision) {
+		return
+	}
+	logging.FromContext(r.Context()).Info(
+		"restored snippet revision",
+		"snippet_id", snippetID,
+		"revision", revision,
+	)
+	w.Header().Set("ETag", snippetETag(snippet))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snippet)
+}
+
+func parseRevision(s string) (int, error) {
+	revision, err := strconv.Atoi(s)
+	if err != nil || revision < 1 {
+		return 0, fmt.Errorf("invalid revision %q", s)
+	}
+	return revision, nil
+}
+
+func renderRevision(revision models.SnippetRevision) string {
+	return fmt.Sprintf(
+		"title: %s\nlanguage: %s\ntags: %s\n\n%s",
+		revision.Title,
+		revision.Language,
+		strings.Join(revision.Tags, ", "),
+		revision.Content,
+	)
+}
+
+// writeRevisionError maps a revision store error to a response and reports
+// whether the request can continue.
+func writeRevisionError(w http.ResponseWriter, err error, failure problem.Kind) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, database.ErrRevisionNotFound):
+		problem.Write(w, problem.RevisionNotFound)
+	default:
+		return writeSnippetError(w, err, failure)
+	}
+	return false
+}
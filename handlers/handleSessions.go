@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Jitesh117/snippet-manager-backend/database"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/google/uuid"
+)
+
+// tokenResponse is returned by register, login and refresh. Token is the
+// short-lived access token; RefreshToken gets a new pair from /token/refresh
+// and stops working once used.
+type tokenResponse struct {
+	Token        string `json:"token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// startSession logs userID in with a new session and writes its tokens.
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	session, err := h.Users.CreateSession(r.Context(), userID, auth.RefreshTokenTTL)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGenerateToken)
+		return
+	}
+	writeTokens(w, session)
+}
+
+func writeTokens(w http.ResponseWriter, session models.Session) {
+	token, err := auth.GenerateJWT(session.UserID, session.SessionID)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGenerateToken)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(tokenResponse{
+		Token:        token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(auth.AccessTokenTTL.Seconds()),
+		RefreshToken: session.RefreshToken,
+	})
+}
+
+// RefreshToken serves POST /token/refresh, trading a refresh token for a new
+// access and refresh token. Reusing a refresh token revokes its session.
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+	var request struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil ||
+		request.RefreshToken == "" {
+		problem.Write(w, problem.InvalidPayload)
+		return
+	}
+
+	session, err := h.Users.RefreshSession(r.Context(), request.RefreshToken, auth.RefreshTokenTTL)
+	switch {
+	case errors.Is(err, database.ErrRefreshTokenReused):
+		logging.FromContext(r.Context()).Warn("refresh token reused, session revoked")
+		problem.Write(w, problem.RefreshTokenReused)
+		return
+	case errors.Is(err, database.ErrInvalidRefreshToken):
+		problem.Write(w, problem.InvalidRefreshToken)
+		return
+	case err != nil:
+		problem.WriteError(w, err, problem.FailedToRefreshToken)
+		return
+	}
+	writeTokens(w, session)
+}
+
+// Logout serves POST /logout, revoking the session of the access token so it
+// and its refresh token stop working.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+
+	sessionID, err := auth.ExtractSessionIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+	if err := h.Users.RevokeSession(r.Context(), sessionID); err != nil {
+		problem.WriteError(w, err, problem.FailedToLogout)
+		return
+	}
+	logging.FromContext(r.Context()).Info("user logged out", "session_id", sessionID)
+	w.WriteHeader(http.StatusNoContent)
+}
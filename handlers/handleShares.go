@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/constants"
+	"github.com/Jitesh117/snippet-manager-backend/database"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/google/uuid"
+)
+
+// handleShares serves the share links of a snippet:
+//
+//	GET    /snippets/{id}/shares
+//	POST   /snippets/{id}/shares
+//	DELETE /snippets/{id}/shares/{shareID}
+func (h *Handler) handleShares(
+	w http.ResponseWriter,
+	r *http.Request,
+	snippetID uuid.UUID,
+	rest string,
+) {
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			h.listShareLinks(w, r, snippetID)
+		case http.MethodPost:
+			h.createShareLink(w, r, snippetID)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			problem.Write(w, problem.MethodNotAllowed)
+		}
+		return
+	}
+
+	shareID, err := uuid.Parse(rest)
+	if err != nil {
+		problem.Write(w, problem.InvalidShareID)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "DELETE")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+	h.revokeShareLink(w, r, snippetID, shareID)
+}
+
+func (h *Handler) createShareLink(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+	var request struct {
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	// The body is optional, a link without one never expires.
+	if r.Body != nil {
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil && err != io.EOF {
+			problem.Write(w, problem.InvalidPayload)
+			return
+		}
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		problem.Write(w, problem.InvalidPayload.WithDetail(constants.ErrInvalidExpiry))
+		return
+	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	link, err := h.Snippets.CreateShareLink(r.Context(), snippetID, userID, request.ExpiresAt)
+	if !writeShareError(w, err, problem.FailedToShareSnippet) {
+		return
+	}
+	logging.FromContext(r.Context()).Info(
+		"created share link",
+		"snippet_id", snippetID,
+		"share_id", link.ShareID,
+	)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+func (h *Handler) listShareLinks(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	links, err := h.Snippets.ListShareLinks(r.Context(), snippetID, userID)
+	if !writeShareError(w, err, problem.FailedToGetShareLinks) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+func (h *Handler) revokeShareLink(
+	w http.ResponseWriter,
+	r *http.Request,
+	snippetID uuid.UUID,
+	shareID uuid.UUID,
+) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	err = h.Snippets.RevokeShareLink(r.Context(), snippetID, userID, shareID)
+	if !writeShareError(w, err, problem.FailedToRevokeShareLink) {
+		return
+	}
+	logging.FromContext(r.Context()).Info(
+		"revoked share link",
+		"snippet_id", snippetID,
+		"share_id", shareID,
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSharedSnippet serves GET /s/{token} without authentication.
+func (h *Handler) GetSharedSnippet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+	token := r.URL.Path[len("/s/"):]
+	if token == "" {
+		problem.Write(w, problem.ShareLinkNotFound)
+		return
+	}
+
+	snippet, err := h.Snippets.GetSharedSnippet(r.Context(), token)
+	if !writeShareError(w, err, problem.FailedToGetSnippets) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(snippet)
+}
+
+// Explore serves GET /explore, the public snippets of every user, newest
+// first.
+func (h *Handler) Explore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+
+	page, err := parsePage(r, "created_at", "desc")
+	if err != nil {
+		problem.WriteError(w, err, problem.InvalidPayload)
+		return
+	}
+
+	result, err := h.Snippets.GetPublicSnippets(r.Context(), r.URL.Query().Get("language"), page)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGetSnippets)
+		return
+	}
+	writeSnippetPage(w, page, result)
+}
+
+// writeShareError maps a sharing store error to a response and reports
+// whether the request can continue.
+func writeShareError(w http.ResponseWriter, err error, failure problem.Kind) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, database.ErrShareLinkNotFound):
+		problem.Write(w, problem.ShareLinkNotFound)
+	case errors.Is(err, database.ErrSnippetPrivate):
+		problem.Write(w, problem.SnippetPrivate)
+	default:
+		return writeSnippetError(w, err, failure)
+	}
+	return false
+}
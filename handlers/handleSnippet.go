@@ -1,112 +1,305 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
 
-	"github.com/Jitesh117/snippet-manager-backend/constants"
 	"github.com/Jitesh117/snippet-manager-backend/database"
 	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
 	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
 	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
 	"github.com/google/uuid"
 )
 
-func HandleSnippet(w http.ResponseWriter, r *http.Request) {
-	idStr := r.URL.Path[len("/snippets/"):]
+func (h *Handler) HandleSnippet(w http.ResponseWriter, r *http.Request) {
+	idStr, rest, _ := strings.Cut(r.URL.Path[len("/snippets/"):], "/")
 	snippetID, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, constants.ErrInvalidSnippetID, http.StatusBadRequest)
+		problem.Write(w, problem.InvalidSnippetID)
+		return
+	}
+	if rest == "revisions" || strings.HasPrefix(rest, "revisions/") {
+		h.handleRevisions(w, r, snippetID, strings.TrimPrefix(rest[len("revisions"):], "/"))
+		return
+	}
+	if rest == "shares" || strings.HasPrefix(rest, "shares/") {
+		h.handleShares(w, r, snippetID, strings.TrimPrefix(rest[len("shares"):], "/"))
+		return
+	}
+	if rest != "" {
+		problem.Write(w, problem.NotFound)
 		return
 	}
 	switch r.Method {
 	case http.MethodGet:
-		getSnippetByID(w, r, snippetID)
+		h.getSnippetByID(w, r, snippetID)
 	case http.MethodPut:
-		updateSnippetByID(w, r, snippetID)
+		h.updateSnippetByID(w, r, snippetID)
+	case http.MethodPatch:
+		h.patchSnippetByID(w, r, snippetID)
 	case http.MethodDelete:
-		deleteSnippetByID(w, r, snippetID)
+		h.deleteSnippetByID(w, r, snippetID)
 	default:
-		w.Header().Set("Allow", "GET, PUT, DELETE")
-		http.Error(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+		problem.Write(w, problem.MethodNotAllowed)
 	}
 }
 
-func updateSnippetByID(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+func (h *Handler) updateSnippetByID(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+	version, ok := ifMatchVersion(r)
+	if !ok {
+		problem.Write(w, problem.IfMatchRequired)
+		return
+	}
 	var requestSnippet models.Snippet
 	if err := json.NewDecoder(r.Body).Decode(&requestSnippet); err != nil {
-		http.Error(w, constants.ErrInvalidPayload, http.StatusBadRequest)
+		problem.Write(w, problem.InvalidPayload)
 		return
 	}
+	requestSnippet.Tags = helper.NormalizeTags(requestSnippet.Tags)
 	if err := helper.ValidateSnippet(requestSnippet); err != nil {
-		http.Error(w, constants.ErrInvalidPayload+": "+err.Error(), http.StatusBadRequest)
+		problem.WriteValidation(w, err)
 		return
 	}
 	userID, err := auth.ExtractUserIDFromToken(r)
 	if err != nil {
-		http.Error(w, constants.ErrFailedToGetUserID+": "+err.Error(), http.StatusBadRequest)
+		problem.Write(w, problem.FailedToGetUserID)
 		return
 	}
 
-	snippet, err := database.UpdateSnippet(
+	snippet, err := h.Snippets.UpdateSnippet(
+		r.Context(),
 		requestSnippet.Title,
 		requestSnippet.Language,
 		requestSnippet.Content,
+		requestSnippet.Tags,
+		requestSnippet.Visibility,
 		snippetID,
 		userID,
+		version,
 	)
-	if err != nil {
-		http.Error(w, constants.ErrFailedToUpdateSnippet, http.StatusInternalServerError)
-		log.Println(err)
+	if errors.Is(err, database.ErrVersionMismatch) {
+		h.writeSnippetChanged(w, r, snippetID, userID)
+		return
+	}
+	if !writeSnippetError(w, err, problem.FailedToUpdateSnippet) {
 		return
 	}
 
-	log.Println("Updated snippet!")
+	logging.FromContext(r.Context()).Info("updated snippet", "snippet_id", snippetID)
+	w.Header().Set("ETag", snippetETag(snippet))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(snippet)
 }
 
-func getSnippetByID(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+const (
+	// maxPatchAttempts bounds how often an unconditional PATCH is merged
+	// again after losing a race with another change to the snippet.
+	maxPatchAttempts = 3
+	// maxPatchBytes is the largest merge patch read.
+	maxPatchBytes = 1 << 20
+)
+
+// patchSnippetByID applies a JSON merge patch to the snippet. If-Match is
+// optional here: without it the patch is merged into whatever version is
+// current, since it only replaces the fields it names.
+func (h *Handler) patchSnippetByID(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != helper.MergePatchContentType {
+		w.Header().Set("Accept-Patch", helper.MergePatchContentType)
+		problem.Write(w, problem.UnsupportedMedia.WithDetail("send "+helper.MergePatchContentType))
+		return
+	}
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		problem.Write(w, problem.PayloadTooLarge)
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.InvalidPayload)
+		return
+	}
 	userID, err := auth.ExtractUserIDFromToken(r)
 	if err != nil {
-		http.Error(w, constants.ErrFailedToGetUserID+": "+err.Error(), http.StatusBadRequest)
+		problem.Write(w, problem.FailedToGetUserID)
 		return
 	}
+	version, conditional := ifMatchVersion(r)
 
-	snippet, err := database.GetSnippetByID(snippetID, userID)
-	if err != nil {
-		http.Error(
-			w,
-			constants.ErrFailedToGetSnippets+": "+err.Error(),
-			http.StatusInternalServerError,
+	for attempt := 1; ; attempt++ {
+		current, err := h.Snippets.GetSnippetByID(r.Context(), snippetID, userID)
+		if !writeSnippetError(w, err, problem.FailedToGetSnippets) {
+			return
+		}
+		if version != 0 && version != current.Version {
+			w.Header().Set("ETag", snippetETag(current))
+			problem.WriteCurrent(w, problem.SnippetChanged, current)
+			return
+		}
+		merged, err := mergeSnippet(current, patch)
+		var kind problem.Kind
+		if errors.As(err, &kind) {
+			problem.Write(w, kind)
+			return
+		}
+		if err != nil {
+			problem.WriteValidation(w, err)
+			return
+		}
+
+		snippet, err := h.Snippets.UpdateSnippet(
+			r.Context(),
+			merged.Title,
+			merged.Language,
+			merged.Content,
+			merged.Tags,
+			merged.Visibility,
+			snippetID,
+			userID,
+			current.Version,
 		)
-		log.Println(err)
+		if errors.Is(err, database.ErrVersionMismatch) {
+			if !conditional && attempt < maxPatchAttempts {
+				continue
+			}
+			h.writeSnippetChanged(w, r, snippetID, userID)
+			return
+		}
+		if !writeSnippetError(w, err, problem.FailedToUpdateSnippet) {
+			return
+		}
+		logging.FromContext(r.Context()).Info("patched snippet", "snippet_id", snippetID)
+		w.Header().Set("ETag", snippetETag(snippet))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snippet)
 		return
 	}
-	log.Println("Snippet fetched from ID")
+}
+
+// mergeSnippet applies a merge patch to snippet and validates the result the
+// same way a full update is validated. Read-only members such as snippet_id
+// and version are ignored, as they are on PUT.
+func mergeSnippet(snippet models.Snippet, patch []byte) (models.Snippet, error) {
+	doc, err := json.Marshal(snippet)
+	if err != nil {
+		return models.Snippet{}, err
+	}
+	doc, err = helper.MergePatch(doc, patch)
+	if errors.Is(err, helper.ErrInvalidMergePatch) {
+		return models.Snippet{}, problem.InvalidPayload.WithDetail(err.Error())
+	}
+	if err != nil {
+		return models.Snippet{}, problem.InvalidPayload
+	}
+	var merged models.Snippet
+	if err := json.Unmarshal(doc, &merged); err != nil {
+		return models.Snippet{}, problem.InvalidPayload
+	}
+	// "tags": null removes every tag rather than leaving them untouched
+	if merged.Tags == nil {
+		merged.Tags = []string{}
+	}
+	merged.Tags = helper.NormalizeTags(merged.Tags)
+	if err := helper.ValidateSnippet(merged); err != nil {
+		return models.Snippet{}, err
+	}
+	return merged, nil
+}
+
+func (h *Handler) getSnippetByID(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	snippet, err := h.Snippets.GetSnippetByID(r.Context(), snippetID, userID)
+	if !writeSnippetError(w, err, problem.FailedToGetSnippets) {
+		return
+	}
+	etag := snippetETag(snippet)
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	logging.FromContext(r.Context()).Debug("fetched snippet", "snippet_id", snippetID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(snippet)
 }
 
-func deleteSnippetByID(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+func (h *Handler) deleteSnippetByID(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+	version, ok := ifMatchVersion(r)
+	if !ok {
+		problem.Write(w, problem.IfMatchRequired)
+		return
+	}
 	userID, err := auth.ExtractUserIDFromToken(r)
 	if err != nil {
-		http.Error(w, constants.ErrFailedToGetUserID+": "+err.Error(), http.StatusBadRequest)
+		problem.Write(w, problem.FailedToGetUserID)
 		return
 	}
 
-	snippet, err := database.DeleteSnippetByID(snippetID, userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, constants.ErrSnippetNotFound, http.StatusBadRequest)
-			return
-		}
-		http.Error(w, constants.ErrFailedToDeleteSnippet, http.StatusInternalServerError)
+	snippet, err := h.Snippets.DeleteSnippetByID(r.Context(), snippetID, userID, version)
+	if errors.Is(err, database.ErrVersionMismatch) {
+		h.writeSnippetChanged(w, r, snippetID, userID)
 		return
 	}
-	log.Println("snippet deleted from DB")
+	if !writeSnippetError(w, err, problem.FailedToDeleteSnippet) {
+		return
+	}
+	logging.FromContext(r.Context()).Info("moved snippet to the trash", "snippet_id", snippetID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(snippet)
 }
+
+// writeSnippetChanged rejects a change made against a stale version of the
+// snippet, sending the current version so the client can merge and retry.
+func (h *Handler) writeSnippetChanged(
+	w http.ResponseWriter,
+	r *http.Request,
+	snippetID, userID uuid.UUID,
+) {
+	current, err := h.Snippets.GetSnippetByID(r.Context(), snippetID, userID)
+	if !writeSnippetError(w, err, problem.FailedToGetSnippets) {
+		return
+	}
+	w.Header().Set("ETag", snippetETag(current))
+	problem.WriteCurrent(w, problem.SnippetChanged, current)
+}
+
+// writeSnippetError maps a store error about a single snippet to a response
+// and reports whether the request can continue.
+func writeSnippetError(w http.ResponseWriter, err error, failure problem.Kind) bool {
+	if err == nil {
+		return true
+	}
+	problem.WriteError(w, err, snippetProblem(err, failure))
+	return false
+}
+
+// snippetProblem is the Kind a store error about a single snippet is served
+// as, failure when it isn't one the client can act on.
+func snippetProblem(err error, failure problem.Kind) problem.Kind {
+	switch {
+	case errors.Is(err, database.ErrSnippetNotFound):
+		return problem.SnippetNotFound
+	case errors.Is(err, database.ErrOrgNotFound):
+		return problem.OrgNotFound
+	case errors.Is(err, database.ErrForbidden):
+		return problem.AccessDenied
+	case errors.Is(err, database.ErrVersionMismatch):
+		return problem.SnippetChanged
+	case errors.Is(err, database.ErrNotFound):
+		return problem.NotFound
+	default:
+		return problem.From(err, failure)
+	}
+}
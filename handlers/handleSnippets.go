@@ -2,155 +2,243 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
 
-	"github.com/Jitesh117/snippet-manager-backend/constants"
 	"github.com/Jitesh117/snippet-manager-backend/database"
 	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
 	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
 	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
 	"github.com/google/uuid"
 )
 
-func HandleSnippets(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleSnippets(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		getAllSnippets(w)
+		h.getAllSnippets(w, r)
 	case http.MethodPost:
-		createSnippet(w, r)
+		h.createSnippet(w, r)
 	default:
 		w.Header().Set("Allow", "GET, POST")
-		http.Error(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+		problem.Write(w, problem.MethodNotAllowed)
 	}
 }
 
-func getAllSnippets(w http.ResponseWriter) {
-	snippets, err := database.GetAllSnippets()
+func (h *Handler) getAllSnippets(w http.ResponseWriter, r *http.Request) {
+	if tags := r.URL.Query()["tag"]; len(tags) > 0 {
+		h.getSnippetsByTags(w, r, tags)
+		return
+	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
 	if err != nil {
-		http.Error(w, constants.ErrFailedToGetSnippets, http.StatusInternalServerError)
+		problem.Write(w, problem.FailedToGetUserID)
 		return
 	}
-	log.Println("Got all Snippets")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(snippets)
+	page, err := parsePage(r, "created_at", "asc")
+	if err != nil {
+		problem.WriteError(w, err, problem.InvalidPayload)
+		return
+	}
+
+	result, err := h.Snippets.GetAllSnippets(r.Context(), userID, page)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGetSnippets)
+		return
+	}
+	logging.FromContext(r.Context()).Debug("listed snippets", "count", len(result.Snippets))
+	writeSnippetPage(w, page, result)
 }
 
-func createSnippet(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) createSnippet(w http.ResponseWriter, r *http.Request) {
 	var requestSnippet models.Snippet
 	var userID uuid.UUID
 
 	if err := json.NewDecoder(r.Body).Decode(&requestSnippet); err != nil {
-		http.Error(w, constants.ErrInvalidPayload, http.StatusBadRequest)
+		problem.Write(w, problem.InvalidPayload)
 		return
 	}
+	requestSnippet.Tags = helper.NormalizeTags(requestSnippet.Tags)
 	if err := helper.ValidateSnippet(requestSnippet); err != nil {
-		http.Error(w, constants.ErrInvalidPayload+": "+err.Error(), http.StatusBadRequest)
+		problem.WriteValidation(w, err)
 		return
 	}
+	if requestSnippet.Visibility == "" {
+		requestSnippet.Visibility = models.VisibilityPrivate
+	}
 
 	userID, err := auth.ExtractUserIDFromToken(r)
 	if err != nil {
-		http.Error(w, constants.ErrFailedToExtractTokenID+": "+err.Error(), http.StatusBadRequest)
+		problem.Write(w, problem.FailedToGetUserID)
 		return
 	}
 
-	snippet, err := database.CreateSnippet(
+	snippet, err := h.Snippets.CreateSnippet(
+		r.Context(),
 		requestSnippet.Title,
 		requestSnippet.Language,
 		requestSnippet.Content,
+		requestSnippet.Tags,
+		requestSnippet.Visibility,
+		requestSnippet.OrgID,
 		userID,
 	)
+	if errors.Is(err, database.ErrOrgNotFound) || errors.Is(err, database.ErrForbidden) {
+		writeOrgError(w, err, problem.FailedToCreateSnippet)
+		return
+	}
 	if err != nil {
-		http.Error(w, constants.ErrFailedToCreateSnippet, http.StatusInternalServerError)
-		log.Println(err)
+		problem.WriteError(w, err, problem.FailedToCreateSnippet)
 		return
 	}
-	log.Println("Created snippet!")
-	w.WriteHeader(http.StatusCreated)
+	logging.FromContext(r.Context()).Info("created snippet", "snippet_id", snippet.SnippetId)
+	w.Header().Set("ETag", snippetETag(snippet))
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(snippet)
 }
 
-func GetSnippetByLanguage(w http.ResponseWriter, r *http.Request) {
+// getSnippetsByTags serves GET /snippets?tag=a&tag=b. By default a snippet must
+// have every tag; match=any returns snippets with at least one of them.
+func (h *Handler) getSnippetsByTags(w http.ResponseWriter, r *http.Request, tags []string) {
+	match := r.URL.Query().Get("match")
+	if match == "" {
+		match = "all"
+	}
+	if match != "all" && match != "any" {
+		problem.Write(w, problem.InvalidTagMatch)
+		return
+	}
+	tags = helper.NormalizeTags(tags)
+	for _, tag := range tags {
+		if err := helper.ValidateTag(tag); err != nil {
+			problem.WriteValidation(w, err)
+			return
+		}
+	}
+	page, err := parsePage(r, "updated_at", "desc")
+	if err != nil {
+		problem.WriteError(w, err, problem.InvalidPayload)
+		return
+	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	result, err := h.Snippets.GetSnippetsByTags(r.Context(), userID, tags, match == "all", page)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGetSnippets)
+		return
+	}
+	writeSnippetPage(w, page, result)
+}
+
+func (h *Handler) GetSnippetByLanguage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.Header().Set("Allow", "GET")
-		http.Error(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+		problem.Write(w, problem.MethodNotAllowed)
 		return
 	}
 
 	// Extract language from the URL query parameters
 	language := r.URL.Query().Get("language")
 	if language == "" {
-		http.Error(
-			w,
-			constants.ErrInvalidPayload+": "+"Missing language parameter",
-			http.StatusBadRequest,
-		)
+		problem.Write(w, problem.MissingLanguage)
+		return
+	}
+	page, err := parsePage(r, "updated_at", "desc")
+	if err != nil {
+		problem.WriteError(w, err, problem.InvalidPayload)
 		return
 	}
 
 	userID, err := auth.ExtractUserIDFromToken(r)
 	if err != nil {
-		http.Error(w, constants.ErrFailedToGetUserID, http.StatusUnauthorized)
+		problem.Write(w, problem.FailedToGetUserID)
 		return
 	}
 
-	snippets, err := database.GetSnippetsByLanguage(language, userID)
+	result, err := h.Snippets.GetSnippetsByLanguage(r.Context(), language, userID, page)
 	if err != nil {
-		http.Error(
-			w,
-			constants.ErrFailedToGetSnippets,
-			http.StatusInternalServerError,
-		)
+		problem.WriteError(w, err, problem.FailedToGetSnippets)
 		return
 	}
+	writeSnippetPage(w, page, result)
+}
 
-	if len(snippets) == 0 {
-		http.Error(w, constants.ErrSnippetNotFound, http.StatusNotFound)
+func (h *Handler) GetSortedSnippets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		problem.Write(w, problem.MethodNotAllowed)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(snippets)
+	page, err := parsePage(r, "created_at", "asc")
 	if err != nil {
-		http.Error(w, "Failed to encode snippets to JSON", http.StatusInternalServerError)
+		problem.WriteError(w, err, problem.InvalidPayload)
 		return
 	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	result, err := h.Snippets.GetSnippetsSorted(r.Context(), userID, page)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGetSnippets)
+		return
+	}
+	writeSnippetPage(w, page, result)
 }
 
-func GetSortedSnippets(w http.ResponseWriter, r *http.Request) {
+// SearchSnippets serves GET /snippets/search?q=, a page of the snippets the
+// caller can read that match the query, most relevant first. Pages are
+// walked with cursors like the other listings, but only sort by rank.
+func (h *Handler) SearchSnippets(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.Header().Set("Allow", "GET")
-		http.Error(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+		problem.Write(w, problem.MethodNotAllowed)
 		return
 	}
 
-	sortBy := r.URL.Query().Get("sort_by")
-	order := r.URL.Query().Get("order")
-	if sortBy == "" {
-		sortBy = "created_at"
-	}
-
-	if order == "" {
-		order = "asc"
+	terms, err := helper.ParseSearchQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		problem.Write(w, problem.InvalidSearchQuery.WithDetail(err.Error()))
+		return
 	}
-	if !helper.IsValidSortField(sortBy) || !helper.IsValidOrder(order) {
-		http.Error(w, constants.ErrInvalidSortOptions, http.StatusBadRequest)
+	page, err := parseSearchPage(r)
+	if err != nil {
+		problem.WriteError(w, err, problem.InvalidPayload)
 		return
 	}
 
 	userID, err := auth.ExtractUserIDFromToken(r)
 	if err != nil {
-		http.Error(w, constants.ErrFailedToGetUserID, http.StatusUnauthorized)
+		problem.Write(w, problem.FailedToGetUserID)
 		return
 	}
 
-	snippets, err := database.GetSnippetsSorted(userID, sortBy, order)
+	result, err := h.Snippets.SearchSnippets(r.Context(), userID, terms, page)
 	if err != nil {
-		http.Error(w, constants.ErrFailedToGetSnippets, http.StatusInternalServerError)
+		problem.WriteError(w, err, problem.FailedToSearchSnippets)
 		return
 	}
+	response := listResponse[models.SnippetSearchResult]{
+		Items:   result.Results,
+		HasMore: result.HasMore,
+		Limit:   page.Limit,
+	}
+	if result.HasMore {
+		last := result.Results[len(result.Results)-1]
+		response.NextCursor = helper.EncodeCursor(page.CursorForResult(last))
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(snippets)
+	json.NewEncoder(w).Encode(response)
 }
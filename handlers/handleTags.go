@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Jitesh117/snippet-manager-backend/database"
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/google/uuid"
+)
+
+// HandleTags serves GET /tags, the caller's tags with how many snippets use
+// each one.
+func (h *Handler) HandleTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	tags, err := h.Snippets.ListTags(r.Context(), userID)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGetTags)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// HandleTag serves PUT /tags/{name} to rename a tag and
+// POST /tags/{name}/merge to fold it into another tag.
+func (h *Handler) HandleTag(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path[len("/tags/"):]
+	name, action, _ := strings.Cut(path, "/")
+	name = strings.ToLower(name)
+	if err := helper.ValidateTag(name); err != nil {
+		problem.WriteValidation(w, err)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodPut:
+		h.renameTag(w, r, name)
+	case action == "merge" && r.Method == http.MethodPost:
+		h.mergeTag(w, r, name)
+	case action == "":
+		w.Header().Set("Allow", "PUT")
+		problem.Write(w, problem.MethodNotAllowed)
+	case action == "merge":
+		w.Header().Set("Allow", "POST")
+		problem.Write(w, problem.MethodNotAllowed)
+	default:
+		problem.Write(w, problem.NotFound)
+	}
+}
+
+func (h *Handler) renameTag(w http.ResponseWriter, r *http.Request, name string) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	newName, userID, ok := decodeTagTarget(w, r, &request, &request.Name)
+	if !ok {
+		return
+	}
+
+	err := h.Snippets.RenameTag(r.Context(), userID, name, newName)
+	if !writeTagError(w, err, problem.FailedToRenameTag) {
+		return
+	}
+	logging.FromContext(r.Context()).Info("renamed tag", "tag", name, "new_name", newName)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"name": newName})
+}
+
+func (h *Handler) mergeTag(w http.ResponseWriter, r *http.Request, name string) {
+	var request struct {
+		Into string `json:"into"`
+	}
+	target, userID, ok := decodeTagTarget(w, r, &request, &request.Into)
+	if !ok {
+		return
+	}
+	if target == name {
+		problem.Write(w, problem.MergeIntoItself)
+		return
+	}
+
+	err := h.Snippets.MergeTags(r.Context(), userID, name, target)
+	if !writeTagError(w, err, problem.FailedToMergeTags) {
+		return
+	}
+	logging.FromContext(r.Context()).Info("merged tags", "tag", name, "into", target)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"name": target})
+}
+
+// decodeTagTarget decodes request, normalizes and validates the tag name it
+// points at and extracts the caller. It writes the error response itself and
+// reports false if anything failed.
+func decodeTagTarget(
+	w http.ResponseWriter,
+	r *http.Request,
+	request any,
+	field *string,
+) (string, uuid.UUID, bool) {
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		problem.Write(w, problem.InvalidPayload)
+		return "", uuid.Nil, false
+	}
+	target := strings.ToLower(strings.TrimSpace(*field))
+	if err := helper.ValidateTag(target); err != nil {
+		problem.WriteValidation(w, err)
+		return "", uuid.Nil, false
+	}
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return "", uuid.Nil, false
+	}
+	return target, userID, true
+}
+
+// writeTagError maps a tag store error to a response and reports whether
+// the request can continue.
+func writeTagError(w http.ResponseWriter, err error, failure problem.Kind) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, database.ErrTagNotFound):
+		problem.Write(w, problem.TagNotFound)
+	case errors.Is(err, database.ErrTagExists):
+		problem.Write(w, problem.TagAlreadyExists)
+	default:
+		problem.WriteError(w, err, failure)
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/constants"
+	"github.com/Jitesh117/snippet-manager-backend/database"
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/google/uuid"
+)
+
+// HandleAccessTokens serves GET /tokens, the caller's personal access tokens,
+// and POST /tokens to create one.
+func (h *Handler) HandleAccessTokens(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listAccessTokens(w, r)
+	case http.MethodPost:
+		h.createAccessToken(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		problem.Write(w, problem.MethodNotAllowed)
+	}
+}
+
+// HandleAccessToken serves
+//
+//	DELETE /tokens/{id}
+//	POST   /tokens/{id}/rotate
+func (h *Handler) HandleAccessToken(w http.ResponseWriter, r *http.Request) {
+	idStr, action, _ := strings.Cut(r.URL.Path[len("/tokens/"):], "/")
+	tokenID, err := uuid.Parse(idStr)
+	if err != nil {
+		problem.Write(w, problem.InvalidAccessTokenID)
+		return
+	}
+
+	switch action {
+	case "":
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "DELETE")
+			problem.Write(w, problem.MethodNotAllowed)
+			return
+		}
+		h.revokeAccessToken(w, r, tokenID)
+	case "rotate":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			problem.Write(w, problem.MethodNotAllowed)
+			return
+		}
+		h.rotateAccessToken(w, r, tokenID)
+	default:
+		problem.Write(w, problem.NotFound)
+	}
+}
+
+func (h *Handler) createAccessToken(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		problem.Write(w, problem.InvalidPayload)
+		return
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if err := helper.ValidateAccessToken(request.Name, request.Scopes); err != nil {
+		problem.WriteValidation(w, err)
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		problem.Write(w, problem.InvalidPayload.WithDetail(constants.ErrInvalidExpiry))
+		return
+	}
+	slices.Sort(request.Scopes)
+	request.Scopes = slices.Compact(request.Scopes)
+
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	token, err := h.Users.CreateAccessToken(
+		r.Context(),
+		userID,
+		request.Name,
+		request.Scopes,
+		request.ExpiresAt,
+	)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToCreateAccessToken)
+		return
+	}
+	logging.FromContext(r.Context()).Info("created access token", "token_id", token.TokenID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+func (h *Handler) listAccessTokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	tokens, err := h.Users.ListAccessTokens(r.Context(), userID)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGetAccessTokens)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// rotateAccessToken issues a new secret for the token; the old one stops
+// working right away.
+func (h *Handler) rotateAccessToken(w http.ResponseWriter, r *http.Request, tokenID uuid.UUID) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	token, err := h.Users.RotateAccessToken(r.Context(), tokenID, userID)
+	if !writeAccessTokenError(w, err, problem.FailedToRotateAccessToken) {
+		return
+	}
+	logging.FromContext(r.Context()).Info("rotated access token", "token_id", tokenID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(token)
+}
+
+func (h *Handler) revokeAccessToken(w http.ResponseWriter, r *http.Request, tokenID uuid.UUID) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	err = h.Users.RevokeAccessToken(r.Context(), tokenID, userID)
+	if !writeAccessTokenError(w, err, problem.FailedToRevokeAccessToken) {
+		return
+	}
+	logging.FromContext(r.Context()).Info("revoked access token", "token_id", tokenID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAccessTokenError maps an access token store error to a response and
+// reports whether the request can continue.
+func writeAccessTokenError(w http.ResponseWriter, err error, failure problem.Kind) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, database.ErrAccessTokenNotFound):
+		problem.Write(w, problem.AccessTokenNotFound)
+	default:
+		problem.WriteError(w, err, failure)
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/google/uuid"
+)
+
+// trashedSnippet is a snippet in the trash and when it will be purged.
+type trashedSnippet struct {
+	models.Snippet
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
+}
+
+// HandleTrash serves GET /trash, a page of the deleted snippets the caller
+// can still restore, most recently deleted first by default.
+func (h *Handler) HandleTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+	page, err := parseTrashPage(r)
+	if err != nil {
+		problem.WriteError(w, err, problem.InvalidPayload)
+		return
+	}
+
+	result, err := h.Snippets.ListTrash(r.Context(), userID, page)
+	if err != nil {
+		problem.WriteError(w, err, problem.FailedToGetTrash)
+		return
+	}
+	trash := make([]trashedSnippet, len(result.Snippets))
+	for i, snippet := range result.Snippets {
+		trash[i].Snippet = snippet
+		if snippet.DeletedAt != nil && h.TrashRetention > 0 {
+			purgeAt := snippet.DeletedAt.Add(h.TrashRetention)
+			trash[i].PurgeAt = &purgeAt
+		}
+	}
+	writePage(w, page, result, trash)
+}
+
+// HandleTrashedSnippet serves a snippet in the trash:
+//
+//	POST   /trash/{id}/restore
+//	DELETE /trash/{id}
+func (h *Handler) HandleTrashedSnippet(w http.ResponseWriter, r *http.Request) {
+	idStr, rest, _ := strings.Cut(r.URL.Path[len("/trash/"):], "/")
+	snippetID, err := uuid.Parse(idStr)
+	if err != nil {
+		problem.Write(w, problem.InvalidSnippetID)
+		return
+	}
+	switch rest {
+	case "restore":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			problem.Write(w, problem.MethodNotAllowed)
+			return
+		}
+		h.restoreSnippet(w, r, snippetID)
+	case "":
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "DELETE")
+			problem.Write(w, problem.MethodNotAllowed)
+			return
+		}
+		h.purgeSnippet(w, r, snippetID)
+	default:
+		problem.Write(w, problem.NotFound)
+	}
+}
+
+func (h *Handler) restoreSnippet(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	snippet, err := h.Snippets.RestoreSnippet(r.Context(), snippetID, userID)
+	if !writeSnippetError(w, err, problem.FailedToRestoreSnippet) {
+		return
+	}
+	logging.FromContext(r.Context()).Info(
+		"restored snippet from the trash",
+		"snippet_id", snippetID,
+	)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", snippetETag(snippet))
+	json.NewEncoder(w).Encode(snippet)
+}
+
+func (h *Handler) purgeSnippet(w http.ResponseWriter, r *http.Request, snippetID uuid.UUID) {
+	userID, err := auth.ExtractUserIDFromToken(r)
+	if err != nil {
+		problem.Write(w, problem.FailedToGetUserID)
+		return
+	}
+
+	err = h.Snippets.PurgeSnippet(r.Context(), snippetID, userID)
+	if !writeSnippetError(w, err, problem.FailedToPurgeSnippet) {
+		return
+	}
+	logging.FromContext(r.Context()).Info("purged snippet from the trash", "snippet_id", snippetID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,54 +2,55 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
+	"time"
 
-	"github.com/Jitesh117/snippet-manager-backend/constants"
 	"github.com/Jitesh117/snippet-manager-backend/database"
 	"github.com/Jitesh117/snippet-manager-backend/helper"
-	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
 	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
 )
 
-func RegisterUser(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.Header().Set("Allow", "POST")
-		http.Error(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+		problem.Write(w, problem.MethodNotAllowed)
 		return
 	}
 	var user models.User
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
-		http.Error(w, constants.ErrInvalidPayload, http.StatusBadRequest)
+		problem.Write(w, problem.InvalidPayload)
 		return
 	}
 	if err := helper.ValidateUser(user); err != nil {
-		http.Error(w, constants.ErrInvalidPayload+": "+err.Error(), http.StatusBadRequest)
+		problem.WriteValidation(w, err)
 		return
 	}
 
-	userID, err := database.CreateUser(user)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	userID, err := h.Users.CreateUser(r.Context(), user)
+	switch {
+	case errors.Is(err, database.ErrUsernameTaken):
+		problem.Write(w, problem.UsernameTaken)
 		return
-	}
-	token, err := auth.GenerateJWT(userID)
-	if err != nil {
-		http.Error(w, constants.ErrFailedToGenerateToken, http.StatusInternalServerError)
+	case errors.Is(err, database.ErrEmailTaken):
+		problem.Write(w, problem.EmailTaken)
+		return
+	case err != nil:
+		problem.WriteError(w, err, problem.FailedToCreateUser)
 		return
 	}
 
-	log.Println("userID: ", userID)
-	log.Println("user signed in!")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	logging.FromContext(r.Context()).Info("user registered", "user_id", userID)
+	h.startSession(w, r, userID)
 }
 
-func LoginUser(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.Header().Set("Allow", "POST")
-		http.Error(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+		problem.Write(w, problem.MethodNotAllowed)
 		return
 	}
 	var loginData struct {
@@ -58,36 +59,25 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 	}
 	err := json.NewDecoder(r.Body).Decode(&loginData)
 	if err != nil {
-		http.Error(w, constants.ErrInvalidPayload, http.StatusBadRequest)
+		problem.Write(w, problem.InvalidPayload)
 		return
 	}
 
-	userID, err := database.CheckUserCredentials(loginData.Email, loginData.Password)
-	if err != nil {
-		http.Error(w, constants.ErrInvalidCredentials, http.StatusUnauthorized)
+	userID, err := h.Users.CheckUserCredentials(r.Context(), loginData.Email, loginData.Password)
+	if !writeCredentialsError(w, err) {
 		return
 	}
-	log.Println(userID)
-
-	token, err := auth.GenerateJWT(userID)
-	if err != nil {
-		http.Error(
-			w,
-			constants.ErrFailedToGenerateToken+": "+err.Error(),
-			http.StatusInternalServerError,
-		)
-		return
-	}
-
-	log.Println("user logged in!")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	logging.FromContext(r.Context()).Info("user logged in", "user_id", userID)
+	h.startSession(w, r, userID)
 }
 
-func DeleteUserByID(w http.ResponseWriter, r *http.Request) {
+// DeleteUserByID closes the account of the given credentials. Its personal
+// snippets go to the trash and RestoreUser brings both back until the trash
+// is purged, which the response's purge_at says when it is enabled.
+func (h *Handler) DeleteUserByID(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		w.Header().Set("Allow", "DELETE")
-		http.Error(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+		problem.Write(w, problem.MethodNotAllowed)
 		return
 	}
 
@@ -98,29 +88,59 @@ func DeleteUserByID(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&userData)
 	if err != nil {
-		http.Error(w, constants.ErrInvalidPayload, http.StatusBadRequest)
+		problem.Write(w, problem.InvalidPayload)
 		return
 	}
 
-	userID, err := database.CheckUserCredentials(userData.Email, userData.Password)
-	if err != nil {
-		http.Error(w, constants.ErrInvalidCredentials, http.StatusUnauthorized)
+	userID, err := h.Users.CheckUserCredentials(r.Context(), userData.Email, userData.Password)
+	if !writeCredentialsError(w, err) {
 		return
 	}
-	deletedUserID, err := database.DeleteUser(userID)
+	deletedUserID, err := h.Users.DeleteUser(r.Context(), userID)
 	if err != nil {
-		http.Error(w, constants.ErrFailedToDeleteUser, http.StatusInternalServerError)
+		problem.WriteError(w, err, problem.FailedToDeleteUser)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("user deleted", "user_id", deletedUserID)
+	response := map[string]string{"userID": deletedUserID.String()}
+	if h.TrashRetention > 0 {
+		response["purge_at"] = time.Now().UTC().Add(h.TrashRetention).Format(time.RFC3339)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// RestoreUser serves POST /restoreUser, which reopens an account closed by
+// DeleteUserByID given its credentials and logs the user in.
+func (h *Handler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		problem.Write(w, problem.MethodNotAllowed)
+		return
+	}
+	var userData struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&userData); err != nil {
+		problem.Write(w, problem.InvalidPayload)
 		return
 	}
 
-	log.Println("user deleted!")
-	json.NewEncoder(w).Encode(map[string]string{"userID": deletedUserID.String()})
+	userID, err := h.Users.RestoreUser(r.Context(), userData.Email, userData.Password)
+	if !writeCredentialsError(w, err) {
+		return
+	}
+	logging.FromContext(r.Context()).Info("user restored", "user_id", userID)
+	h.startSession(w, r, userID)
 }
 
-func ChangePassword(w http.ResponseWriter, r *http.Request) {
+// ChangePassword ends every session of the user, including the caller's, and
+// revokes their personal access tokens.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		w.Header().Set("Allow", "PUT")
-		http.Error(w, constants.ErrMethodNotAllowed, http.StatusMethodNotAllowed)
+		problem.Write(w, problem.MethodNotAllowed)
 		return
 	}
 
@@ -132,34 +152,43 @@ func ChangePassword(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&userData)
 	if err != nil {
-		http.Error(w, constants.ErrInvalidPayload, http.StatusBadRequest)
+		problem.Write(w, problem.InvalidPayload)
 		return
 	}
 
 	if userData.Password == userData.NewPassword {
-		http.Error(w, "New password must be different from the old password", http.StatusBadRequest)
+		problem.Write(w, problem.PasswordUnchanged)
 		return
 	}
 
-	userID, err := database.CheckUserCredentials(userData.Email, userData.Password)
-	if err != nil {
-		http.Error(w, constants.ErrInvalidCredentials, http.StatusUnauthorized)
+	userID, err := h.Users.CheckUserCredentials(r.Context(), userData.Email, userData.Password)
+	if !writeCredentialsError(w, err) {
 		return
 	}
-	err = helper.ValidatePassword(userData.NewPassword)
+	err = helper.ValidatePassword("new_password", userData.NewPassword)
 	if err != nil {
-		http.Error(w, constants.ErrInvalidPayload+": "+err.Error(), http.StatusBadRequest)
+		problem.WriteValidation(w, err)
 		return
 	}
-	err = database.ChangePassword(userID, userData.NewPassword)
+	err = h.Users.ChangePassword(r.Context(), userID, userData.NewPassword)
 	if err != nil {
-		http.Error(
-			w,
-			constants.ErrFailedToUpdatePassword+": "+err.Error(),
-			http.StatusInternalServerError,
-		)
+		problem.WriteError(w, err, problem.FailedToUpdatePassword)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Password updated successfully"))
 }
+
+// writeCredentialsError maps a CheckUserCredentials error to a response and
+// reports whether the request can continue.
+func writeCredentialsError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, database.ErrInvalidCredentials):
+		problem.Write(w, problem.InvalidCredentials)
+	default:
+		problem.WriteError(w, err, problem.FailedToAuthenticate)
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/database"
+)
+
+// Handler serves the HTTP API on top of the injected stores.
+type Handler struct {
+	Snippets database.SnippetStore
+	Users    database.UserStore
+	Orgs     database.OrgStore
+	// TrashRetention is how long deleted snippets stay in the trash, zero
+	// when they are never purged.
+	TrashRetention time.Duration
+	// Ready checks the dependencies the readiness probe reports on. Nil
+	// means there is nothing to check.
+	Ready func(ctx context.Context) error
+
+	draining atomic.Bool
+}
+
+func New(
+	snippets database.SnippetStore,
+	users database.UserStore,
+	orgs database.OrgStore,
+) *Handler {
+	return &Handler{Snippets: snippets, Users: users, Orgs: orgs}
+}
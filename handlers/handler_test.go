@@ -2,22 +2,42 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Jitesh117/snippet-manager-backend/database"
 	"github.com/Jitesh117/snippet-manager-backend/handlers"
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/library"
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
 	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/google/uuid"
 )
 
-var jwtTokenString, snippetID string
+var (
+	jwtTokenString, snippetID string
+	h                         *handlers.Handler
+)
 
 func TestMain(m *testing.M) {
-	database.InitDB()
-	m.Run()
-	database.CloseDB()
+	store := database.NewMemoryStore()
+	auth.Sessions = store
+	auth.AccessTokens = store
+	h = handlers.New(store, store, store)
+	h.TrashRetention = 30 * 24 * time.Hour
+	os.Exit(m.Run())
 }
 
 func TestRegister(t *testing.T) {
@@ -32,7 +52,7 @@ func TestRegister(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.RegisterUser)
+	handler := http.HandlerFunc(h.RegisterUser)
 
 	handler.ServeHTTP(rr, req)
 
@@ -64,7 +84,7 @@ func TestLogin(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.LoginUser)
+	handler := http.HandlerFunc(h.LoginUser)
 
 	handler.ServeHTTP(rr, req)
 
@@ -88,7 +108,7 @@ func TestChangePassword(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.ChangePassword)
+	handler := http.HandlerFunc(h.ChangePassword)
 
 	handler.ServeHTTP(rr, req)
 
@@ -97,6 +117,178 @@ func TestChangePassword(t *testing.T) {
 	}
 }
 
+type tokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func TestSessions(t *testing.T) {
+	user := models.User{
+		UserName: "sessionUser",
+		Email:    "sessions@testNew.com",
+		Password: "Password@123",
+	}
+	var first tokens
+	rr := doRequest(t, h.RegisterUser, http.MethodPost, "/register", user)
+	json.NewDecoder(rr.Body).Decode(&first)
+	if first.Token == "" || first.RefreshToken == "" {
+		t.Fatalf("register returned %v: %s", rr.Code, rr.Body)
+	}
+
+	protected := auth.JWTAuthMiddleware(h.HandleTags, auth.SnippetScope)
+	authorized := func(token string) bool {
+		t.Helper()
+		return doRequestAs(t, token, protected, http.MethodGet, "/tags", nil).Code == http.StatusOK
+	}
+	refresh := func(refreshToken string) (*httptest.ResponseRecorder, tokens) {
+		t.Helper()
+		var next tokens
+		body := map[string]string{"refresh_token": refreshToken}
+		rr := doRequest(t, h.RefreshToken, http.MethodPost, "/token/refresh", body)
+		json.NewDecoder(rr.Body).Decode(&next)
+		return rr, next
+	}
+
+	rr, second := refresh(first.RefreshToken)
+	if rr.Code != http.StatusOK || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("refresh returned %v: %+v", rr.Code, second)
+	}
+	if !authorized(second.Token) {
+		t.Fatalf("refreshed access token was rejected")
+	}
+
+	// Replaying the rotated token revokes the whole family.
+	if rr, _ := refresh(first.RefreshToken); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("reused refresh token returned %v", rr.Code)
+	}
+	if rr, _ := refresh(second.RefreshToken); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh token of a revoked session returned %v", rr.Code)
+	}
+	if authorized(second.Token) {
+		t.Fatalf("access token of a revoked session still works")
+	}
+
+	var login tokens
+	rr = doRequest(t, h.LoginUser, http.MethodPost, "/login", user)
+	json.NewDecoder(rr.Body).Decode(&login)
+	logout := auth.JWTAuthMiddleware(h.Logout, nil)
+	rr = doRequestAs(t, login.Token, logout, http.MethodPost, "/logout", nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("logout returned %v: %s", rr.Code, rr.Body)
+	}
+	if authorized(login.Token) {
+		t.Fatalf("access token still works after logout")
+	}
+	if rr, _ := refresh(login.RefreshToken); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh after logout returned %v", rr.Code)
+	}
+
+	rr = doRequest(t, h.LoginUser, http.MethodPost, "/login", user)
+	json.NewDecoder(rr.Body).Decode(&login)
+	change := map[string]string{
+		"email":        user.Email,
+		"password":     user.Password,
+		"new_password": "NewPassword@123",
+	}
+	doRequest(t, h.ChangePassword, http.MethodPut, "/changePassword", change)
+	if authorized(login.Token) {
+		t.Fatalf("access token still works after a password change")
+	}
+
+	user.Password = "NewPassword@123"
+	rr = doRequest(t, h.LoginUser, http.MethodPost, "/login", user)
+	json.NewDecoder(rr.Body).Decode(&login)
+	doRequest(t, h.DeleteUserByID, http.MethodDelete, "/deleteUser", user)
+	if authorized(login.Token) {
+		t.Fatalf("access token still works after the account was deleted")
+	}
+	if rr, _ := refresh(login.RefreshToken); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh after account deletion returned %v", rr.Code)
+	}
+}
+
+func TestAccessTokens(t *testing.T) {
+	create := func(body any) (*httptest.ResponseRecorder, models.AccessToken) {
+		t.Helper()
+		var token models.AccessToken
+		rr := doRequest(t, h.HandleAccessTokens, http.MethodPost, "/tokens", body)
+		json.NewDecoder(rr.Body).Decode(&token)
+		return rr, token
+	}
+	for _, bad := range []map[string]any{
+		{"name": "", "scopes": []string{models.ScopeSnippetsRead}},
+		{"name": "cli", "scopes": []string{}},
+		{"name": "cli", "scopes": []string{"admin"}},
+		{
+			"name":       "cli",
+			"scopes":     []string{models.ScopeSnippetsRead},
+			"expires_at": "2001-01-01T00:00:00Z",
+		},
+	} {
+		if rr, _ := create(bad); rr.Code != http.StatusBadRequest {
+			t.Errorf("%v: got %v want 400", bad, rr.Code)
+		}
+	}
+
+	rr, reader := create(map[string]any{"name": "editor plugin", "scopes": []string{"snippets:read"}})
+	if rr.Code != http.StatusCreated || !strings.HasPrefix(reader.Token, models.AccessTokenPrefix) {
+		t.Fatalf("create returned %v: %+v", rr.Code, reader)
+	}
+	_, writer := create(map[string]any{"name": "sync script", "scopes": []string{"snippets:write"}})
+
+	snippets := auth.JWTAuthMiddleware(h.HandleSnippets, auth.SnippetScope)
+	status := func(token, method string, body any) int {
+		t.Helper()
+		return doRequestAs(t, token, snippets, method, "/snippets", body).Code
+	}
+	if code := status(reader.Token, http.MethodGet, nil); code != http.StatusOK {
+		t.Errorf("read with snippets:read returned %v", code)
+	}
+	newSnippet := models.Snippet{Title: "From CLI", Language: "go", Content: "package cli"}
+	if code := status(reader.Token, http.MethodPost, newSnippet); code != http.StatusForbidden {
+		t.Errorf("write with snippets:read returned %v", code)
+	}
+	if code := status(writer.Token, http.MethodPost, newSnippet); code != http.StatusCreated {
+		t.Errorf("write with snippets:write returned %v", code)
+	}
+	// Routes without a scope are for sessions only.
+	tokens := auth.JWTAuthMiddleware(h.HandleAccessTokens, nil)
+	rr = doRequestAs(t, writer.Token, tokens, http.MethodGet, "/tokens", nil)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("token management with an access token returned %v", rr.Code)
+	}
+
+	var listed []models.AccessToken
+	rr = doRequest(t, h.HandleAccessTokens, http.MethodGet, "/tokens", nil)
+	json.NewDecoder(rr.Body).Decode(&listed)
+	if len(listed) != 2 || listed[0].Token != "" || listed[0].LastUsedAt == nil {
+		t.Fatalf("list returned %+v", listed)
+	}
+
+	var rotated models.AccessToken
+	base := "/tokens/" + reader.TokenID.String()
+	rr = doRequest(t, h.HandleAccessToken, http.MethodPost, base+"/rotate", nil)
+	json.NewDecoder(rr.Body).Decode(&rotated)
+	if rr.Code != http.StatusOK || rotated.Token == reader.Token || rotated.Name != reader.Name {
+		t.Fatalf("rotate returned %v: %+v", rr.Code, rotated)
+	}
+	if code := status(reader.Token, http.MethodGet, nil); code != http.StatusUnauthorized {
+		t.Errorf("rotated-out token returned %v", code)
+	}
+
+	rr = doRequest(t, h.HandleAccessToken, http.MethodDelete, base, nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("revoke returned %v", rr.Code)
+	}
+	if code := status(rotated.Token, http.MethodGet, nil); code != http.StatusUnauthorized {
+		t.Errorf("revoked token returned %v", code)
+	}
+	rr = doRequest(t, h.HandleAccessToken, http.MethodDelete, base, nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("revoking twice returned %v", rr.Code)
+	}
+}
+
 func TestGetAllSnippets(t *testing.T) {
 	req, err := http.NewRequest(http.MethodGet, "/snippets", nil)
 	if err != nil {
@@ -105,7 +297,7 @@ func TestGetAllSnippets(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer "+jwtTokenString)
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.HandleSnippets)
+	handler := http.HandlerFunc(h.HandleSnippets)
 
 	handler.ServeHTTP(rr, req)
 
@@ -130,7 +322,7 @@ func TestCreateSnippet(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer "+jwtTokenString)
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.HandleSnippets)
+	handler := http.HandlerFunc(h.HandleSnippets)
 
 	handler.ServeHTTP(rr, req)
 
@@ -154,7 +346,7 @@ func TestGetSnippetByID(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer "+jwtTokenString)
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.HandleSnippet)
+	handler := http.HandlerFunc(h.HandleSnippet)
 
 	handler.ServeHTTP(rr, req)
 
@@ -180,15 +372,1218 @@ func TestUpdateSnippetByID(t *testing.T) {
 		t.Fatal(err)
 	}
 	req.Header.Set("Authorization", "Bearer "+jwtTokenString)
+	req.Header.Set("If-Match", `"1"`)
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.HandleSnippet)
+	handler := http.HandlerFunc(h.HandleSnippet)
 
 	handler.ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
 	}
+	if etag := rr.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("got ETag %s want \"2\"", etag)
+	}
+}
+
+func TestSnippetETags(t *testing.T) {
+	created := doRequest(t, h.HandleSnippets, http.MethodPost, "/snippets", models.Snippet{
+		Title:    "Synced",
+		Language: "Go",
+		Content:  "package sync",
+	})
+	var snippet models.Snippet
+	json.NewDecoder(created.Body).Decode(&snippet)
+	target := "/snippets/" + snippet.SnippetId.String()
+	etag := created.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("create returned ETag %q want \"1\"", etag)
+	}
+
+	rr := doRequestWith(t, jwtTokenString, http.Header{"If-None-Match": {etag}}, h.HandleSnippet,
+		http.MethodGet, target, nil)
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Errorf("unchanged GET returned %v: %s", rr.Code, rr.Body)
+	}
+
+	rr = doRequest(t, h.HandleSnippet, http.MethodPut, target, snippet)
+	if rr.Code != http.StatusPreconditionRequired {
+		t.Errorf(
+			"PUT without If-Match returned %v want %v",
+			rr.Code,
+			http.StatusPreconditionRequired,
+		)
+	}
+
+	ifMatch := http.Header{"If-Match": {etag}}
+	snippet.Content = "package synced"
+	rr = doRequestWith(t, jwtTokenString, ifMatch, h.HandleSnippet, http.MethodPut, target, snippet)
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT returned %v with ETag %q", rr.Code, rr.Header().Get("ETag"))
+	}
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		rr = doRequestWith(t, jwtTokenString, ifMatch, h.HandleSnippet, method, target, snippet)
+		var details struct {
+			problem.Details
+			Current models.Snippet `json:"current"`
+		}
+		json.NewDecoder(rr.Body).Decode(&details)
+		if rr.Code != http.StatusPreconditionFailed || details.Code != problem.SnippetChanged.Code {
+			t.Errorf("stale %s returned %v %q", method, rr.Code, details.Code)
+		}
+		if details.Current.Version != 2 || details.Current.Content != snippet.Content {
+			t.Errorf("stale %s returned current %+v", method, details.Current)
+		}
+	}
+
+	rr = doRequestWith(t, jwtTokenString, http.Header{"If-None-Match": {etag}}, h.HandleSnippet,
+		http.MethodGet, target, nil)
+	if rr.Code != http.StatusOK {
+		t.Errorf("changed GET returned %v want %v", rr.Code, http.StatusOK)
+	}
+	rr = doRequestWith(t, jwtTokenString, http.Header{"If-Match": {`"2"`}}, h.HandleSnippet,
+		http.MethodDelete, target, nil)
+	if rr.Code != http.StatusOK {
+		t.Errorf("DELETE returned %v: %s", rr.Code, rr.Body)
+	}
+}
+
+func TestPatchSnippet(t *testing.T) {
+	created := doRequest(t, h.HandleSnippets, http.MethodPost, "/snippets", models.Snippet{
+		Title:    "Draft",
+		Language: "Go",
+		Content:  "package draft",
+		Tags:     []string{"wip"},
+	})
+	var original models.Snippet
+	json.NewDecoder(created.Body).Decode(&original)
+	target := "/snippets/" + original.SnippetId.String()
+	mergePatch := http.Header{"Content-Type": {helper.MergePatchContentType}}
+	patch := func(body any) (*httptest.ResponseRecorder, models.Snippet) {
+		t.Helper()
+		rr := doRequestWith(t, jwtTokenString, mergePatch, h.HandleSnippet, http.MethodPatch,
+			target, body)
+		var snippet models.Snippet
+		json.Unmarshal(rr.Body.Bytes(), &snippet)
+		return rr, snippet
+	}
+
+	rr, renamed := patch(map[string]any{"title": "Final"})
+	if rr.Code != http.StatusOK || renamed.Title != "Final" ||
+		renamed.Content != original.Content || renamed.Version != 2 ||
+		!renamed.UpdatedAt.After(original.UpdatedAt) {
+		t.Fatalf("rename returned %v: %+v", rr.Code, renamed)
+	}
+	rr, same := patch(map[string]any{"title": "Final", "version": 7})
+	if rr.Code != http.StatusOK || same.Version != 2 || !same.UpdatedAt.Equal(renamed.UpdatedAt) {
+		t.Errorf("no-op patch returned %v: %+v", rr.Code, same)
+	}
+	rr, cleared := patch(map[string]any{"tags": nil})
+	if rr.Code != http.StatusOK || len(cleared.Tags) != 0 || cleared.Version != 3 {
+		t.Errorf("clearing tags returned %v: %+v", rr.Code, cleared)
+	}
+
+	rr, _ = patch(map[string]any{"title": ""})
+	var details problem.Details
+	json.NewDecoder(rr.Body).Decode(&details)
+	if rr.Code != http.StatusBadRequest || len(details.Errors) != 1 ||
+		details.Errors[0].Field != "title" {
+		t.Errorf("emptying the title returned %v: %+v", rr.Code, details)
+	}
+	rr = doRequest(t, h.HandleSnippet, http.MethodPatch, target, map[string]any{"title": "x"})
+	if rr.Code != http.StatusUnsupportedMediaType ||
+		rr.Header().Get("Accept-Patch") != helper.MergePatchContentType {
+		t.Errorf("plain JSON patch returned %v", rr.Code)
+	}
+	stale := http.Header{
+		"Content-Type": {helper.MergePatchContentType},
+		"If-Match":     {`"1"`},
+	}
+	rr = doRequestWith(t, jwtTokenString, stale, h.HandleSnippet, http.MethodPatch, target,
+		map[string]any{"title": "Lost"})
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale patch returned %v want %v", rr.Code, http.StatusPreconditionFailed)
+	}
+	rr, _ = patch(map[string]any{"content": strings.Repeat("x", 2<<20)})
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized patch returned %v want %v", rr.Code, http.StatusRequestEntityTooLarge)
+	}
+	doRequestWith(t, jwtTokenString, anyVersion, h.HandleSnippet, http.MethodDelete, target, nil)
+}
+
+func TestBulkSnippets(t *testing.T) {
+	type result struct {
+		Status  int             `json:"status"`
+		Snippet models.Snippet  `json:"snippet"`
+		Error   problem.Details `json:"error"`
+	}
+	bulk := func(mode string, ops ...map[string]any) (int, []result) {
+		t.Helper()
+		rr := doRequest(t, h.BulkSnippets, http.MethodPost, "/snippets/bulk", map[string]any{
+			"mode":       mode,
+			"operations": ops,
+		})
+		var response struct {
+			Results []result `json:"results"`
+		}
+		json.NewDecoder(rr.Body).Decode(&response)
+		if len(response.Results) != len(ops) {
+			t.Fatalf("%s batch returned %v with %d results", mode, rr.Code, len(response.Results))
+		}
+		return rr.Code, response.Results
+	}
+	statuses := func(results []result) []int {
+		var got []int
+		for _, result := range results {
+			got = append(got, result.Status)
+		}
+		return got
+	}
+	create := map[string]any{"op": "create", "title": "Bulk", "language": "Go", "content": "x"}
+	missing := map[string]any{"op": "delete", "snippet_id": uuid.NewString(), "version": 1}
+
+	code, results := bulk("atomic", create, create)
+	if code != http.StatusOK || !reflect.DeepEqual(statuses(results), []int{201, 201}) {
+		t.Fatalf("atomic batch returned %v %v", code, statuses(results))
+	}
+	first, second := results[0].Snippet, results[1].Snippet
+
+	code, results = bulk("atomic",
+		map[string]any{"op": "delete", "snippet_id": first.SnippetId, "version": 1},
+		missing,
+	)
+	if code != http.StatusMultiStatus || !reflect.DeepEqual(statuses(results), []int{424, 404}) ||
+		results[1].Error.Code != problem.SnippetNotFound.Code {
+		t.Errorf("failed atomic batch returned %v %+v", code, results)
+	}
+	rr := doRequest(t, h.HandleSnippet, http.MethodGet, "/snippets/"+first.SnippetId.String(), nil)
+	if rr.Code != http.StatusOK {
+		t.Errorf("rolled back delete left the snippet with %v", rr.Code)
+	}
+
+	code, results = bulk("best_effort",
+		map[string]any{"op": "update", "snippet_id": first.SnippetId, "version": 1,
+			"title": "Bulk renamed", "language": "Go", "content": "x"},
+		map[string]any{"op": "update", "snippet_id": second.SnippetId, "version": 1,
+			"title": "", "language": "Go", "content": "x"},
+		map[string]any{"op": "archive", "snippet_id": second.SnippetId},
+		missing,
+		map[string]any{"op": "delete", "snippet_id": second.SnippetId, "version": 2},
+		map[string]any{"op": "delete", "snippet_id": second.SnippetId, "version": 1},
+		map[string]any{"op": "delete", "snippet_id": first.SnippetId},
+	)
+	want := []int{200, 400, 400, 404, 412, 200, 428}
+	if code != http.StatusMultiStatus || !reflect.DeepEqual(statuses(results), want) {
+		t.Errorf("best effort batch returned %v %v want %v", code, statuses(results), want)
+	}
+	if results[0].Snippet.Title != "Bulk renamed" || results[0].Snippet.Version != 2 {
+		t.Errorf("update returned %+v", results[0].Snippet)
+	}
+	if len(results[1].Error.Errors) != 1 || results[2].Error.Code != problem.InvalidOperation.Code {
+		t.Errorf("invalid operations returned %+v and %+v", results[1].Error, results[2].Error)
+	}
+	doRequestWith(t, jwtTokenString, anyVersion, h.HandleSnippet, http.MethodDelete,
+		"/snippets/"+first.SnippetId.String(), nil)
+
+	rr = doRequest(t, h.BulkSnippets, http.MethodPost, "/snippets/bulk", map[string]any{
+		"mode":       "sometimes",
+		"operations": []any{create},
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown mode returned %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	rr := doRequest(t, h.RegisterUser, http.MethodPost, "/register", models.User{
+		UserName: "librarian",
+		Email:    "librarian@testNew.com",
+		Password: "Password@123",
+	})
+	var registered struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(rr.Body).Decode(&registered)
+	librarian := registered.Token
+	for _, title := range []string{"Deploy", "Lint"} {
+		snippet := models.Snippet{Title: title, Language: "Shell", Content: "make " + title}
+		doRequestAs(t, librarian, h.HandleSnippets, http.MethodPost, "/snippets", snippet)
+	}
+
+	exports := map[string][]byte{}
+	for format, contentType := range map[string]string{
+		"ndjson": "application/x-ndjson",
+		"json":   "application/json",
+		"zip":    "application/zip",
+		"vscode": "application/json",
+	} {
+		rr := doRequestAs(t, librarian, h.Export, http.MethodGet, "/export?format="+format, nil)
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != contentType {
+			t.Errorf("%s export returned %v %q", format, rr.Code, rr.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(rr.Header().Get("Content-Disposition"), library.Extension(format)) {
+			t.Errorf("%s export is named %q", format, rr.Header().Get("Content-Disposition"))
+		}
+		exports[format] = rr.Body.Bytes()
+	}
+	rr = doRequestAs(t, librarian, h.Export, http.MethodGet, "/export?format=xml", nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("xml export returned %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	type report struct {
+		Applied bool           `json:"applied"`
+		Summary map[string]int `json:"summary"`
+		Items   []struct {
+			Action    string `json:"action"`
+			RenamedTo string `json:"renamed_to"`
+		} `json:"items"`
+	}
+	importLibrary := func(query string, body []byte) (int, report) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, "/import?"+query, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+librarian)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.Import).ServeHTTP(rr, req)
+		var got report
+		json.NewDecoder(rr.Body).Decode(&got)
+		return rr.Code, got
+	}
+
+	// Re-importing an export changes nothing, whatever its format.
+	for format, body := range exports {
+		if format == "vscode" {
+			continue
+		}
+		code, got := importLibrary("format="+format, body)
+		if code != http.StatusOK || got.Summary["duplicate"] != 2 || got.Applied {
+			t.Errorf("%s re-import returned %v %+v", format, code, got)
+		}
+	}
+
+	changed := strings.Replace(string(exports["ndjson"]), "make Deploy", "make release", 1)
+	changed += `{"title": "Format", "language": "Shell", "content": "shfmt -w ."}` + "\n"
+	changed += `{"title": "", "language": "Shell", "content": "nothing"}` + "\n"
+
+	code, got := importLibrary("dry_run=true", []byte(changed))
+	want := map[string]int{"duplicate": 1, "skip": 1, "create": 1, "invalid": 1}
+	if code != http.StatusMultiStatus || got.Applied || !reflect.DeepEqual(got.Summary, want) {
+		t.Errorf("dry run returned %v %+v", code, got)
+	}
+	rr = doRequestAs(t, librarian, h.GetSortedSnippets, http.MethodGet, "/snippets/sorted", nil)
+	var page snippetList
+	json.NewDecoder(rr.Body).Decode(&page)
+	if len(page.Items) != 2 {
+		t.Errorf("dry run left %d snippets want 2", len(page.Items))
+	}
+
+	code, got = importLibrary("on_conflict=rename", []byte(changed))
+	if code != http.StatusMultiStatus || !got.Applied || got.Items[0].RenamedTo != "Deploy (2)" {
+		t.Errorf("renaming import returned %v %+v", code, got)
+	}
+	code, got = importLibrary("on_conflict=overwrite&format=ndjson", []byte(changed))
+	want = map[string]int{"overwrite": 1, "duplicate": 2, "invalid": 1}
+	if code != http.StatusMultiStatus || !got.Applied || !reflect.DeepEqual(got.Summary, want) {
+		t.Errorf("overwriting import returned %v %+v", code, got)
+	}
+
+	for _, query := range []string{"on_conflict=merge", "dry_run=maybe", "format=xml"} {
+		if code, _ := importLibrary(query, exports["ndjson"]); code != http.StatusBadRequest {
+			t.Errorf("import with %s returned %v want %v", query, code, http.StatusBadRequest)
+		}
+	}
+	// Editor snippets only match existing ones by title and language, and
+	// Deploy was overwritten since the export.
+	code, got = importLibrary("format=vscode&dry_run=1", exports["vscode"])
+	if code != http.StatusOK || got.Summary["duplicate"] != 1 || got.Summary["skip"] != 1 {
+		t.Errorf("vscode import returned %v %+v", code, got)
+	}
+	jetbrains := `<templateSet group="user">
+	  <template name="fmt" value="shfmt -w $FILE$" description="Format">
+	    <variable name="FILE" expression="" defaultValue="&quot;.&quot;" alwaysStopAt="true" />
+	    <context><option name="SHELL_SCRIPT" value="true" /></context>
+	  </template>
+	</templateSet>`
+	code, got = importLibrary("format=jetbrains&dry_run=true", []byte(jetbrains))
+	if code != http.StatusOK || got.Summary["skip"] != 1 {
+		t.Errorf("jetbrains import returned %v %+v", code, got)
+	}
+	if code, _ := importLibrary("format=zip", []byte("not a zip")); code != http.StatusBadRequest {
+		t.Errorf("invalid archive returned %v want %v", code, http.StatusBadRequest)
+	}
+}
+
+func TestSearchSnippets(t *testing.T) {
+	for _, q := range []string{`"updated content"`, "upd*", "println snippet"} {
+		req, err := http.NewRequest(
+			http.MethodGet,
+			"/snippets/search?q="+url.QueryEscape(q),
+			nil,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+jwtTokenString)
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(h.SearchSnippets)
+
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+		var list searchList
+		if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+			t.Fatalf("failed to parse response body: %v", err)
+		}
+		results := list.Items
+		if len(results) != 1 || results[0].SnippetId.String() != snippetID {
+			t.Errorf("query %q: got %d results, want the updated snippet", q, len(results))
+			continue
+		}
+		if !strings.Contains(results[0].ContentHighlight, "<mark>") {
+			t.Errorf("query %q: content highlight has no marks: %q", q, results[0].ContentHighlight)
+		}
+	}
+
+	snippet := models.Snippet{
+		Title:    "<b>markup</b>",
+		Language: "HTML",
+		Content:  `<script>alert("markup")</script>`,
+	}
+	doRequest(t, h.HandleSnippets, http.MethodPost, "/snippets", snippet)
+	rr := doRequest(t, h.SearchSnippets, http.MethodGet, "/snippets/search?q=markup", nil)
+	var list searchList
+	json.NewDecoder(rr.Body).Decode(&list)
+	results := list.Items
+	if len(results) != 1 {
+		t.Fatalf("got %d results for markup want 1", len(results))
+	}
+	want := "&lt;b&gt;<mark>markup</mark>&lt;/b&gt;"
+	if results[0].TitleHighlight != want {
+		t.Errorf("got title highlight %q want %q", results[0].TitleHighlight, want)
+	}
+	if strings.Contains(results[0].ContentHighlight, "<script>") {
+		t.Errorf("content highlight isn't escaped: %q", results[0].ContentHighlight)
+	}
+
+	// Results that rank the same are paged in snippet ID order.
+	for _, title := range []string{"paged search 1", "paged search 2", "paged search 3"} {
+		snippet := models.Snippet{Title: title, Language: "Markdown", Content: "ranked"}
+		doRequest(t, h.HandleSnippets, http.MethodPost, "/snippets", snippet)
+	}
+	seen := map[uuid.UUID]bool{}
+	target := "/snippets/search?q=ranked&limit=2"
+	for pages := 0; ; pages++ {
+		rr := doRequest(t, h.SearchSnippets, http.MethodGet, target, nil)
+		list = searchList{}
+		json.NewDecoder(rr.Body).Decode(&list)
+		if rr.Code != http.StatusOK || pages > 2 {
+			t.Fatalf("page %d returned %v: %s", pages, rr.Code, rr.Body)
+		}
+		for _, result := range list.Items {
+			if seen[result.SnippetId] {
+				t.Errorf("result %s is on two pages", result.Title)
+			}
+			seen[result.SnippetId] = true
+		}
+		if !list.HasMore {
+			break
+		}
+		target = "/snippets/search?q=ranked&limit=2&cursor=" + url.QueryEscape(list.NextCursor)
+	}
+	if len(seen) != 3 {
+		t.Errorf("got %d results over every page want 3", len(seen))
+	}
+	rr = doRequest(t, h.SearchSnippets, http.MethodGet, "/snippets/search?q=ranked&sort_by=title", nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("search sorted by title returned %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestTags(t *testing.T) {
+	snippet := models.Snippet{
+		Title:    "kubectl logs",
+		Language: "Shell",
+		Content:  "kubectl logs -f deploy/api",
+		Tags:     []string{"K8s", "oncall", "k8s"},
+	}
+	rr := doRequest(t, h.HandleSnippets, http.MethodPost, "/snippets", snippet)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create returned %v: %s", rr.Code, rr.Body)
+	}
+	var created models.Snippet
+	json.NewDecoder(rr.Body).Decode(&created)
+	if !reflect.DeepEqual(created.Tags, []string{"k8s", "oncall"}) {
+		t.Errorf("got tags %v want normalized [k8s oncall]", created.Tags)
+	}
+
+	snippet = models.Snippet{
+		Title:    "Updated Snippet",
+		Language: "Go",
+		Content:  "fmt.Println('Updated content')",
+		Tags:     []string{"oncall"},
+	}
+	target := "/snippets/" + snippetID
+	rr = doRequestWith(
+		t,
+		jwtTokenString,
+		anyVersion,
+		h.HandleSnippet,
+		http.MethodPut,
+		target,
+		snippet,
+	)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update returned %v: %s", rr.Code, rr.Body)
+	}
+
+	for query, want := range map[string]int{
+		"tag=oncall":                   2,
+		"tag=oncall&tag=k8s":           1,
+		"tag=oncall&tag=k8s&match=any": 2,
+		"tag=missing":                  0,
+	} {
+		rr = doRequest(t, h.HandleSnippets, http.MethodGet, "/snippets?"+query, nil)
+		var list snippetList
+		json.NewDecoder(rr.Body).Decode(&list)
+		if rr.Code != http.StatusOK || len(list.Items) != want {
+			t.Errorf("%s: got %v with %d snippets want %d", query, rr.Code, len(list.Items), want)
+		}
+	}
+
+	rr = doRequest(t, h.HandleTag, http.MethodPut, "/tags/k8s", map[string]string{"name": "oncall"})
+	if rr.Code != http.StatusConflict {
+		t.Errorf("rename onto an existing tag returned %v want %v", rr.Code, http.StatusConflict)
+	}
+	rr = doRequest(t, h.HandleTag, http.MethodPut, "/tags/k8s", map[string]string{"name": "kube"})
+	if rr.Code != http.StatusOK {
+		t.Errorf("rename returned %v: %s", rr.Code, rr.Body)
+	}
+	rr = doRequest(
+		t,
+		h.HandleTag,
+		http.MethodPost,
+		"/tags/kube/merge",
+		map[string]string{"into": "oncall"},
+	)
+	if rr.Code != http.StatusOK {
+		t.Errorf("merge returned %v: %s", rr.Code, rr.Body)
+	}
+
+	rr = doRequest(t, h.HandleTags, http.MethodGet, "/tags", nil)
+	var tags []models.Tag
+	json.NewDecoder(rr.Body).Decode(&tags)
+	if !reflect.DeepEqual(tags, []models.Tag{{Name: "oncall", Count: 2}}) {
+		t.Errorf("got tags %+v want only oncall used twice", tags)
+	}
+
+	// The rename and the merge are both in the history of the snippet.
+	base := "/snippets/" + created.SnippetId.String()
+	rr = doRequest(t, h.HandleSnippet, http.MethodGet, base+"/revisions", nil)
+	var revisions []models.SnippetRevision
+	json.NewDecoder(rr.Body).Decode(&revisions)
+	if len(revisions) != 3 || !reflect.DeepEqual(revisions[1].Tags, []string{"kube", "oncall"}) ||
+		!reflect.DeepEqual(revisions[2].Tags, []string{"oncall"}) {
+		t.Errorf("got revisions %+v want the rename and the merge recorded", revisions)
+	}
+	rr = doRequest(t, h.HandleSnippet, http.MethodGet, base, nil)
+	var current models.Snippet
+	json.NewDecoder(rr.Body).Decode(&current)
+	if current.Version != len(revisions) {
+		t.Errorf("got version %d with %d revisions", current.Version, len(revisions))
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	base := "/snippets/" + snippetID + "/revisions"
+
+	rr := doRequest(t, h.HandleSnippet, http.MethodGet, base, nil)
+	var revisions []models.SnippetRevision
+	json.NewDecoder(rr.Body).Decode(&revisions)
+	if rr.Code != http.StatusOK || len(revisions) != 3 {
+		t.Fatalf("got %v with %d revisions want create plus two updates", rr.Code, len(revisions))
+	}
+
+	rr = doRequest(t, h.HandleSnippet, http.MethodGet, base+"/diff?from=1&to=2", nil)
+	if diff := rr.Body.String(); !strings.Contains(
+		diff,
+		"-title: test snippet\n+title: Updated Snippet\n",
+	) {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+
+	rr = doRequest(t, h.HandleSnippet, http.MethodPost, base+"/1/restore", nil)
+	var restored models.Snippet
+	json.NewDecoder(rr.Body).Decode(&restored)
+	if rr.Code != http.StatusOK || restored.Title != "test snippet" {
+		t.Errorf("restore returned %v with title %q", rr.Code, restored.Title)
+	}
+
+	rr = doRequest(t, h.HandleSnippet, http.MethodGet, base+"/4", nil)
+	var latest models.SnippetRevision
+	json.NewDecoder(rr.Body).Decode(&latest)
+	if rr.Code != http.StatusOK || latest.Title != "test snippet" {
+		t.Errorf("restore should be recorded as revision 4, got %v %+v", rr.Code, latest)
+	}
+	rr = doRequest(t, h.HandleSnippet, http.MethodPost, base+"/4/restore", nil)
+	json.NewDecoder(rr.Body).Decode(&restored)
+	if rr.Code != http.StatusOK || restored.Version != 5 {
+		t.Errorf("restoring the current state returned %v at version %d want 5", rr.Code, restored.Version)
+	}
+	rr = doRequest(t, h.HandleSnippet, http.MethodGet, base+"/5", nil)
+	if rr.Code != http.StatusOK {
+		t.Errorf("restoring the current state should be recorded as revision 5, got %v", rr.Code)
+	}
+
+	rr = doRequest(t, h.HandleSnippet, http.MethodGet, base+"/9", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("missing revision returned %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestSharing(t *testing.T) {
+	base := "/snippets/" + snippetID
+
+	rr := doRequest(t, h.HandleSnippet, http.MethodPost, base+"/shares", nil)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("sharing a private snippet returned %v want %v", rr.Code, http.StatusConflict)
+	}
+
+	snippet := models.Snippet{
+		Title:      "Shared Snippet",
+		Language:   "Go",
+		Content:    "fmt.Println('shared')",
+		Visibility: models.VisibilityUnlisted,
+	}
+	rr = doRequestWith(
+		t,
+		jwtTokenString,
+		anyVersion,
+		h.HandleSnippet,
+		http.MethodPut,
+		base,
+		snippet,
+	)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update returned %v: %s", rr.Code, rr.Body)
+	}
+
+	rr = doRequest(t, h.HandleSnippet, http.MethodPost, base+"/shares", nil)
+	var link models.ShareLink
+	json.NewDecoder(rr.Body).Decode(&link)
+	if rr.Code != http.StatusCreated || link.Token == "" {
+		t.Fatalf("create share link returned %v: %+v", rr.Code, link)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/s/"+link.Token, nil)
+	rr = httptest.NewRecorder()
+	h.GetSharedSnippet(rr, req)
+	var shared models.Snippet
+	json.NewDecoder(rr.Body).Decode(&shared)
+	if rr.Code != http.StatusOK || shared.Title != snippet.Title {
+		t.Errorf("shared snippet returned %v with title %q", rr.Code, shared.Title)
+	}
+
+	rr = doRequest(t, h.Explore, http.MethodGet, "/explore", nil)
+	var public snippetList
+	json.NewDecoder(rr.Body).Decode(&public)
+	if rr.Code != http.StatusOK || len(public.Items) != 0 {
+		t.Errorf(
+			"explore returned %v with %d snippets want no unlisted ones",
+			rr.Code,
+			len(public.Items),
+		)
+	}
+
+	rr = doRequest(t, h.HandleSnippet, http.MethodGet, base+"/shares", nil)
+	var links []models.ShareLink
+	json.NewDecoder(rr.Body).Decode(&links)
+	if rr.Code != http.StatusOK || len(links) != 1 || links[0].Token != "" {
+		t.Errorf("list share links returned %v: %+v", rr.Code, links)
+	}
+
+	rr = doRequest(
+		t,
+		h.HandleSnippet,
+		http.MethodDelete,
+		base+"/shares/"+link.ShareID.String(),
+		nil,
+	)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("revoke returned %v: %s", rr.Code, rr.Body)
+	}
+	rr = httptest.NewRecorder()
+	h.GetSharedSnippet(rr, httptest.NewRequest(http.MethodGet, "/s/"+link.Token, nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("revoked link returned %v want %v", rr.Code, http.StatusNotFound)
+	}
+
+	snippet.Visibility = models.VisibilityPublic
+	doRequestWith(t, jwtTokenString, anyVersion, h.HandleSnippet, http.MethodPut, base, snippet)
+	rr = doRequest(t, h.Explore, http.MethodGet, "/explore?language=Go", nil)
+	public = snippetList{}
+	json.NewDecoder(rr.Body).Decode(&public)
+	if len(public.Items) != 1 || public.Items[0].SnippetId.String() != snippetID {
+		t.Errorf("explore returned %d snippets want the public one", len(public.Items))
+	}
+}
+
+func TestTrash(t *testing.T) {
+	rr := doRequest(t, h.RegisterUser, http.MethodPost, "/register", models.User{
+		UserName: "tidier",
+		Email:    "tidier@testNew.com",
+		Password: "Password@123",
+	})
+	var registered struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(rr.Body).Decode(&registered)
+	tidier := registered.Token
+	do := func(handler http.HandlerFunc, method, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		return doRequestWith(t, tidier, anyVersion, handler, method, target, nil)
+	}
+
+	trashed := make([]string, 2)
+	for i := range trashed {
+		snippet := models.Snippet{
+			Title:    fmt.Sprintf("Trashed %d", i),
+			Language: "Go",
+			Content:  "// soon gone",
+			Tags:     []string{"trashed"},
+		}
+		rr := doRequestAs(t, tidier, h.HandleSnippets, http.MethodPost, "/snippets", snippet)
+		var created models.Snippet
+		json.NewDecoder(rr.Body).Decode(&created)
+		trashed[i] = created.SnippetId.String()
+		rr = do(h.HandleSnippet, http.MethodDelete, "/snippets/"+trashed[i])
+		var deleted models.Snippet
+		json.NewDecoder(rr.Body).Decode(&deleted)
+		if rr.Code != http.StatusOK || deleted.DeletedAt == nil {
+			t.Fatalf("delete returned %v with deleted_at %v", rr.Code, deleted.DeletedAt)
+		}
+	}
+
+	rr = do(h.HandleSnippet, http.MethodGet, "/snippets/"+trashed[0])
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("trashed snippet returned %v want %v", rr.Code, http.StatusNotFound)
+	}
+	rr = do(h.HandleTags, http.MethodGet, "/tags")
+	if strings.Contains(rr.Body.String(), `"trashed"`) {
+		t.Errorf("tags of trashed snippets are listed: %s", rr.Body)
+	}
+
+	var trash struct {
+		Items []struct {
+			models.Snippet
+			PurgeAt *time.Time `json:"purge_at"`
+		} `json:"items"`
+		NextCursor string `json:"next_cursor"`
+		HasMore    bool   `json:"has_more"`
+	}
+	rr = do(h.HandleTrash, http.MethodGet, "/trash?limit=1")
+	json.NewDecoder(rr.Body).Decode(&trash)
+	if rr.Code != http.StatusOK || len(trash.Items) != 1 || !trash.HasMore {
+		t.Fatalf("trash returned %v with %d snippets want a first page of 1", rr.Code, len(trash.Items))
+	}
+	if trash.Items[0].SnippetId.String() != trashed[1] {
+		t.Errorf("trash should list the most recently deleted first")
+	}
+	purgeAt := trash.Items[0].PurgeAt
+	if purgeAt == nil || !purgeAt.After(time.Now().Add(29*24*time.Hour)) {
+		t.Errorf("got purge_at %v want a month away", purgeAt)
+	}
+	rr = do(h.HandleTrash, http.MethodGet, "/trash?limit=1&cursor="+url.QueryEscape(trash.NextCursor))
+	trash.Items = nil
+	json.NewDecoder(rr.Body).Decode(&trash)
+	if len(trash.Items) != 1 || trash.Items[0].SnippetId.String() != trashed[0] || trash.HasMore {
+		t.Errorf("second page of the trash returned %v: %s", rr.Code, rr.Body)
+	}
+	rr = do(h.HandleSnippets, http.MethodGet, "/snippets?sort_by=deleted_at")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("sorting live snippets by deleted_at returned %v", rr.Code)
+	}
+
+	rr = do(h.HandleTrashedSnippet, http.MethodPost, "/trash/"+trashed[0]+"/restore")
+	var restored models.Snippet
+	json.NewDecoder(rr.Body).Decode(&restored)
+	if rr.Code != http.StatusOK || restored.DeletedAt != nil || len(restored.Tags) != 1 {
+		t.Errorf("restore returned %v: %+v", rr.Code, restored)
+	}
+	rr = do(h.HandleSnippet, http.MethodGet, "/snippets/"+trashed[0])
+	if rr.Code != http.StatusOK {
+		t.Errorf("restored snippet returned %v", rr.Code)
+	}
+	rr = do(h.HandleTrashedSnippet, http.MethodPost, "/trash/"+trashed[0]+"/restore")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("restoring a live snippet returned %v want %v", rr.Code, http.StatusNotFound)
+	}
+
+	rr = do(h.HandleTrashedSnippet, http.MethodDelete, "/trash/"+trashed[1])
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("purge returned %v: %s", rr.Code, rr.Body)
+	}
+	rr = do(h.HandleTrashedSnippet, http.MethodDelete, "/trash/"+trashed[1])
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("purging twice returned %v want %v", rr.Code, http.StatusNotFound)
+	}
+	rr = do(h.HandleTrash, http.MethodGet, "/trash")
+	if body := rr.Body.String(); !strings.Contains(body, `"items":[]`) {
+		t.Errorf("trash should be empty, got %s", body)
+	}
+
+	rr = do(h.HandleTrashedSnippet, http.MethodGet, "/trash/"+trashed[0])
+	if rr.Code != http.StatusMethodNotAllowed || rr.Header().Get("Allow") != "DELETE" {
+		t.Errorf("got %v allowing %q", rr.Code, rr.Header().Get("Allow"))
+	}
+	rr = doRequestAs(t, tidier, h.HandleSnippet, http.MethodDelete, "/snippets/"+trashed[0], nil)
+	if rr.Code != http.StatusPreconditionRequired {
+		t.Errorf("delete without If-Match returned %v", rr.Code)
+	}
+}
+
+func TestOrganizations(t *testing.T) {
+	rr := doRequest(t, h.RegisterUser, http.MethodPost, "/register", models.User{
+		UserName: "teammate",
+		Email:    "teammate@testNew.com",
+		Password: "Password@123",
+	})
+	var registered struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(rr.Body).Decode(&registered)
+	teammate := registered.Token
+
+	rr = doRequest(t, h.HandleOrgs, http.MethodPost, "/orgs", map[string]string{"name": "Platform"})
+	var org models.Organization
+	json.NewDecoder(rr.Body).Decode(&org)
+	if rr.Code != http.StatusCreated || org.Role != models.RoleOwner {
+		t.Fatalf("create org returned %v: %+v", rr.Code, org)
+	}
+	base := "/orgs/" + org.OrgID.String()
+
+	invite := map[string]string{"email": "teammate@testNew.com", "role": models.RoleViewer}
+	rr = doRequest(t, h.HandleOrg, http.MethodPost, base+"/invites", invite)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("invite returned %v: %s", rr.Code, rr.Body)
+	}
+	rr = doRequestAs(t, teammate, h.HandleInvites, http.MethodGet, "/invites", nil)
+	var invites []models.OrgInvite
+	json.NewDecoder(rr.Body).Decode(&invites)
+	if len(invites) != 1 || invites[0].OrgName != "Platform" {
+		t.Fatalf("got invites %+v want the Platform invite", invites)
+	}
+	accept := "/invites/" + invites[0].InviteID.String() + "/accept"
+	rr = doRequestAs(t, teammate, h.HandleInvite, http.MethodPost, accept, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("accept returned %v: %s", rr.Code, rr.Body)
+	}
+
+	snippet := models.Snippet{
+		Title:    "Deploy",
+		Language: "Shell",
+		Content:  "make deploy",
+		OrgID:    &org.OrgID,
+	}
+	rr = doRequest(t, h.HandleSnippets, http.MethodPost, "/snippets", snippet)
+	var created models.Snippet
+	json.NewDecoder(rr.Body).Decode(&created)
+	if rr.Code != http.StatusCreated || created.OrgID == nil || *created.OrgID != org.OrgID {
+		t.Fatalf("create org snippet returned %v: %+v", rr.Code, created)
+	}
+
+	rr = doRequestAs(t, teammate, h.HandleOrg, http.MethodGet, base+"/snippets", nil)
+	var library snippetList
+	json.NewDecoder(rr.Body).Decode(&library)
+	if rr.Code != http.StatusOK || len(library.Items) != 1 {
+		t.Errorf("viewer got %v with %d org snippets want 1", rr.Code, len(library.Items))
+	}
+	target := "/snippets/" + created.SnippetId.String()
+	rr = doRequestWith(t, teammate, anyVersion, h.HandleSnippet, http.MethodPut, target, snippet)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("viewer update returned %v want %v", rr.Code, http.StatusForbidden)
+	}
+	orgExport := "/export?org_id=" + org.OrgID.String()
+	rr = doRequestAs(t, teammate, h.Export, http.MethodGet, orgExport, nil)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "make deploy") {
+		t.Errorf("org export returned %v: %s", rr.Code, rr.Body)
+	}
+	rr = doRequestAs(t, teammate, h.Export, http.MethodGet, "/export", nil)
+	if strings.Contains(rr.Body.String(), "make deploy") {
+		t.Errorf("personal export has org snippets: %s", rr.Body)
+	}
+
+	rr = doRequest(t, h.HandleOrg, http.MethodGet, base+"/members", nil)
+	var members []models.OrgMember
+	json.NewDecoder(rr.Body).Decode(&members)
+	if len(members) != 2 {
+		t.Fatalf("got members %+v want owner and viewer", members)
+	}
+	rr = doRequest(
+		t,
+		h.HandleOrg,
+		http.MethodDelete,
+		base+"/members/"+members[1].UserID.String(),
+		nil,
+	)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("remove member returned %v: %s", rr.Code, rr.Body)
+	}
+	rr = doRequestAs(t, teammate, h.HandleOrg, http.MethodGet, base+"/snippets", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("removed member got %v want %v", rr.Code, http.StatusNotFound)
+	}
+	rr = doRequestAs(t, teammate, h.Export, http.MethodGet, orgExport, nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("removed member's org export returned %v want %v", rr.Code, http.StatusNotFound)
+	}
+	rr = doRequest(
+		t,
+		h.HandleOrg,
+		http.MethodDelete,
+		base+"/members/"+members[0].UserID.String(),
+		nil,
+	)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("removing the last owner returned %v want %v", rr.Code, http.StatusConflict)
+	}
+	h.Users.DeleteUser(context.Background(), members[1].UserID)
+}
+
+func TestPagination(t *testing.T) {
+	for _, title := range []string{"page c", "page a", "page e", "page b", "page d"} {
+		snippet := models.Snippet{Title: title, Language: "Text", Content: "paged"}
+		rr := doRequest(t, h.HandleSnippets, http.MethodPost, "/snippets", snippet)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("create returned %v: %s", rr.Code, rr.Body)
+		}
+	}
+
+	var titles []string
+	target := "/snippets/language?language=Text&sort_by=title&order=asc&limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		rr := doRequest(t, h.GetSnippetByLanguage, http.MethodGet, target, nil)
+		var list snippetList
+		json.NewDecoder(rr.Body).Decode(&list)
+		if rr.Code != http.StatusOK || list.Limit != 2 || len(list.Items) > 2 {
+			t.Fatalf("got %v with %+v", rr.Code, list)
+		}
+		for _, snippet := range list.Items {
+			titles = append(titles, snippet.Title)
+		}
+		if !list.HasMore {
+			if list.NextCursor != "" {
+				t.Errorf("last page has a next cursor")
+			}
+			break
+		}
+		target = "/snippets/language?language=Text&sort_by=title&order=asc&limit=2&cursor=" +
+			url.QueryEscape(list.NextCursor)
+
+		if pages == 0 {
+			// A cursor only works with the sort order it was issued for.
+			mismatched := "/snippets/language?language=Text&sort_by=title&order=desc&cursor=" +
+				url.QueryEscape(list.NextCursor)
+			rr = doRequest(t, h.GetSnippetByLanguage, http.MethodGet, mismatched, nil)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("mismatched cursor returned %v want %v", rr.Code, http.StatusBadRequest)
+			}
+			tampered := strings.Replace(target, "cursor=", "cursor=x", 1)
+			rr = doRequest(t, h.GetSnippetByLanguage, http.MethodGet, tampered, nil)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("tampered cursor returned %v want %v", rr.Code, http.StatusBadRequest)
+			}
+		}
+	}
+	want := []string{"page a", "page b", "page c", "page d", "page e"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("got titles %v want %v", titles, want)
+	}
+
+	rr := doRequest(t, h.HandleSnippets, http.MethodGet, "/snippets?limit=1000", nil)
+	var list snippetList
+	json.NewDecoder(rr.Body).Decode(&list)
+	if list.Limit != 100 {
+		t.Errorf("got limit %d want it capped at 100", list.Limit)
+	}
+
+	for _, snippet := range list.Items {
+		if snippet.Language == "Text" {
+			target := "/snippets/" + snippet.SnippetId.String()
+			doRequestWith(
+				t,
+				jwtTokenString,
+				anyVersion,
+				h.HandleSnippet,
+				http.MethodDelete,
+				target,
+				nil,
+			)
+		}
+	}
+}
+
+// searchList is the envelope of paginated search results.
+type searchList struct {
+	Items      []models.SnippetSearchResult `json:"items"`
+	NextCursor string                       `json:"next_cursor"`
+	HasMore    bool                         `json:"has_more"`
+}
+
+// snippetList is the envelope of paginated snippet listings.
+type snippetList struct {
+	Items      []models.Snippet `json:"items"`
+	NextCursor string           `json:"next_cursor"`
+	HasMore    bool             `json:"has_more"`
+	Limit      int              `json:"limit"`
+}
+
+// doRequest sends body as JSON to handler as the test user.
+func doRequest(
+	t *testing.T,
+	handler http.HandlerFunc,
+	method, target string,
+	body any,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	return doRequestAs(t, jwtTokenString, handler, method, target, body)
+}
+
+// doRequestAs sends body as JSON to handler authenticated with token.
+func doRequestAs(
+	t *testing.T,
+	token string,
+	handler http.HandlerFunc,
+	method, target string,
+	body any,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	return doRequestWith(t, token, nil, handler, method, target, body)
+}
+
+// anyVersion makes a snippet update or delete unconditional.
+var anyVersion = http.Header{"If-Match": {"*"}}
+
+// doRequestWith is doRequestAs with extra request headers.
+func doRequestWith(
+	t *testing.T,
+	token string,
+	header http.Header,
+	handler http.HandlerFunc,
+	method, target string,
+	body any,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestProblemDetails(t *testing.T) {
+	decode := func(t *testing.T, rr *httptest.ResponseRecorder, status int) problem.Details {
+		t.Helper()
+		if rr.Code != status {
+			t.Fatalf("got status %d, want %d: %s", rr.Code, status, rr.Body)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != problem.ContentType {
+			t.Fatalf("got content type %q, want %q", ct, problem.ContentType)
+		}
+		var details problem.Details
+		if err := json.NewDecoder(rr.Body).Decode(&details); err != nil {
+			t.Fatal(err)
+		}
+		if details.Status != status {
+			t.Errorf("got status member %d, want %d", details.Status, status)
+		}
+		return details
+	}
+
+	t.Run("field errors", func(t *testing.T) {
+		rr := doRequest(t, h.HandleSnippets, http.MethodPost, "/snippets",
+			models.Snippet{Content: "x", Visibility: "secret"})
+		details := decode(t, rr, http.StatusBadRequest)
+		if details.Code != problem.InvalidPayload.Code {
+			t.Errorf("got code %q, want %q", details.Code, problem.InvalidPayload.Code)
+		}
+		var fields []string
+		for _, field := range details.Errors {
+			fields = append(fields, field.Field+":"+field.Code)
+		}
+		want := []string{"title:required", "language:required", "visibility:invalid"}
+		if !reflect.DeepEqual(fields, want) {
+			t.Errorf("got field errors %v, want %v", fields, want)
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		user := models.User{
+			UserName: "testerTestNew",
+			Email:    "someoneElse@testNew.com",
+			Password: "Password@123",
+		}
+		rr := doRequest(t, h.RegisterUser, http.MethodPost, "/register", user)
+		if details := decode(t, rr, http.StatusConflict); details.Code != "username_taken" {
+			t.Errorf("got code %q, want username_taken", details.Code)
+		}
+	})
+
+	t.Run("middleware", func(t *testing.T) {
+		handler := auth.JWTAuthMiddleware(h.HandleSnippets, auth.SnippetScope)
+		rr := doRequestAs(t, "not-a-jwt", handler, http.MethodGet, "/snippets", nil)
+		if details := decode(t, rr, http.StatusUnauthorized); details.Code != "invalid_token" {
+			t.Errorf("got code %q, want invalid_token", details.Code)
+		}
+	})
+}
+
+// timeoutStore fails every sorted listing as if the query ran out of time.
+type timeoutStore struct {
+	database.SnippetStore
+}
+
+func (timeoutStore) GetSnippetsSorted(
+	context.Context,
+	uuid.UUID,
+	helper.Page,
+) (models.SnippetPage, error) {
+	return models.SnippetPage{}, fmt.Errorf("%w: canceling statement", context.DeadlineExceeded)
+}
+
+func TestDeadlineExceeded(t *testing.T) {
+	slow := handlers.New(timeoutStore{h.Snippets}, h.Users, h.Orgs)
+	rr := doRequest(t, slow.GetSortedSnippets, http.MethodGet, "/snippets/sorted", nil)
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Fatalf("got status %d want %d", rr.Code, http.StatusGatewayTimeout)
+	}
+	var details problem.Details
+	json.NewDecoder(rr.Body).Decode(&details)
+	if details.Code != problem.Timeout.Code {
+		t.Errorf("got code %q want %q", details.Code, problem.Timeout.Code)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	probes := handlers.New(h.Snippets, h.Users, h.Orgs)
+	status := func(t *testing.T, handler http.HandlerFunc, path string, want problem.Kind) {
+		t.Helper()
+		rr := doRequest(t, handler, http.MethodGet, path, nil)
+		if want.Code == "" {
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got status %d want %d: %s", rr.Code, http.StatusOK, rr.Body)
+			}
+			return
+		}
+		var details problem.Details
+		json.NewDecoder(rr.Body).Decode(&details)
+		if rr.Code != want.Status || details.Code != want.Code {
+			t.Fatalf("got %d %q want %d %q", rr.Code, details.Code, want.Status, want.Code)
+		}
+	}
+
+	status(t, probes.Readyz, "/readyz", problem.Kind{})
+	probes.Ready = func(context.Context) error {
+		return errors.New("database schema is at version 3, expected 10")
+	}
+	status(t, probes.Readyz, "/readyz", problem.NotReady)
+	status(t, probes.Healthz, "/healthz", problem.Kind{})
+
+	probes.Ready = nil
+	probes.Drain()
+	status(t, probes.Readyz, "/readyz", problem.ShuttingDown)
+	status(t, probes.Healthz, "/healthz", problem.Kind{})
+}
+
+func TestSnippetAccess(t *testing.T) {
+	rr := doRequest(t, h.RegisterUser, http.MethodPost, "/register", models.User{
+		UserName: "outsider",
+		Email:    "outsider@testNew.com",
+		Password: "Password@123",
+	})
+	var registered struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(rr.Body).Decode(&registered)
+	outsider := registered.Token
+
+	snippet := models.Snippet{Title: "Mine", Language: "Go", Content: "package main"}
+	missing := "/snippets/" + uuid.NewString()
+	owned := "/snippets/" + snippetID
+	for _, tc := range []struct {
+		name   string
+		token  string
+		method string
+		target string
+		want   problem.Kind
+	}{
+		{"get missing", jwtTokenString, http.MethodGet, missing, problem.SnippetNotFound},
+		{"update missing", jwtTokenString, http.MethodPut, missing, problem.SnippetNotFound},
+		{"delete missing", jwtTokenString, http.MethodDelete, missing, problem.SnippetNotFound},
+		{"update foreign", outsider, http.MethodPut, owned, problem.AccessDenied},
+		{"delete foreign", outsider, http.MethodDelete, owned, problem.AccessDenied},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := doRequestWith(
+				t,
+				tc.token,
+				anyVersion,
+				h.HandleSnippet,
+				tc.method,
+				tc.target,
+				snippet,
+			)
+			var details problem.Details
+			json.NewDecoder(rr.Body).Decode(&details)
+			if rr.Code != tc.want.Status || details.Code != tc.want.Code {
+				t.Errorf(
+					"got %d %q want %d %q",
+					rr.Code,
+					details.Code,
+					tc.want.Status,
+					tc.want.Code,
+				)
+			}
+		})
+	}
+
+	t.Run("list foreign", func(t *testing.T) {
+		rr := doRequestAs(t, outsider, h.HandleSnippets, http.MethodGet, "/snippets", nil)
+		var list struct {
+			Items []models.Snippet `json:"items"`
+		}
+		json.NewDecoder(rr.Body).Decode(&list)
+		for _, listed := range list.Items {
+			if listed.SnippetId.String() == snippetID {
+				t.Errorf("another user's snippet was listed")
+			}
+		}
+	})
 }
 
 func TestDeleteUser(t *testing.T) {
@@ -204,11 +1599,21 @@ func TestDeleteUser(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.DeleteUserByID)
+	handler := http.HandlerFunc(h.DeleteUserByID)
 
 	handler.ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
 	}
+
+	rr = doRequest(t, h.RestoreUser, http.MethodPost, "/restoreUser", userData)
+	if rr.Code != http.StatusOK {
+		t.Errorf("restoring a deleted account returned %v: %s", rr.Code, rr.Body)
+	}
+	rr = doRequest(t, h.LoginUser, http.MethodPost, "/login", userData)
+	if rr.Code != http.StatusOK {
+		t.Errorf("login after restore returned %v", rr.Code)
+	}
+	doRequest(t, h.DeleteUserByID, http.MethodDelete, "/deleteUser", userData)
 }
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// listResponse is the envelope of every paginated listing. NextCursor is
+// passed back as ?cursor= to get the following page and is only set when
+// HasMore is.
+type listResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	Limit      int    `json:"limit"`
+}
+
+// parsePage reads the limit, cursor, sort_by and order parameters of a
+// listing. sort_by and order default to the listing's natural order. Errors
+// are problem.Kinds ready to be written.
+func parsePage(r *http.Request, sortBy, order string) (helper.Page, error) {
+	return parseSortedPage(r, sortBy, order, helper.IsValidSortField)
+}
+
+// parseTrashPage is parsePage for listings of the trash, which are most
+// recently deleted first unless asked otherwise.
+func parseTrashPage(r *http.Request) (helper.Page, error) {
+	return parseSortedPage(r, "deleted_at", "desc", helper.IsValidTrashSortField)
+}
+
+// parseSearchPage is parsePage for search results, which are sorted by rank.
+func parseSearchPage(r *http.Request) (helper.Page, error) {
+	return parseSortedPage(r, "rank", "desc", helper.IsValidSearchSortField)
+}
+
+func parseSortedPage(
+	r *http.Request,
+	sortBy string,
+	order string,
+	validSort func(string) bool,
+) (helper.Page, error) {
+	query := r.URL.Query()
+	page := helper.Page{SortBy: sortBy, Order: order, Limit: defaultPageLimit}
+	if s := query.Get("sort_by"); s != "" {
+		page.SortBy = s
+	}
+	if o := query.Get("order"); o != "" {
+		page.Order = o
+	}
+	if !validSort(page.SortBy) || !helper.IsValidOrder(page.Order) {
+		return helper.Page{}, problem.InvalidSortOptions
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return helper.Page{}, problem.InvalidLimit
+		}
+		page.Limit = min(limit, maxPageLimit)
+	}
+
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := helper.DecodeCursor(cursorStr)
+		if err != nil {
+			return helper.Page{}, problem.InvalidCursor
+		}
+		if cursor.SortBy != page.SortBy || cursor.Order != page.Order {
+			return helper.Page{}, problem.CursorSortMismatch
+		}
+		page.After = &cursor
+	}
+	return page, nil
+}
+
+func writeSnippetPage(w http.ResponseWriter, page helper.Page, result models.SnippetPage) {
+	writePage(w, page, result, result.Snippets)
+}
+
+// writePage writes result with its snippets rendered as items, one for
+// each snippet in the same order.
+func writePage[T any](w http.ResponseWriter, page helper.Page, result models.SnippetPage, items []T) {
+	response := listResponse[T]{
+		Items:   items,
+		HasMore: result.HasMore,
+		Limit:   page.Limit,
+	}
+	if result.HasMore {
+		last := result.Snippets[len(result.Snippets)-1]
+		response.NextCursor = helper.EncodeCursor(page.CursorFor(last))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,164 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorTimeFormat has a fixed width so time keys compare correctly as
+// strings.
+const cursorTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// cursorKey signs cursors. It is random until SetCursorKey is called, which
+// only means cursors don't survive a restart.
+var cursorKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// SetCursorKey derives the cursor signing key from secret, so cursors stay
+// valid across restarts and instances that share it.
+func SetCursorKey(secret []byte) {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("snippet list cursor"))
+	cursorKey = mac.Sum(nil)
+}
+
+// Cursor marks the last row of a page: the value of the column the listing
+// is sorted by and the snippet ID that breaks ties. It also records the sort
+// so a cursor can't be replayed against a different ordering.
+type Cursor struct {
+	SortBy string    `json:"s"`
+	Order  string    `json:"o"`
+	Key    string    `json:"k"`
+	ID     uuid.UUID `json:"i"`
+}
+
+// Page asks for one page of a listing sorted by SortBy, starting after the
+// After cursor when it is set.
+type Page struct {
+	SortBy string
+	Order  string
+	Limit  int
+	After  *Cursor
+}
+
+// CursorFor returns the cursor pointing just past snippet in page's order.
+func (p Page) CursorFor(snippet models.Snippet) Cursor {
+	return Cursor{
+		SortBy: p.SortBy,
+		Order:  p.Order,
+		Key:    SortKey(snippet, p.SortBy),
+		ID:     snippet.SnippetId,
+	}
+}
+
+// CursorForResult returns the cursor pointing just past a search result,
+// whose pages are sorted by rank.
+func (p Page) CursorForResult(result models.SnippetSearchResult) Cursor {
+	return Cursor{
+		SortBy: p.SortBy,
+		Order:  p.Order,
+		Key:    strconv.FormatFloat(result.Rank, 'g', -1, 64),
+		ID:     result.SnippetId,
+	}
+}
+
+// SortKey renders the sort column of snippet. Keys of the same column
+// compare as strings in the same order as the column itself.
+func SortKey(snippet models.Snippet, sortBy string) string {
+	switch sortBy {
+	case "title":
+		return snippet.Title
+	case "updated_at":
+		return snippet.UpdatedAt.UTC().Format(cursorTimeFormat)
+	case "deleted_at":
+		if snippet.DeletedAt == nil {
+			return ""
+		}
+		return snippet.DeletedAt.UTC().Format(cursorTimeFormat)
+	default:
+		return snippet.CreatedAt.UTC().Format(cursorTimeFormat)
+	}
+}
+
+// SortValue is Key as the type of its column, for use as a query argument.
+func (c Cursor) SortValue() any {
+	switch c.SortBy {
+	case "title":
+		return c.Key
+	case "rank":
+		// DecodeCursor has already checked that rank keys parse.
+		rank, _ := strconv.ParseFloat(c.Key, 64)
+		return rank
+	}
+	// DecodeCursor has already checked that time keys parse.
+	t, _ := time.Parse(cursorTimeFormat, c.Key)
+	return t
+}
+
+// EncodeCursor returns c as an opaque, signed string.
+func EncodeCursor(c Cursor) string {
+	payload, _ := json.Marshal(c)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signCursor(encoded))
+}
+
+// DecodeCursor verifies and decodes a cursor made by EncodeCursor.
+func DecodeCursor(s string) (Cursor, error) {
+	encoded, signature, ok := strings.Cut(s, ".")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, signCursor(encoded)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	// Callers check that the cursor's sort matches their listing's, which
+	// keeps trash cursors out of the other listings.
+	if !(IsValidTrashSortField(c.SortBy) || IsValidSearchSortField(c.SortBy)) ||
+		!IsValidOrder(c.Order) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	switch c.SortBy {
+	case "title":
+	case "rank":
+		if _, err := strconv.ParseFloat(c.Key, 64); err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+	default:
+		if _, err := time.Parse(cursorTimeFormat, c.Key); err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+func signCursor(encoded string) []byte {
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,65 @@
+package helper_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	helper.SetCursorKey([]byte("a secret"))
+	snippet := models.Snippet{
+		SnippetId: uuid.New(),
+		Title:     "retry",
+		UpdatedAt: time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC),
+	}
+	page := helper.Page{SortBy: "updated_at", Order: "desc", Limit: 10}
+	cursor := page.CursorFor(snippet)
+
+	decoded, err := helper.DecodeCursor(helper.EncodeCursor(cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != cursor {
+		t.Errorf("got %+v want %+v", decoded, cursor)
+	}
+	if got := decoded.SortValue(); !snippet.UpdatedAt.Equal(got.(time.Time)) {
+		t.Errorf("got sort value %v want %v", got, snippet.UpdatedAt)
+	}
+
+	encoded := helper.EncodeCursor(cursor)
+	for _, bad := range []string{"", "garbage", "x" + encoded, encoded + "x"} {
+		if _, err := helper.DecodeCursor(bad); err != helper.ErrInvalidCursor {
+			t.Errorf("%q: got %v want ErrInvalidCursor", bad, err)
+		}
+	}
+
+	helper.SetCursorKey([]byte("another secret"))
+	if _, err := helper.DecodeCursor(encoded); err != helper.ErrInvalidCursor {
+		t.Errorf("cursor signed with another key was accepted")
+	}
+}
+
+func TestSortKeyOrder(t *testing.T) {
+	earlier := models.Snippet{CreatedAt: time.Date(2024, 1, 1, 0, 0, 9, 0, time.UTC)}
+	later := models.Snippet{CreatedAt: time.Date(2024, 1, 1, 0, 0, 10, 5, time.UTC)}
+	if helper.SortKey(earlier, "created_at") >= helper.SortKey(later, "created_at") {
+		t.Errorf("time keys don't sort in time order")
+	}
+}
+
+func TestRankCursor(t *testing.T) {
+	page := helper.Page{SortBy: "rank", Order: "desc", Limit: 10}
+	result := models.SnippetSearchResult{Rank: 0.1}
+	result.SnippetId = uuid.New()
+	decoded, err := helper.DecodeCursor(helper.EncodeCursor(page.CursorForResult(result)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.SortValue(); got != result.Rank {
+		t.Errorf("got sort value %v want %v", got, result.Rank)
+	}
+}
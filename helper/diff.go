@@ -0,0 +1,245 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContext = 3
+	// maxDiffLines and maxDiffBytes bound the texts UnifiedDiff compares,
+	// both sides together, which keeps the Myers search cheap enough to run
+	// while serving a request.
+	maxDiffLines = 10_000
+	maxDiffBytes = 1 << 20
+)
+
+// ErrDiffTooLarge is returned by UnifiedDiff for texts past its size limit.
+var ErrDiffTooLarge = errors.New("texts are too large to diff")
+
+type diffKind byte
+
+const (
+	diffEqual  diffKind = ' '
+	diffDelete diffKind = '-'
+	diffInsert diffKind = '+'
+)
+
+type diffOp struct {
+	kind diffKind
+	line string
+	a, b int // 0-based line positions in the old and new text
+}
+
+// UnifiedDiff returns a unified diff of two texts with three lines of
+// context, or an empty string if they are identical.
+func UnifiedDiff(fromName, toName, from, to string) (string, error) {
+	if len(from)+len(to) > maxDiffBytes {
+		return "", ErrDiffTooLarge
+	}
+	fromLines, toLines := splitLines(from), splitLines(to)
+	if len(fromLines)+len(toLines) > maxDiffLines {
+		return "", ErrDiffTooLarge
+	}
+	ops := diffLines(fromLines, toLines)
+
+	var b strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change and grow the hunk until there is a run of
+		// more than 2*diffContext unchanged lines.
+		first := start
+		for first < len(ops) && ops[first].kind == diffEqual {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != diffEqual {
+				last = i
+			} else if i-last > 2*diffContext {
+				break
+			}
+		}
+		hunkStart := max(first-diffContext, start)
+		hunkEnd := min(last+diffContext+1, len(ops))
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&b, ops[hunkStart:hunkEnd])
+		start = hunkEnd
+	}
+	return b.String(), nil
+}
+
+func writeHunk(b *strings.Builder, ops []diffOp) {
+	aStart, bStart := ops[0].a, ops[0].b
+	aCount, bCount := 0, 0
+	for _, op := range ops {
+		if op.kind != diffInsert {
+			aCount++
+		}
+		if op.kind != diffDelete {
+			bCount++
+		}
+	}
+	// An empty range is addressed by the line before it.
+	if aCount > 0 {
+		aStart++
+	}
+	if bCount > 0 {
+		bStart++
+	}
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, op := range ops {
+		b.WriteByte(byte(op.kind))
+		b.WriteString(op.line)
+		b.WriteByte('\n')
+	}
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a shortest edit script from a to b with the linear
+// space variant of Myers' algorithm: it finds the middle of the edit path,
+// then diffs the halves on either side of it the same way.
+func diffLines(a, b []string) []diffOp {
+	d := differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+type differ struct {
+	a, b []string
+	ops  []diffOp
+}
+
+// compare appends the edit script from a[aLo:aHi] to b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, diffOp{diffEqual, d.a[aLo], aLo, bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+		suffix++
+	}
+
+	if aLo == aHi || bLo == bHi {
+		d.replace(aLo, aHi, bLo, bHi)
+	} else if x, y, ok := d.middle(aLo, aHi, bLo, bHi); ok {
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	} else {
+		d.replace(aLo, aHi, bLo, bHi)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, diffOp{diffEqual, d.a[aHi+i], aHi + i, bHi + i})
+	}
+}
+
+// replace appends the deletion of a[aLo:aHi] and the insertion of
+// b[bLo:bHi].
+func (d *differ) replace(aLo, aHi, bLo, bHi int) {
+	for x := aLo; x < aHi; x++ {
+		d.ops = append(d.ops, diffOp{diffDelete, d.a[x], x, bLo})
+	}
+	for y := bLo; y < bHi; y++ {
+		d.ops = append(d.ops, diffOp{diffInsert, d.b[y], aHi, y})
+	}
+}
+
+// middle searches for the furthest reaching paths from both ends of
+// a[aLo:aHi] and b[bLo:bHi] at once, one edit at a time, and returns the
+// point where they first overlap, which lies on a shortest edit path. ok is
+// false when the ranges have no line in common. Only the two current
+// frontiers are kept, so memory is linear in the length of the ranges.
+func (d *differ) middle(aLo, aHi, bLo, bHi int) (x, y int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+	delta := n - m
+	// With an odd delta the paths meet while extending forward, with an
+	// even one while extending backward.
+	odd := delta%2 != 0
+	// Diagonals that ran off the edge of the grid are skipped from then on.
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+
+	for step := 0; step < maxD; step++ {
+		for k := -step + fStart; k <= step-fEnd; k += 2 {
+			i := offset + k
+			var fx int
+			if k == -step || (k != step && forward[i-1] < forward[i+1]) {
+				fx = forward[i+1]
+			} else {
+				fx = forward[i-1] + 1
+			}
+			fy := fx - k
+			for fx < n && fy < m && d.a[aLo+fx] == d.b[bLo+fy] {
+				fx++
+				fy++
+			}
+			forward[i] = fx
+			switch {
+			case fx > n:
+				fEnd += 2
+			case fy > m:
+				fStart += 2
+			case odd:
+				j := offset + delta - k
+				if j >= 0 && j < len(backward) && backward[j] != -1 && fx >= n-backward[j] {
+					return aLo + fx, bLo + fy, true
+				}
+			}
+		}
+
+		for k := -step + bStart; k <= step-bEnd; k += 2 {
+			i := offset + k
+			var bx int
+			if k == -step || (k != step && backward[i-1] < backward[i+1]) {
+				bx = backward[i+1]
+			} else {
+				bx = backward[i-1] + 1
+			}
+			by := bx - k
+			for bx < n && by < m && d.a[aHi-1-bx] == d.b[bHi-1-by] {
+				bx++
+				by++
+			}
+			backward[i] = bx
+			switch {
+			case bx > n:
+				bEnd += 2
+			case by > m:
+				bStart += 2
+			case !odd:
+				j := offset + delta - k
+				if j >= 0 && j < len(forward) && forward[j] != -1 {
+					fx := forward[j]
+					if fx >= n-bx {
+						return aLo + fx, bLo + fx - (delta - k), true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
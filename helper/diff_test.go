@@ -0,0 +1,57 @@
+package helper_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got, _ := helper.UnifiedDiff("old", "new", from, to); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got, _ := helper.UnifiedDiff("old", "new", from, from); got != "" {
+		t.Errorf("identical texts should have an empty diff, got:\n%s", got)
+	}
+}
+
+func TestUnifiedDiffLimits(t *testing.T) {
+	// Two texts with no line in common take the most edits to diff.
+	var from, to strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&from, "old %d\n", i)
+		fmt.Fprintf(&to, "new %d\n", i)
+	}
+	diff, err := helper.UnifiedDiff("old", "new", from.String(), to.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(diff, "\n-old"); got != 2000 {
+		t.Errorf("got %d deleted lines want 2000", got)
+	}
+
+	huge := strings.Repeat("line\n", 20_000)
+	if _, err := helper.UnifiedDiff("old", "new", huge, "x\n"); !errors.Is(err, helper.ErrDiffTooLarge) {
+		t.Errorf("got %v want ErrDiffTooLarge", err)
+	}
+}
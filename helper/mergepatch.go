@@ -0,0 +1,50 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// MergePatchContentType is the media type of RFC 7396 JSON merge patches.
+const MergePatchContentType = "application/merge-patch+json"
+
+var ErrInvalidMergePatch = errors.New("merge patch must be a JSON object")
+
+// MergePatch applies an RFC 7396 merge patch to the JSON object doc: members
+// of patch replace those of doc, null members delete them and nested objects
+// are merged the same way. Anything but an object replaces a value wholesale,
+// so arrays can't be patched element by element.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target map[string]any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	var changes any
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, err
+	}
+	// Replacing a whole resource is what PUT is for.
+	if _, ok := changes.(map[string]any); !ok {
+		return nil, ErrInvalidMergePatch
+	}
+	return json.Marshal(mergeValue(target, changes))
+}
+
+func mergeValue(target, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	merged, ok := target.(map[string]any)
+	if !ok {
+		merged = map[string]any{}
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = mergeValue(merged[name], value)
+	}
+	return merged
+}
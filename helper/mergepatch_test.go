@@ -0,0 +1,48 @@
+package helper_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Cases from RFC 7396 appendix A that apply to object documents.
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tc := range tests {
+		got, err := helper.MergePatch([]byte(tc.doc), []byte(tc.patch))
+		if err != nil {
+			t.Errorf("MergePatch(%s, %s): %v", tc.doc, tc.patch, err)
+			continue
+		}
+		var gotValue, wantValue any
+		json.Unmarshal(got, &gotValue)
+		json.Unmarshal([]byte(tc.want), &wantValue)
+		if !reflect.DeepEqual(gotValue, wantValue) {
+			t.Errorf("MergePatch(%s, %s) = %s want %s", tc.doc, tc.patch, got, tc.want)
+		}
+	}
+
+	for _, patch := range []string{`["a"]`, `"a"`, `null`} {
+		_, err := helper.MergePatch([]byte(`{"a":"b"}`), []byte(patch))
+		if !errors.Is(err, helper.ErrInvalidMergePatch) {
+			t.Errorf("MergePatch with %s: got %v want ErrInvalidMergePatch", patch, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const maxSearchTerms = 16
+
+// SearchTerm is one clause of a search query. A term with several words is a
+// phrase whose words must appear next to each other. Prefix applies to the
+// last word, so `retr*` matches "retry" and "retries".
+type SearchTerm struct {
+	Words  []string
+	Prefix bool
+}
+
+// ParseSearchQuery splits a user query into terms that must all match.
+// Double-quoted text is a phrase and a trailing * makes a word a prefix.
+func ParseSearchQuery(query string) ([]SearchTerm, error) {
+	var terms []SearchTerm
+	rest := strings.TrimSpace(query)
+	for rest != "" {
+		var raw string
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in search query")
+			}
+			raw, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			raw, rest = rest[:end], rest[end:]
+		}
+		rest = strings.TrimSpace(rest)
+
+		prefix := strings.HasSuffix(raw, "*")
+		words := SearchWords(raw)
+		if len(words) == 0 {
+			continue
+		}
+		terms = append(terms, SearchTerm{Words: words, Prefix: prefix})
+	}
+
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("search query must contain at least one word")
+	}
+	if len(terms) > maxSearchTerms {
+		return nil, fmt.Errorf("search query can have at most %d terms", maxSearchTerms)
+	}
+	return terms, nil
+}
+
+// SearchWords lowercases text and splits it into the words a search can
+// match on.
+func SearchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
@@ -0,0 +1,29 @@
+package helper_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	terms, err := helper.ParseSearchQuery(`retr* "exponential back-off" HTTP`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []helper.SearchTerm{
+		{Words: []string{"retr"}, Prefix: true},
+		{Words: []string{"exponential", "back", "off"}},
+		{Words: []string{"http"}},
+	}
+	if !reflect.DeepEqual(terms, want) {
+		t.Errorf("got %+v want %+v", terms, want)
+	}
+
+	for _, bad := range []string{"", "   ", `"unterminated`, "*** ---"} {
+		if _, err := helper.ParseSearchQuery(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
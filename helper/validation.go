@@ -3,6 +3,7 @@ package helper
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 
@@ -10,30 +11,115 @@ import (
 	"github.com/Jitesh117/snippet-manager-backend/models"
 )
 
+// FieldError is what's wrong with one field of a request. Code is stable and
+// meant for programs, Message for people.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request.
+type ValidationError []FieldError
+
+func (v ValidationError) Error() string {
+	messages := make([]string, len(v))
+	for i, field := range v {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (v *ValidationError) add(field, code, message string) {
+	*v = append(*v, FieldError{Field: field, Code: code, Message: message})
+}
+
+// err returns v as an error, or nil when nothing was wrong.
+func (v ValidationError) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
 func ValidateSnippet(snippet models.Snippet) error {
+	var problems ValidationError
 	if snippet.Title == "" {
-		return fmt.Errorf(constants.ErrEmptyTitle)
+		problems.add("title", "required", constants.ErrEmptyTitle)
 	}
 	if snippet.Language == "" {
-		return fmt.Errorf(constants.ErrEmptyLanguage)
+		problems.add("language", "required", constants.ErrEmptyLanguage)
 	}
 	if snippet.Content == "" {
-		return fmt.Errorf(constants.ErrEmptyContent)
+		problems.add("content", "required", constants.ErrEmptyContent)
+	}
+	switch snippet.Visibility {
+	case "", models.VisibilityPrivate, models.VisibilityUnlisted, models.VisibilityPublic:
+	default:
+		problems.add("visibility", "invalid", constants.ErrInvalidVisibility)
+	}
+	if len(snippet.Tags) > maxTagsPerSnippet {
+		problems.add("tags", "too_many", fmt.Sprintf(constants.ErrTooManyTags, maxTagsPerSnippet))
+	}
+	for i, tag := range snippet.Tags {
+		if err := ValidateTag(tag); err != nil {
+			problems.add(fmt.Sprintf("tags[%d]", i), "invalid", err.Error())
+		}
+	}
+	return problems.err()
+}
+
+const maxTagsPerSnippet = 20
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,31}$`)
+
+// ValidateTag checks a tag that has already been through NormalizeTags.
+func ValidateTag(tag string) error {
+	if !tagPattern.MatchString(tag) {
+		return fmt.Errorf(constants.ErrInvalidTag)
 	}
 	return nil
 }
 
+// NormalizeTags lowercases, trims, de-duplicates and sorts tags. A nil slice
+// stays nil so callers can tell "no tags sent" apart from "clear all tags".
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
 func validateEmail(email string) bool {
 	emailPattern := `^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`
 	return regexp.MustCompile(emailPattern).MatchString(email)
 }
 
-func ValidatePassword(password string) error {
+// ValidatePassword checks a password sent as field.
+func ValidatePassword(field, password string) error {
+	var problems ValidationError
+	validatePassword(&problems, field, password)
+	return problems.err()
+}
+
+func validatePassword(problems *ValidationError, field, password string) {
 	if len(password) < 8 {
-		return fmt.Errorf(constants.ErrPasswordTooShort)
+		problems.add(field, "too_short", constants.ErrPasswordTooShort)
+		return
 	}
 	if len(password) > 20 {
-		return fmt.Errorf(constants.ErrPasswordTooLong)
+		problems.add(field, "too_long", constants.ErrPasswordTooLong)
+		return
 	}
 
 	var (
@@ -71,39 +157,90 @@ func ValidatePassword(password string) error {
 	}
 
 	if len(missing) > 0 {
-		return fmt.Errorf("password must contain at least one %s", strings.Join(missing, ", "))
+		problems.add(
+			field,
+			"too_weak",
+			fmt.Sprintf(constants.ErrInvalidPassword, strings.Join(missing, ", ")),
+		)
 	}
-
-	return nil
 }
 
 func ValidateUser(user models.User) error {
-	if user.UserName == "" {
-		return fmt.Errorf(constants.ErrEmptyUsername)
-	}
-	if len(user.UserName) < 3 {
-		return fmt.Errorf(constants.ErrUsernameTooShort)
+	var problems ValidationError
+	switch {
+	case user.UserName == "":
+		problems.add("username", "required", constants.ErrEmptyUsername)
+	case len(user.UserName) < 3:
+		problems.add("username", "too_short", constants.ErrUsernameTooShort)
+	case len(user.UserName) > 30:
+		problems.add("username", "too_long", constants.ErrUsernameTooLong)
 	}
 
-	if len(user.UserName) > 30 {
-		return fmt.Errorf(constants.ErrUsernameTooLong)
+	switch {
+	case user.Email == "":
+		problems.add("email", "required", constants.ErrEmptyEmail)
+	case !validateEmail(user.Email):
+		problems.add("email", "invalid", constants.ErrInvalidEmailFormat)
 	}
 
-	if user.Email == "" {
-		return fmt.Errorf(constants.ErrEmptyContent)
+	if user.Password == "" {
+		problems.add("password", "required", constants.ErrEmptyPassword)
+	} else {
+		validatePassword(&problems, "password", user.Password)
 	}
-	if !validateEmail(user.Email) {
-		return fmt.Errorf(constants.ErrInvalidEmailFormat)
+	return problems.err()
+}
+
+func ValidateOrgName(name string) error {
+	var problems ValidationError
+	switch {
+	case strings.TrimSpace(name) == "":
+		problems.add("name", "required", constants.ErrEmptyOrgName)
+	case len(name) > 100:
+		problems.add("name", "too_long", constants.ErrOrgNameTooLong)
 	}
+	return problems.err()
+}
 
-	if user.Password == "" {
-		return fmt.Errorf(constants.ErrEmptyPassword)
+func ValidateInvite(email, role string) error {
+	var problems ValidationError
+	switch {
+	case email == "":
+		problems.add("email", "required", constants.ErrEmptyEmail)
+	case !validateEmail(email):
+		problems.add("email", "invalid", constants.ErrInvalidEmailFormat)
 	}
-	if err := ValidatePassword(user.Password); err != nil {
-		return err
+	switch role {
+	case models.RoleOwner, models.RoleEditor, models.RoleViewer:
+	default:
+		problems.add("role", "invalid", constants.ErrInvalidRole)
 	}
+	return problems.err()
+}
 
-	return nil
+func ValidateAccessToken(name string, scopes []string) error {
+	var problems ValidationError
+	switch {
+	case strings.TrimSpace(name) == "":
+		problems.add("name", "required", constants.ErrEmptyTokenName)
+	case len(name) > 100:
+		problems.add("name", "too_long", constants.ErrTokenNameTooLong)
+	}
+	if len(scopes) == 0 {
+		problems.add("scopes", "required", constants.ErrEmptyScopes)
+	}
+	for i, scope := range scopes {
+		switch scope {
+		case models.ScopeSnippetsRead, models.ScopeSnippetsWrite:
+		default:
+			problems.add(
+				fmt.Sprintf("scopes[%d]", i),
+				"invalid",
+				fmt.Sprintf(constants.ErrInvalidScope, scope),
+			)
+		}
+	}
+	return problems.err()
 }
 
 func IsValidSortField(field string) bool {
@@ -115,6 +252,18 @@ func IsValidSortField(field string) bool {
 	return validFields[field]
 }
 
+// IsValidTrashSortField is IsValidSortField for listings of the trash, which
+// can also be sorted by when snippets were deleted.
+func IsValidTrashSortField(field string) bool {
+	return field == "deleted_at" || IsValidSortField(field)
+}
+
+// IsValidSearchSortField is IsValidSortField for search results, which are
+// only sorted by rank.
+func IsValidSearchSortField(field string) bool {
+	return field == "rank"
+}
+
 func IsValidOrder(order string) bool {
 	return order == "asc" || order == "desc"
 }
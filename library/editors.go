@@ -0,0 +1,91 @@
+package library
+
+import (
+	"slices"
+	"strings"
+)
+
+// defaultLanguage is the language of imported editor snippets that don't
+// say theirs, or say one no editor table below knows.
+const defaultLanguage = "Text"
+
+// maxPrefixRunes bounds the prefix, or abbreviation, that editors expand
+// into a snippet.
+const maxPrefixRunes = 32
+
+// editorLanguage is a language as stored and as each editor names it.
+type editorLanguage struct {
+	name    string
+	aliases []string
+	// vscode is the VS Code language identifier.
+	vscode string
+	// jetbrains is the JetBrains live template context, "" where there
+	// isn't one.
+	jetbrains string
+	// sublime is the Sublime Text scope.
+	sublime string
+}
+
+var editorLanguages = []editorLanguage{
+	{"C", nil, "c", "", "source.c"},
+	{"C#", []string{"csharp", "cs"}, "csharp", "", "source.cs"},
+	{"C++", []string{"cpp"}, "cpp", "", "source.c++"},
+	{"CSS", nil, "css", "CSS", "source.css"},
+	{"Go", []string{"golang"}, "go", "GO", "source.go"},
+	{"HTML", nil, "html", "HTML", "text.html"},
+	{"Java", nil, "java", "JAVA_CODE", "source.java"},
+	{"JavaScript", []string{"js"}, "javascript", "JAVA_SCRIPT", "source.js"},
+	{"JSON", nil, "json", "JSON", "source.json"},
+	{"Kotlin", nil, "kotlin", "KOTLIN", "source.Kotlin"},
+	{"Markdown", nil, "markdown", "", "text.html.markdown"},
+	{"PHP", nil, "php", "PHP", "source.php"},
+	{"Python", nil, "python", "Python", "source.python"},
+	{"Ruby", nil, "ruby", "RUBY", "source.ruby"},
+	{"Rust", nil, "rust", "RUST", "source.rust"},
+	{"Shell", []string{"bash", "sh", "zsh"}, "shellscript", "SHELL_SCRIPT", "source.shell"},
+	{"SQL", nil, "sql", "SQL", "source.sql"},
+	{"TypeScript", []string{"ts"}, "typescript", "TypeScript", "source.ts"},
+	{"XML", nil, "xml", "XML", "text.xml"},
+	{"YAML", []string{"yml"}, "yaml", "", "source.yaml"},
+}
+
+// editorLanguageOf looks language up by name or alias, ignoring case.
+func editorLanguageOf(language string) (editorLanguage, bool) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	for _, known := range editorLanguages {
+		if strings.ToLower(known.name) == language || slices.Contains(known.aliases, language) {
+			return known, true
+		}
+	}
+	return editorLanguage{}, false
+}
+
+// languageFrom returns the stored name of the language an editor calls id,
+// as read by editorID, or "" if none is known by that id.
+func languageFrom(id string, editorID func(editorLanguage) string) string {
+	for _, known := range editorLanguages {
+		if id != "" && editorID(known) == id {
+			return known.name
+		}
+	}
+	return ""
+}
+
+// prefix is what an editor expands into a snippet with the given title.
+func prefix(title string) string {
+	if prefix := slug(title, maxPrefixRunes); prefix != "" {
+		return prefix
+	}
+	return "snippet"
+}
+
+// firstTitle returns the first of the candidate titles of an imported
+// editor snippet that isn't blank.
+func firstTitle(candidates ...string) string {
+	for _, title := range candidates {
+		if title = strings.TrimSpace(title); title != "" {
+			return title
+		}
+	}
+	return ""
+}
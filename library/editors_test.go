@@ -0,0 +1,175 @@
+package library_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jitesh117/snippet-manager-backend/library"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+)
+
+func editorSnippets() []models.Snippet {
+	return []models.Snippet{
+		{
+			Title:    "For loop",
+			Language: "Go",
+			Content:  "for ${1:i} := 0; $1 < ${2:n}; $1++ {\n\t$0\n}",
+		},
+		{
+			Title:    "Home",
+			Language: "Shell",
+			Content:  "cd \\$HOME && ${1|ls,ls -la|} ${TM_SELECTED_TEXT}",
+		},
+		{
+			Title:    "CDATA",
+			Language: "Brainfuck",
+			Content:  "]]> is fine",
+		},
+	}
+}
+
+func TestEditorRoundTrip(t *testing.T) {
+	for _, format := range []string{
+		library.FormatVSCode,
+		library.FormatJetBrains,
+		library.FormatSublime,
+	} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := library.NewWriter(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, snippet := range editorSnippets() {
+				if err := writer.Write(snippet); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := library.Read(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := editorSnippets()
+			want[1].Content = "cd \\$HOME && ${1|ls,ls -la|} $TM_SELECTED_TEXT"
+			want[2].Language = "Text"
+			if format != library.FormatJetBrains {
+				want[1].Content = editorSnippets()[1].Content
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEmptyEditorLibrary(t *testing.T) {
+	for _, format := range []string{
+		library.FormatVSCode,
+		library.FormatJetBrains,
+		library.FormatSublime,
+	} {
+		var buf bytes.Buffer
+		writer, _ := library.NewWriter(&buf, format)
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := library.Read(&buf, format)
+		if err != nil || len(got) != 0 {
+			t.Errorf("%s: got %v, %v want no snippets", format, got, err)
+		}
+	}
+}
+
+func TestReadEditorFiles(t *testing.T) {
+	tests := []struct {
+		format, body string
+		want         models.Snippet
+	}{
+		{
+			library.FormatVSCode,
+			`{"Print": {"prefix": ["log", "print"], "body": "console.log($1);",
+				"scope": "javascript,typescript"}}`,
+			models.Snippet{Title: "Print", Language: "JavaScript", Content: "console.log($1);"},
+		},
+		{
+			library.FormatVSCode,
+			`{"": {"prefix": "fn", "body": ["fn $1() {", "}"], "scope": "zig"}}`,
+			models.Snippet{Title: "fn", Language: "zig", Content: "fn $1() {\n}"},
+		},
+		{
+			library.FormatJetBrains,
+			`<templateSet group="user">
+			  <template name="price" value="$$$AMOUNT$ in $CUR$ $END$" description="">
+			    <variable name="CUR" expression="enum(&quot;EUR&quot;, &quot;USD&quot;)"
+			      defaultValue="" alwaysStopAt="true" />
+			    <variable name="AMOUNT" expression="" defaultValue="&quot;1&quot;"
+			      alwaysStopAt="true" />
+			    <variable name="FILE" expression="fileName()" defaultValue=""
+			      alwaysStopAt="false" />
+			    <context>
+			      <option name="JAVA_CODE" value="false" />
+			      <option name="KOTLIN" value="true" />
+			    </context>
+			  </template>
+			</templateSet>`,
+			models.Snippet{
+				Title:    "price",
+				Language: "Kotlin",
+				Content:  "\\$${2:1} in ${1|EUR,USD|} $0",
+			},
+		},
+		{
+			library.FormatSublime,
+			`<snippet>
+			  <content><![CDATA[def ${1:name}(self):
+	${0:pass}]]></content>
+			  <tabTrigger>defs</tabTrigger>
+			  <scope>source.python meta.class</scope>
+			</snippet>`,
+			models.Snippet{
+				Title:    "defs",
+				Language: "Python",
+				Content:  "def ${1:name}(self):\n\t${0:pass}",
+			},
+		},
+	}
+	for _, tc := range tests {
+		got, err := library.Read(strings.NewReader(tc.body), tc.format)
+		if err != nil {
+			t.Errorf("%s: %v", tc.format, err)
+			continue
+		}
+		if len(got) != 1 || !reflect.DeepEqual(got[0], tc.want) {
+			t.Errorf("%s: got %+v want %+v", tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestJetBrainsTemplates(t *testing.T) {
+	var buf bytes.Buffer
+	writer, _ := library.NewWriter(&buf, library.FormatJetBrains)
+	writer.Write(models.Snippet{
+		Title:    "Header",
+		Language: "Go",
+		Content:  "// ${TM_FILENAME} costs \\$5: ${1:what}, $USER",
+	})
+	writer.Close()
+
+	for _, want := range []string{
+		`name="header"`,
+		`value="// $TM_FILENAME$ costs $$5: $VAR1$, $USER$"`,
+		`<variable name="VAR1" expression="" defaultValue="&#34;what&#34;" alwaysStopAt="true">`,
+		`<variable name="TM_FILENAME" expression="fileName()" defaultValue="" alwaysStopAt="false">`,
+		`<variable name="USER" expression="" defaultValue="&#34;USER&#34;" alwaysStopAt="true">`,
+		`<option name="GO" value="true">`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("template set lacks %s:\n%s", want, buf.String())
+		}
+	}
+}
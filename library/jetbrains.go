@@ -0,0 +1,118 @@
+package library
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+)
+
+// jetbrainsGroup names the template group of exported live templates.
+const jetbrainsGroup = "snippets"
+
+// jetbrainsOther is the live template context of languages without one.
+const jetbrainsOther = "OTHER"
+
+type jetbrainsTemplateSet struct {
+	XMLName   xml.Name            `xml:"templateSet"`
+	Group     string              `xml:"group,attr"`
+	Templates []jetbrainsTemplate `xml:"template"`
+}
+
+// jetbrainsTemplate is a live template. Name is the abbreviation that
+// expands it and Value its body, with $NAME$ variables.
+type jetbrainsTemplate struct {
+	Name             string              `xml:"name,attr"`
+	Value            string              `xml:"value,attr"`
+	Description      string              `xml:"description,attr"`
+	ToReformat       bool                `xml:"toReformat,attr"`
+	ToShortenFQNames bool                `xml:"toShortenFQNames,attr"`
+	Variables        []jetbrainsVariable `xml:"variable"`
+	Context          []jetbrainsOption   `xml:"context>option"`
+}
+
+type jetbrainsOption struct {
+	Name  string `xml:"name,attr"`
+	Value bool   `xml:"value,attr"`
+}
+
+// jetbrainsWriter opens the template set with the first snippet or Close,
+// so nothing reaches w before either.
+type jetbrainsWriter struct {
+	encoder *xml.Encoder
+	started bool
+}
+
+func newJetBrainsWriter(w io.Writer) *jetbrainsWriter {
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return &jetbrainsWriter{encoder: encoder}
+}
+
+var jetbrainsSetStart = xml.StartElement{
+	Name: xml.Name{Local: "templateSet"},
+	Attr: []xml.Attr{{Name: xml.Name{Local: "group"}, Value: jetbrainsGroup}},
+}
+
+func (w *jetbrainsWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	return w.encoder.EncodeToken(jetbrainsSetStart)
+}
+
+func (w *jetbrainsWriter) Write(snippet models.Snippet) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	value, variables := toJetBrains(snippet.Content)
+	context := jetbrainsOther
+	if language, ok := editorLanguageOf(snippet.Language); ok && language.jetbrains != "" {
+		context = language.jetbrains
+	}
+	return w.encoder.EncodeElement(jetbrainsTemplate{
+		Name:             prefix(snippet.Title),
+		Value:            value,
+		Description:      snippet.Title,
+		ToShortenFQNames: true,
+		Variables:        variables,
+		Context:          []jetbrainsOption{{Name: context, Value: true}},
+	}, xml.StartElement{Name: xml.Name{Local: "template"}})
+}
+
+func (w *jetbrainsWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if err := w.encoder.EncodeToken(jetbrainsSetStart.End()); err != nil {
+		return err
+	}
+	return w.encoder.Close()
+}
+
+// readJetBrains reads a live template set. Templates get the language of
+// the first context they're enabled in that has one.
+func readJetBrains(r io.Reader) ([]models.Snippet, error) {
+	var set jetbrainsTemplateSet
+	if err := xml.NewDecoder(r).Decode(&set); err != nil {
+		return nil, invalid(err)
+	}
+	snippets := []models.Snippet{}
+	for _, template := range set.Templates {
+		language := defaultLanguage
+		for _, option := range template.Context {
+			name := languageFrom(option.Name, func(l editorLanguage) string { return l.jetbrains })
+			if option.Value && name != "" {
+				language = name
+				break
+			}
+		}
+		snippets = append(snippets, models.Snippet{
+			Title:    firstTitle(template.Description, template.Name),
+			Language: language,
+			Content:  fromJetBrains(template.Value, template.Variables),
+		})
+	}
+	return snippets, nil
+}
@@ -0,0 +1,92 @@
+package library
+
+import (
+	"strings"
+	"unicode"
+)
+
+// extensions maps lowercased language names to file name extensions.
+// Languages that aren't listed get .txt.
+var extensions = map[string]string{
+	"bash":       ".sh",
+	"c":          ".c",
+	"c#":         ".cs",
+	"c++":        ".cpp",
+	"cpp":        ".cpp",
+	"csharp":     ".cs",
+	"css":        ".css",
+	"dart":       ".dart",
+	"dockerfile": ".dockerfile",
+	"elixir":     ".ex",
+	"go":         ".go",
+	"golang":     ".go",
+	"haskell":    ".hs",
+	"html":       ".html",
+	"java":       ".java",
+	"javascript": ".js",
+	"js":         ".js",
+	"json":       ".json",
+	"kotlin":     ".kt",
+	"lua":        ".lua",
+	"makefile":   ".mk",
+	"markdown":   ".md",
+	"perl":       ".pl",
+	"php":        ".php",
+	"powershell": ".ps1",
+	"python":     ".py",
+	"r":          ".r",
+	"ruby":       ".rb",
+	"rust":       ".rs",
+	"scala":      ".scala",
+	"sh":         ".sh",
+	"shell":      ".sh",
+	"sql":        ".sql",
+	"swift":      ".swift",
+	"toml":       ".toml",
+	"ts":         ".ts",
+	"typescript": ".ts",
+	"xml":        ".xml",
+	"yaml":       ".yaml",
+	"yml":        ".yaml",
+	"zsh":        ".sh",
+}
+
+// maxFileNameRunes bounds the part of a file name taken from a title.
+const maxFileNameRunes = 64
+
+// FileName names the file of a snippet in an archive: its title, lowercased
+// with anything but letters and digits turned into dashes, plus the extension
+// of its language.
+func FileName(title, language string) string {
+	name := slug(title, maxFileNameRunes)
+	if name == "" {
+		name = "snippet"
+	}
+	extension, ok := extensions[strings.ToLower(strings.TrimSpace(language))]
+	if !ok {
+		extension = ".txt"
+	}
+	return name + extension
+}
+
+// slug lowercases title and joins its runs of letters and digits with
+// dashes, keeping at most limit runes.
+func slug(title string, limit int) string {
+	var name []rune
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			dash = len(name) > 0
+			continue
+		}
+		if dash {
+			name = append(name, '-')
+			dash = false
+		}
+		name = append(name, r)
+		if len(name) >= limit {
+			break
+		}
+	}
+	return string(name)
+}
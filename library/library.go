@@ -0,0 +1,233 @@
+// Package library reads and writes whole snippet libraries, for backups, for
+// moving snippets between accounts or installations and for the snippet
+// files of code editors.
+package library
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+)
+
+// Library formats.
+const (
+	// FormatNDJSON is one snippet per line, the default.
+	FormatNDJSON = "ndjson"
+	// FormatJSON is a single array of snippets.
+	FormatJSON = "json"
+	// FormatZIP is an archive with a file per snippet and a manifest.json
+	// holding everything but the content.
+	FormatZIP = "zip"
+	// FormatVSCode is a VS Code .code-snippets file.
+	FormatVSCode = "vscode"
+	// FormatJetBrains is a JetBrains live template set.
+	FormatJetBrains = "jetbrains"
+	// FormatSublime is an archive of Sublime Text .sublime-snippet files.
+	// A single .sublime-snippet file can be read as well.
+	FormatSublime = "sublime"
+)
+
+// Formats lists every format, library formats first.
+var Formats = []string{
+	FormatNDJSON,
+	FormatJSON,
+	FormatZIP,
+	FormatVSCode,
+	FormatJetBrains,
+	FormatSublime,
+}
+
+var (
+	ErrUnknownFormat = errors.New("unknown library format")
+	// ErrInvalidLibrary wraps whatever made a library unreadable. Its message
+	// is safe to show to the client that sent the library.
+	ErrInvalidLibrary = errors.New("invalid library")
+	// ErrTooLarge means a library has more than MaxSnippets snippets or an
+	// archive decompresses to more than maxArchiveBytes.
+	ErrTooLarge = errors.New("library is too large")
+)
+
+var contentTypes = map[string]string{
+	FormatNDJSON:    "application/x-ndjson",
+	FormatJSON:      "application/json",
+	FormatZIP:       "application/zip",
+	FormatVSCode:    "application/json",
+	FormatJetBrains: "application/xml",
+	FormatSublime:   "application/zip",
+}
+
+var formatExtensions = map[string]string{
+	FormatVSCode:    ".code-snippets",
+	FormatJetBrains: ".xml",
+	FormatSublime:   ".zip",
+}
+
+// ContentType returns the media type of format.
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// FormatOf returns the library format whose media type is contentType, or ""
+// if none is. Editor formats share media types with library formats, so they
+// are only ever picked by name.
+func FormatOf(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, format := range []string{FormatNDJSON, FormatJSON, FormatZIP} {
+		if mediaType == contentTypes[format] {
+			return format
+		}
+	}
+	return ""
+}
+
+// Extension is the file name extension of libraries in format.
+func Extension(format string) string {
+	if extension, ok := formatExtensions[format]; ok {
+		return extension
+	}
+	return "." + format
+}
+
+// Writer streams a library one snippet at a time. Close finishes the library
+// and must be called even when there were no snippets; it doesn't close the
+// underlying writer.
+type Writer interface {
+	Write(snippet models.Snippet) error
+	Close() error
+}
+
+// NewWriter returns a Writer of format on w.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatZIP:
+		return newZipWriter(w), nil
+	case FormatVSCode:
+		return newVSCodeWriter(w), nil
+	case FormatJetBrains:
+		return newJetBrainsWriter(w), nil
+	case FormatSublime:
+		return newSublimeWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+// Read parses a whole library of format. Snippets are returned as stored,
+// without any validation.
+func Read(r io.Reader, format string) ([]models.Snippet, error) {
+	snippets, err := read(r, format)
+	if err == nil && len(snippets) > MaxSnippets {
+		return nil, ErrTooLarge
+	}
+	return snippets, err
+}
+
+func read(r io.Reader, format string) ([]models.Snippet, error) {
+	switch format {
+	case FormatNDJSON:
+		return readNDJSON(r)
+	case FormatJSON:
+		var snippets []models.Snippet
+		if err := json.NewDecoder(r).Decode(&snippets); err != nil {
+			return nil, invalid(err)
+		}
+		return snippets, nil
+	case FormatZIP:
+		return readZip(r)
+	case FormatVSCode:
+		return readVSCode(r)
+	case FormatJetBrains:
+		return readJetBrains(r)
+	case FormatSublime:
+		return readSublime(r)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+func invalid(err error) error {
+	return fmt.Errorf("%w: %w", ErrInvalidLibrary, err)
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(snippet models.Snippet) error {
+	return w.encoder.Encode(snippet)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+func readNDJSON(r io.Reader) ([]models.Snippet, error) {
+	snippets := []models.Snippet{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxSnippetBytes)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var snippet models.Snippet
+		if err := json.Unmarshal(scanner.Bytes(), &snippet); err != nil {
+			return nil, invalid(fmt.Errorf("line %d: %w", line, err))
+		}
+		snippets = append(snippets, snippet)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, invalid(err)
+	}
+	return snippets, nil
+}
+
+const (
+	// MaxSnippets bounds the snippets of a library that is read.
+	MaxSnippets = 5000
+	// maxSnippetBytes bounds a single snippet of a library.
+	maxSnippetBytes = 4 << 20
+	// maxArchiveBytes bounds what all the files of an archive decompress to.
+	maxArchiveBytes = 64 << 20
+)
+
+// jsonWriter writes the opening bracket with the first snippet, so nothing
+// reaches w before the first snippet or Close.
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (w *jsonWriter) Write(snippet models.Snippet) error {
+	encoded, err := json.Marshal(snippet)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if w.count == 0 {
+		separator = "[\n"
+	}
+	w.count++
+	if _, err := io.WriteString(w.w, separator); err != nil {
+		return err
+	}
+	_, err = w.w.Write(encoded)
+	return err
+}
+
+func (w *jsonWriter) Close() error {
+	end := "\n]\n"
+	if w.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(w.w, end)
+	return err
+}
@@ -0,0 +1,218 @@
+package library_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/library"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+func testSnippets() []models.Snippet {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []models.Snippet{
+		{
+			SnippetId:  uuid.New(),
+			Title:      "Hello, World!",
+			Language:   "Go",
+			Content:    "package main\n\nfunc main() {}\n",
+			Tags:       []string{"demo"},
+			Visibility: models.VisibilityPrivate,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		},
+		{
+			SnippetId:  uuid.New(),
+			Title:      "hello world",
+			Language:   "go",
+			Content:    "// same file name",
+			Tags:       []string{},
+			Visibility: models.VisibilityPublic,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{library.FormatNDJSON, library.FormatJSON, library.FormatZIP} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := library.NewWriter(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := testSnippets()
+			for _, snippet := range want {
+				if err := writer.Write(snippet); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := library.Read(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEmptyLibrary(t *testing.T) {
+	for _, format := range []string{library.FormatNDJSON, library.FormatJSON, library.FormatZIP} {
+		var buf bytes.Buffer
+		writer, _ := library.NewWriter(&buf, format)
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := library.Read(&buf, format)
+		if err != nil || len(got) != 0 {
+			t.Errorf("%s: got %v, %v want no snippets", format, got, err)
+		}
+	}
+}
+
+func TestZipFileNames(t *testing.T) {
+	var buf bytes.Buffer
+	writer, _ := library.NewWriter(&buf, library.FormatZIP)
+	for _, snippet := range testSnippets() {
+		writer.Write(snippet)
+	}
+	writer.Close()
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+	want := []string{"manifest.json", "snippets/hello-world-2.go", "snippets/hello-world.go"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got files %v want %v", names, want)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		title, language, want string
+	}{
+		{"Deploy to prod!", "Shell", "deploy-to-prod.sh"},
+		{"  ", "Go", "snippet.go"},
+		{"Grüße", "Brainfuck", "grüße.txt"},
+		{"../../etc/passwd", "text", "etc-passwd.txt"},
+		{strings.Repeat("a", 100), "python", strings.Repeat("a", 64) + ".py"},
+	}
+	for _, tc := range tests {
+		if got := library.FileName(tc.title, tc.language); got != tc.want {
+			t.Errorf("FileName(%q, %q) = %q want %q", tc.title, tc.language, got, tc.want)
+		}
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := []struct {
+		format, body string
+	}{
+		{library.FormatNDJSON, "{\"title\": \"ok\"}\nnot json\n"},
+		{library.FormatJSON, `{"title": "not an array"}`},
+		{library.FormatZIP, "not a zip"},
+	}
+	for _, tc := range tests {
+		_, err := library.Read(strings.NewReader(tc.body), tc.format)
+		if !errors.Is(err, library.ErrInvalidLibrary) {
+			t.Errorf("%s: got %v want ErrInvalidLibrary", tc.format, err)
+		}
+	}
+	_, err := library.Read(strings.NewReader(""), "xml")
+	if !errors.Is(err, library.ErrUnknownFormat) {
+		t.Errorf("got %v want ErrUnknownFormat", err)
+	}
+}
+
+// archive zips files, each stored as is but claiming to decompress to size
+// bytes when size isn't zero.
+func archive(t *testing.T, files map[string]string, size uint64) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		header := &zip.FileHeader{Name: name, Method: zip.Store}
+		if size > 0 {
+			header.CompressedSize64 = uint64(len(content))
+			header.UncompressedSize64 = size
+			file, err := writer.CreateRaw(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Write([]byte(content))
+			continue
+		}
+		file, err := writer.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadArchiveLimits(t *testing.T) {
+	manifest := func(files ...string) string {
+		entries := make([]string, len(files))
+		for i, file := range files {
+			entries[i] = `{"file": "` + file + `", "title": "t", "language": "Go"}`
+		}
+		return `{"version": 1, "snippets": [` + strings.Join(entries, ",") + `]}`
+	}
+
+	repeated := archive(t, map[string]string{
+		"manifest.json": manifest("snippets/a.go", "snippets/a.go"),
+		"snippets/a.go": "package a",
+	}, 0)
+	_, err := library.Read(bytes.NewReader(repeated), library.FormatZIP)
+	if !errors.Is(err, library.ErrInvalidLibrary) {
+		t.Errorf("file listed twice: got %v want ErrInvalidLibrary", err)
+	}
+
+	files := make([]string, library.MaxSnippets+1)
+	for i := range files {
+		files[i] = "snippets/a.go"
+	}
+	crowded := archive(t, map[string]string{
+		"manifest.json": manifest(files...),
+		"snippets/a.go": "package a",
+	}, 0)
+	_, err = library.Read(bytes.NewReader(crowded), library.FormatZIP)
+	if !errors.Is(err, library.ErrTooLarge) {
+		t.Errorf("too many snippets: got %v want ErrTooLarge", err)
+	}
+
+	// Every file is within the snippet limit, all of them together aren't.
+	bombs := map[string]string{}
+	for i := 0; i < 20; i++ {
+		bombs[fmt.Sprintf("s%d.sublime-snippet", i)] = "<snippet/>"
+	}
+	bomb := archive(t, bombs, 4<<20)
+	_, err = library.Read(bytes.NewReader(bomb), library.FormatSublime)
+	if !errors.Is(err, library.ErrTooLarge) {
+		t.Errorf("archive past the size limit: got %v want ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,420 @@
+package library
+
+import (
+	"cmp"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Snippets are stored with placeholders in the TextMate syntax that VS Code
+// and Sublime Text share: $1 or ${1:default} for tab stops, ${1|a,b|} for
+// choices, $0 for the final cursor and $NAME or ${NAME:default} for
+// variables, with \$, \} and \\ escaping. JetBrains live templates have
+// $NAME$ variables defined next to the template instead, so they are
+// translated going either way.
+
+type segmentKind int
+
+const (
+	literalText segmentKind = iota
+	tabStop
+	namedVariable
+)
+
+// segment is literal text or a placeholder of a TextMate snippet.
+type segment struct {
+	kind segmentKind
+	// text is the literal text, or the name of a variable.
+	text    string
+	number  int
+	value   string
+	choices []string
+}
+
+// parseTextMate splits body into literal text and placeholders. A dollar
+// sign that doesn't start a placeholder is literal, as in editors. Defaults
+// holding placeholders of their own are flattened to text.
+func parseTextMate(body string) []segment {
+	var segments []segment
+	var text strings.Builder
+	for i := 0; i < len(body); {
+		c := body[i]
+		if c == '\\' && i+1 < len(body) && strings.IndexByte(`$}\`, body[i+1]) >= 0 {
+			text.WriteByte(body[i+1])
+			i += 2
+			continue
+		}
+		if c == '$' {
+			if placeholder, n := parsePlaceholder(body[i:]); n > 0 {
+				if text.Len() > 0 {
+					segments = append(segments, segment{kind: literalText, text: text.String()})
+					text.Reset()
+				}
+				segments = append(segments, placeholder)
+				i += n
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	if text.Len() > 0 {
+		segments = append(segments, segment{kind: literalText, text: text.String()})
+	}
+	return segments
+}
+
+// parsePlaceholder parses the placeholder at the start of s, which starts
+// with a dollar sign, and returns it with its length, or a length of 0 if
+// there's none.
+func parsePlaceholder(s string) (segment, int) {
+	if number, n := leadingNumber(s[1:]); n > 0 {
+		return segment{kind: tabStop, number: number}, 1 + n
+	}
+	if name := leadingName(s[1:]); name != "" {
+		return segment{kind: namedVariable, text: name}, 1 + len(name)
+	}
+	if !strings.HasPrefix(s, "${") {
+		return segment{}, 0
+	}
+
+	var placeholder segment
+	i := 2
+	if number, n := leadingNumber(s[i:]); n > 0 {
+		placeholder = segment{kind: tabStop, number: number}
+		i += n
+	} else if name := leadingName(s[i:]); name != "" {
+		placeholder = segment{kind: namedVariable, text: name}
+		i += len(name)
+	} else {
+		return segment{}, 0
+	}
+	if i >= len(s) {
+		return segment{}, 0
+	}
+	switch s[i] {
+	case '}':
+		return placeholder, i + 1
+	case ':':
+		inner, end := untilClosingBrace(s, i+1)
+		if end < 0 {
+			return segment{}, 0
+		}
+		placeholder.value = plainText(parseTextMate(inner))
+		return placeholder, end + 1
+	case '|':
+		end := strings.Index(s[i:], "|}")
+		if placeholder.kind != tabStop || end < 0 {
+			return segment{}, 0
+		}
+		placeholder.choices = splitChoices(s[i+1 : i+end])
+		return placeholder, i + end + 2
+	case '/':
+		// A variable transform, which no other editor has: the variable
+		// is kept without it.
+		_, end := untilClosingBrace(s, i+1)
+		if placeholder.kind != namedVariable || end < 0 {
+			return segment{}, 0
+		}
+		return placeholder, end + 1
+	}
+	return segment{}, 0
+}
+
+func leadingNumber(s string) (int, int) {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	number, _ := strconv.Atoi(s[:n])
+	return number, n
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+
+func leadingName(s string) string {
+	return namePattern.FindString(s)
+}
+
+// untilClosingBrace returns what's between start and the brace closing the
+// placeholder that start is in, and that brace's index, or -1 if it isn't
+// closed.
+func untilClosingBrace(s string, start int) (string, int) {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}' && depth == 0:
+			return s[start:i], i
+		case s[i] == '}':
+			depth--
+		}
+	}
+	return "", -1
+}
+
+func splitChoices(s string) []string {
+	var choices []string
+	var choice strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`$}\,|`, s[i+1]) >= 0:
+			i++
+			choice.WriteByte(s[i])
+		case s[i] == ',':
+			choices = append(choices, choice.String())
+			choice.Reset()
+		default:
+			choice.WriteByte(s[i])
+		}
+	}
+	return append(choices, choice.String())
+}
+
+// plainText is what segments expand to when every placeholder keeps its
+// default.
+func plainText(segments []segment) string {
+	var text strings.Builder
+	for _, segment := range segments {
+		switch {
+		case segment.kind == literalText:
+			text.WriteString(segment.text)
+		case len(segment.choices) > 0:
+			text.WriteString(segment.choices[0])
+		default:
+			text.WriteString(segment.value)
+		}
+	}
+	return text.String()
+}
+
+// escapeTextMate escapes text to read literally in a TextMate snippet:
+// dollar signs always, closing braces inside placeholders and backslashes
+// where they'd escape what follows. More says whether a placeholder or
+// closing brace comes right after text.
+func escapeTextMate(text string, inPlaceholder, more bool) string {
+	var escaped strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '$', c == '}' && inPlaceholder:
+			escaped.WriteByte('\\')
+		case c == '\\' && i+1 < len(text) && strings.IndexByte(`$}\`, text[i+1]) >= 0,
+			c == '\\' && i+1 == len(text) && more:
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteByte(c)
+	}
+	return escaped.String()
+}
+
+// JetBrains variables with special meaning, and the ones that match TextMate
+// variables.
+const (
+	jetbrainsEnd       = "END"
+	jetbrainsSelection = "SELECTION"
+	textMateSelection  = "TM_SELECTED_TEXT"
+)
+
+// jetbrainsExpressions are the JetBrains expressions that compute what the
+// TextMate variable of the same meaning holds.
+var jetbrainsExpressions = map[string]string{
+	"TM_FILENAME":      "fileName()",
+	"TM_FILENAME_BASE": "fileNameWithoutExtension()",
+	"CLIPBOARD":        "clipboard()",
+	"CURRENT_YEAR":     `date("yyyy")`,
+	"CURRENT_MONTH":    `date("MM")`,
+	"CURRENT_DATE":     `date("dd")`,
+}
+
+type jetbrainsVariable struct {
+	Name         string `xml:"name,attr"`
+	Expression   string `xml:"expression,attr"`
+	DefaultValue string `xml:"defaultValue,attr"`
+	AlwaysStopAt bool   `xml:"alwaysStopAt,attr"`
+}
+
+// toJetBrains translates the placeholders of a TextMate body into a
+// JetBrains template and its variables. Tab stops become VAR1, VAR2 and so
+// on, in the order of their numbers, and $0 becomes $END$. Other variables
+// keep their name and, like in VS Code, default to it.
+func toJetBrains(body string) (string, []jetbrainsVariable) {
+	var template strings.Builder
+	tabStops := map[int]*jetbrainsVariable{}
+	var numbers []int
+	var named []jetbrainsVariable
+	seen := map[string]bool{}
+	for _, segment := range parseTextMate(body) {
+		name := segment.text
+		switch {
+		case segment.kind == literalText:
+			template.WriteString(strings.ReplaceAll(segment.text, "$", "$$"))
+			continue
+		case segment.kind == tabStop && segment.number == 0:
+			name = jetbrainsEnd
+		case segment.kind == tabStop:
+			name = "VAR" + strconv.Itoa(segment.number)
+			stop, ok := tabStops[segment.number]
+			if !ok {
+				stop = &jetbrainsVariable{Name: name, AlwaysStopAt: true}
+				tabStops[segment.number] = stop
+				numbers = append(numbers, segment.number)
+			}
+			if stop.Expression == "" && stop.DefaultValue == "" {
+				stop.Expression, stop.DefaultValue = jetbrainsDefault(segment)
+			}
+		case name == textMateSelection:
+			name = jetbrainsSelection
+		case !seen[name]:
+			seen[name] = true
+			variable := jetbrainsVariable{Name: name, Expression: jetbrainsExpressions[name]}
+			if variable.Expression == "" {
+				variable.DefaultValue = strconv.Quote(cmp.Or(segment.value, name))
+				variable.AlwaysStopAt = true
+			}
+			named = append(named, variable)
+		}
+		template.WriteString("$" + name + "$")
+	}
+
+	sort.Ints(numbers)
+	variables := []jetbrainsVariable{}
+	for _, number := range numbers {
+		variables = append(variables, *tabStops[number])
+	}
+	variables = append(variables, named...)
+	return template.String(), variables
+}
+
+// jetbrainsDefault returns the expression and default value of the variable
+// for a tab stop.
+func jetbrainsDefault(stop segment) (expression, defaultValue string) {
+	if len(stop.choices) > 0 {
+		quoted := make([]string, len(stop.choices))
+		for i, choice := range stop.choices {
+			quoted[i] = strconv.Quote(choice)
+		}
+		return "enum(" + strings.Join(quoted, ", ") + ")", ""
+	}
+	if stop.value != "" {
+		return "", strconv.Quote(stop.value)
+	}
+	return "", ""
+}
+
+var (
+	jetbrainsReference = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)\$`)
+	jetbrainsString    = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+	jetbrainsLiteral   = regexp.MustCompile(`^"(?:[^"\\]|\\.)*"$`)
+	jetbrainsEnum      = regexp.MustCompile(`^enum\((.*)\)$`)
+)
+
+// fromJetBrains translates a JetBrains template with its variables into a
+// TextMate body. $END$ becomes $0 and variables that compute what a TextMate
+// variable holds become that variable; the others become tab stops, numbered
+// in the order they're defined in, with their default value or choices on
+// their first use.
+func fromJetBrains(template string, variables []jetbrainsVariable) string {
+	definitions := map[string]jetbrainsVariable{}
+	numbers := map[string]int{}
+	next := 1
+	for _, variable := range variables {
+		definitions[variable.Name] = variable
+		if strings.Contains(template, "$"+variable.Name+"$") && textMateVariable(variable) == "" {
+			numbers[variable.Name] = next
+			next++
+		}
+	}
+
+	var body, text strings.Builder
+	flush := func(more bool) {
+		body.WriteString(escapeTextMate(text.String(), false, more))
+		text.Reset()
+	}
+	used := map[string]bool{}
+	for i := 0; i < len(template); {
+		if strings.HasPrefix(template[i:], "$$") {
+			text.WriteByte('$')
+			i += 2
+			continue
+		}
+		match := jetbrainsReference.FindStringSubmatch(template[i:])
+		if match == nil {
+			text.WriteByte(template[i])
+			i++
+			continue
+		}
+		flush(true)
+		i += len(match[0])
+		name := match[1]
+		definition := definitions[name]
+		switch {
+		case name == jetbrainsEnd:
+			body.WriteString("$0")
+			continue
+		case name == jetbrainsSelection:
+			body.WriteString("$" + textMateSelection)
+			continue
+		case textMateVariable(definition) != "":
+			body.WriteString("$" + textMateVariable(definition))
+			continue
+		}
+		number, ok := numbers[name]
+		if !ok {
+			number = next
+			numbers[name] = number
+			next++
+		}
+		placeholder := "$" + strconv.Itoa(number)
+		if !used[name] {
+			placeholder = textMateTabStop(number, definition)
+		}
+		used[name] = true
+		body.WriteString(placeholder)
+	}
+	flush(false)
+	return body.String()
+}
+
+// textMateVariable returns the TextMate variable that holds what variable
+// computes, or "".
+func textMateVariable(variable jetbrainsVariable) string {
+	for name, expression := range jetbrainsExpressions {
+		if variable.Expression == expression {
+			return name
+		}
+	}
+	return ""
+}
+
+// textMateTabStop writes tab stop number with the default value or choices
+// of variable, when they're plain strings.
+func textMateTabStop(number int, variable jetbrainsVariable) string {
+	if match := jetbrainsEnum.FindStringSubmatch(variable.Expression); match != nil {
+		var choices []string
+		for _, quoted := range jetbrainsString.FindAllString(match[1], -1) {
+			choice, err := strconv.Unquote(quoted)
+			if err != nil {
+				break
+			}
+			choices = append(choices, strings.NewReplacer(
+				`\`, `\\`, `$`, `\$`, `}`, `\}`, `,`, `\,`, `|`, `\|`,
+			).Replace(choice))
+		}
+		if len(choices) > 0 {
+			return "${" + strconv.Itoa(number) + "|" + strings.Join(choices, ",") + "|}"
+		}
+	}
+	if jetbrainsLiteral.MatchString(variable.DefaultValue) {
+		if value, err := strconv.Unquote(variable.DefaultValue); err == nil && value != "" {
+			return "${" + strconv.Itoa(number) + ":" + escapeTextMate(value, true, true) + "}"
+		}
+	}
+	return "$" + strconv.Itoa(number)
+}
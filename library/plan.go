@@ -0,0 +1,129 @@
+package library
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+// What an import does when a snippet conflicts with one the user has.
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+)
+
+// IsValidConflictPolicy reports whether policy is one of the Conflict values.
+func IsValidConflictPolicy(policy string) bool {
+	return policy == ConflictSkip || policy == ConflictOverwrite || policy == ConflictRename
+}
+
+// What an import plan does with one snippet.
+const (
+	// ActionCreate adds the snippet as it is.
+	ActionCreate = "create"
+	// ActionOverwrite replaces the conflicting snippet with the imported one.
+	ActionOverwrite = "overwrite"
+	// ActionRename adds the snippet under a title that doesn't conflict.
+	ActionRename = "rename"
+	// ActionSkip leaves the conflicting snippet alone.
+	ActionSkip = "skip"
+	// ActionDuplicate leaves out a snippet identical to an existing one,
+	// whatever the conflict policy.
+	ActionDuplicate = "duplicate"
+)
+
+// Step is what an import does with one snippet. Snippet is what gets
+// created, or the new state of Existing when overwriting.
+type Step struct {
+	Action   string
+	Snippet  models.Snippet
+	Existing *models.Snippet
+}
+
+// Plan decides what importing snippets into a library that has existing
+// does with each of them. An imported snippet conflicts with the existing
+// one that has its ID, which happens when re-importing an export, or else
+// with one that has the same title and language. Imported snippets also
+// conflict with the ones before them, but only existing snippets are ever
+// overwritten: under that policy, such a conflict is skipped. Snippets must
+// already be normalized and valid.
+func Plan(existing, snippets []models.Snippet, policy string) []Step {
+	byID := map[uuid.UUID]*models.Snippet{}
+	byName := map[string]*models.Snippet{}
+	stored := map[*models.Snippet]bool{}
+	for i := range existing {
+		snippet := &existing[i]
+		byID[snippet.SnippetId] = snippet
+		byName[nameKey(snippet.Title, snippet.Language)] = snippet
+		stored[snippet] = true
+	}
+
+	steps := make([]Step, len(snippets))
+	for i, snippet := range snippets {
+		conflict, ok := byID[snippet.SnippetId]
+		if !ok {
+			conflict = byName[nameKey(snippet.Title, snippet.Language)]
+		}
+		step := Step{Action: ActionCreate, Snippet: snippet, Existing: conflict}
+		switch {
+		case conflict == nil:
+		case sameSnippet(*conflict, snippet):
+			step.Action = ActionDuplicate
+		case policy == ConflictOverwrite && stored[conflict]:
+			step.Action = ActionOverwrite
+			step.Snippet.SnippetId = conflict.SnippetId
+		case policy == ConflictRename:
+			step.Action = ActionRename
+			step.Snippet.Title = freeTitle(byName, snippet.Title, snippet.Language)
+		default:
+			step.Action = ActionSkip
+		}
+
+		switch step.Action {
+		case ActionCreate, ActionRename:
+			added := step.Snippet
+			byName[nameKey(added.Title, added.Language)] = &added
+		case ActionOverwrite:
+			if old := nameKey(conflict.Title, conflict.Language); byName[old] == conflict {
+				delete(byName, old)
+			}
+			// Overwriting always changes the snippet, so a later overwrite
+			// of it in the same import expects the next version.
+			updated := step.Snippet
+			updated.Version = conflict.Version + 1
+			byName[nameKey(updated.Title, updated.Language)] = &updated
+			byID[updated.SnippetId] = &updated
+			stored[&updated] = true
+		}
+		steps[i] = step
+	}
+	return steps
+}
+
+// nameKey identifies a snippet by title and language, ignoring case.
+func nameKey(title, language string) string {
+	return strings.ToLower(strings.TrimSpace(title)) + "\x00" +
+		strings.ToLower(strings.TrimSpace(language))
+}
+
+func sameSnippet(a, b models.Snippet) bool {
+	return a.Title == b.Title &&
+		a.Language == b.Language &&
+		a.Content == b.Content &&
+		slices.Equal(a.Tags, b.Tags)
+}
+
+// freeTitle numbers title, as in "Deploy (2)", until no snippet of the same
+// language has it.
+func freeTitle(byName map[string]*models.Snippet, title, language string) string {
+	for n := 2; ; n++ {
+		candidate := title + " (" + strconv.Itoa(n) + ")"
+		if byName[nameKey(candidate, language)] == nil {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package library_test
+
+import (
+	"testing"
+
+	"github.com/Jitesh117/snippet-manager-backend/library"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+func TestPlan(t *testing.T) {
+	deploy := models.Snippet{
+		SnippetId: uuid.New(),
+		Title:     "Deploy",
+		Language:  "Shell",
+		Content:   "make deploy",
+		Tags:      []string{},
+	}
+	existing := []models.Snippet{deploy}
+
+	changed := deploy
+	changed.Content = "make release"
+	sameName := changed
+	sameName.SnippetId = uuid.New()
+	sameName.Title = "deploy"
+	fresh := models.Snippet{Title: "Lint", Language: "Shell", Content: "make lint"}
+	imported := []models.Snippet{deploy, changed, sameName, fresh, fresh}
+
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{library.ConflictSkip, []string{"duplicate", "skip", "skip", "create", "duplicate"}},
+		{
+			library.ConflictOverwrite,
+			[]string{"duplicate", "overwrite", "overwrite", "create", "duplicate"},
+		},
+		{library.ConflictRename, []string{"duplicate", "rename", "rename", "create", "duplicate"}},
+	}
+	for _, tc := range tests {
+		steps := library.Plan(existing, imported, tc.policy)
+		for i, step := range steps {
+			if step.Action != tc.want[i] {
+				t.Errorf("%s: step %d is %s want %s", tc.policy, i, step.Action, tc.want[i])
+			}
+		}
+		switch tc.policy {
+		case library.ConflictOverwrite:
+			if steps[2].Snippet.SnippetId != deploy.SnippetId {
+				t.Errorf("overwrite by title targets %v want %v",
+					steps[2].Snippet.SnippetId, deploy.SnippetId)
+			}
+		case library.ConflictRename:
+			if steps[1].Snippet.Title != "Deploy (2)" || steps[2].Snippet.Title != "deploy (3)" {
+				t.Errorf("renamed to %q and %q", steps[1].Snippet.Title, steps[2].Snippet.Title)
+			}
+		}
+	}
+
+	// Only existing snippets are overwritten, not earlier ones of the import.
+	other := fresh
+	other.Content = "golangci-lint run"
+	steps := library.Plan(nil, []models.Snippet{fresh, other}, library.ConflictOverwrite)
+	if steps[0].Action != library.ActionCreate || steps[1].Action != library.ActionSkip {
+		t.Errorf("got %s and %s want create and skip", steps[0].Action, steps[1].Action)
+	}
+}
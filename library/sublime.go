@@ -0,0 +1,151 @@
+package library
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+)
+
+// sublimeExtension is the extension of Sublime Text snippet files.
+const sublimeExtension = ".sublime-snippet"
+
+// sublimeSnippet is a .sublime-snippet file. Content is in the TextMate
+// syntax snippets are stored in.
+type sublimeSnippet struct {
+	XMLName     xml.Name `xml:"snippet"`
+	Content     cdata    `xml:"content"`
+	TabTrigger  string   `xml:"tabTrigger,omitempty"`
+	Scope       string   `xml:"scope,omitempty"`
+	Description string   `xml:"description,omitempty"`
+}
+
+type cdata struct {
+	Text string `xml:",cdata"`
+}
+
+// sublimeWriter writes an archive with a .sublime-snippet file per snippet,
+// as Sublime Text wants them.
+type sublimeWriter struct {
+	archive *zip.Writer
+	names   fileNames
+}
+
+func newSublimeWriter(w io.Writer) *sublimeWriter {
+	return &sublimeWriter{archive: zip.NewWriter(w), names: fileNames{}}
+}
+
+func (w *sublimeWriter) Write(snippet models.Snippet) error {
+	base := slug(snippet.Title, maxFileNameRunes)
+	if base == "" {
+		base = "snippet"
+	}
+	name := w.names.unique(base + sublimeExtension)
+	file, err := w.archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: snippet.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	entry := sublimeSnippet{
+		Content:     cdata{snippet.Content},
+		TabTrigger:  prefix(snippet.Title),
+		Description: snippet.Title,
+	}
+	if language, ok := editorLanguageOf(snippet.Language); ok {
+		entry.Scope = language.sublime
+	}
+	encoder := xml.NewEncoder(file)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(entry); err != nil {
+		return err
+	}
+	_, err = io.WriteString(file, "\n")
+	return err
+}
+
+func (w *sublimeWriter) Close() error {
+	return w.archive.Close()
+}
+
+// zipMagic starts every zip archive, empty ones included.
+var zipMagic = []byte("PK")
+
+// readSublime reads an archive of .sublime-snippet files, in archive order,
+// or a single one. Scopes are selectors; snippets get the language of their
+// first scope.
+func readSublime(r io.Reader) ([]models.Snippet, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, zipMagic) {
+		snippet, err := readSublimeSnippet(data, "snippet"+sublimeExtension)
+		if err != nil {
+			return nil, err
+		}
+		return []models.Snippet{snippet}, nil
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, invalid(err)
+	}
+	var files []*zip.File
+	for _, file := range archive.File {
+		if !file.FileInfo().IsDir() && path.Ext(file.Name) == sublimeExtension {
+			files = append(files, file)
+		}
+	}
+	if len(files) > MaxSnippets {
+		return nil, ErrTooLarge
+	}
+	if err := checkArchiveSize(files); err != nil {
+		return nil, err
+	}
+
+	snippets := []models.Snippet{}
+	for _, file := range files {
+		content, err := readZipFile(file, maxSnippetBytes)
+		if err != nil {
+			return nil, err
+		}
+		snippet, err := readSublimeSnippet(content, file.Name)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, snippet)
+	}
+	return snippets, nil
+}
+
+func readSublimeSnippet(data []byte, name string) (models.Snippet, error) {
+	var entry sublimeSnippet
+	if err := xml.Unmarshal(data, &entry); err != nil {
+		return models.Snippet{}, invalid(fmt.Errorf("%s: %w", name, err))
+	}
+	scope := strings.FieldsFunc(entry.Scope, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	language := defaultLanguage
+	if len(scope) > 0 {
+		if known := languageFrom(scope[0], func(l editorLanguage) string {
+			return l.sublime
+		}); known != "" {
+			language = known
+		}
+	}
+	base := strings.TrimSuffix(path.Base(name), sublimeExtension)
+	return models.Snippet{
+		Title:    firstTitle(entry.Description, entry.TabTrigger, base),
+		Language: language,
+		Content:  entry.Content.Text,
+	}, nil
+}
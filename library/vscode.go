@@ -0,0 +1,123 @@
+package library
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+)
+
+// vscodeSnippet is a snippet of a .code-snippets file, which maps snippet
+// names to these. Bodies are in the TextMate syntax snippets are stored in.
+type vscodeSnippet struct {
+	Prefix      lines  `json:"prefix"`
+	Body        lines  `json:"body"`
+	Description string `json:"description,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// lines is a string or an array of strings, which VS Code accepts for both
+// prefixes and bodies; a body array holds its lines.
+type lines []string
+
+func (l *lines) UnmarshalJSON(data []byte) error {
+	var line string
+	if err := json.Unmarshal(data, &line); err == nil {
+		*l = lines{line}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// vscodeWriter writes the opening brace with the first snippet, like
+// jsonWriter. Snippet names must be unique, so titles that were taken are
+// numbered as in "Deploy (2)".
+type vscodeWriter struct {
+	w     io.Writer
+	names map[string]bool
+}
+
+func newVSCodeWriter(w io.Writer) *vscodeWriter {
+	return &vscodeWriter{w: w, names: map[string]bool{}}
+}
+
+func (w *vscodeWriter) Write(snippet models.Snippet) error {
+	name := snippet.Title
+	for n := 2; w.names[name]; n++ {
+		name = snippet.Title + " (" + strconv.Itoa(n) + ")"
+	}
+	entry := vscodeSnippet{
+		Prefix:      lines{prefix(snippet.Title)},
+		Body:        strings.Split(snippet.Content, "\n"),
+		Description: snippet.Title,
+	}
+	if language, ok := editorLanguageOf(snippet.Language); ok {
+		entry.Scope = language.vscode
+	}
+	key, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+	value, err := json.MarshalIndent(entry, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if len(w.names) == 0 {
+		separator = "{\n  "
+	}
+	w.names[name] = true
+	_, err = fmt.Fprintf(w.w, "%s%s: %s", separator, key, value)
+	return err
+}
+
+func (w *vscodeWriter) Close() error {
+	end := "\n}\n"
+	if len(w.names) == 0 {
+		end = "{}\n"
+	}
+	_, err := io.WriteString(w.w, end)
+	return err
+}
+
+// readVSCode reads a .code-snippets file, keeping the order of its snippets.
+// Scopes name a language each; snippets get the first one.
+func readVSCode(r io.Reader) ([]models.Snippet, error) {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, invalid(errors.New("expected an object of snippets"))
+	}
+	snippets := []models.Snippet{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, invalid(err)
+		}
+		name := token.(string)
+		var entry vscodeSnippet
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, invalid(fmt.Errorf("%q: %w", name, err))
+		}
+		var trigger string
+		if len(entry.Prefix) > 0 {
+			trigger = entry.Prefix[0]
+		}
+		scope, _, _ := strings.Cut(entry.Scope, ",")
+		scope = strings.TrimSpace(scope)
+		language := languageFrom(scope, func(l editorLanguage) string { return l.vscode })
+		snippets = append(snippets, models.Snippet{
+			Title:    firstTitle(name, entry.Description, trigger),
+			Language: cmp.Or(language, scope, defaultLanguage),
+			Content:  strings.Join(entry.Body, "\n"),
+		})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, invalid(err)
+	}
+	return snippets, nil
+}
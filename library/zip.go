@@ -0,0 +1,217 @@
+package library
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+)
+
+const (
+	manifestName    = "manifest.json"
+	manifestVersion = 1
+	// snippetDir holds the snippet files of an archive.
+	snippetDir = "snippets/"
+)
+
+// manifest describes every snippet of an archive but its content, which is
+// in File.
+type manifest struct {
+	Version  int             `json:"version"`
+	Snippets []manifestEntry `json:"snippets"`
+}
+
+type manifestEntry struct {
+	File       string    `json:"file"`
+	SnippetID  uuid.UUID `json:"snippet_id"`
+	Title      string    `json:"title"`
+	Language   string    `json:"language"`
+	Tags       []string  `json:"tags"`
+	Visibility string    `json:"visibility"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// zipWriter streams snippet files as they come and writes the manifest last.
+type zipWriter struct {
+	archive  *zip.Writer
+	names    fileNames
+	manifest manifest
+}
+
+func newZipWriter(w io.Writer) *zipWriter {
+	return &zipWriter{
+		archive:  zip.NewWriter(w),
+		names:    fileNames{},
+		manifest: manifest{Version: manifestVersion, Snippets: []manifestEntry{}},
+	}
+}
+
+func (w *zipWriter) Write(snippet models.Snippet) error {
+	name := w.names.unique(snippetDir + FileName(snippet.Title, snippet.Language))
+	file, err := w.archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: snippet.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(file, snippet.Content); err != nil {
+		return err
+	}
+	w.manifest.Snippets = append(w.manifest.Snippets, manifestEntry{
+		File:       name,
+		SnippetID:  snippet.SnippetId,
+		Title:      snippet.Title,
+		Language:   snippet.Language,
+		Tags:       snippet.Tags,
+		Visibility: snippet.Visibility,
+		CreatedAt:  snippet.CreatedAt,
+		UpdatedAt:  snippet.UpdatedAt,
+	})
+	return nil
+}
+
+// fileNames are the file names taken in an archive.
+type fileNames map[string]bool
+
+// unique takes name, numbered as in hello-2.go if an earlier file took it.
+func (names fileNames) unique(name string) string {
+	extension := path.Ext(name)
+	base := strings.TrimSuffix(name, extension)
+	unique := name
+	for n := 2; names[unique]; n++ {
+		unique = base + "-" + strconv.Itoa(n) + extension
+	}
+	names[unique] = true
+	return unique
+}
+
+func (w *zipWriter) Close() error {
+	file, err := w.archive.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(w.manifest); err != nil {
+		return err
+	}
+	return w.archive.Close()
+}
+
+// maxManifestBytes bounds the uncompressed manifest of an archive.
+const maxManifestBytes = 16 << 20
+
+func readZip(r io.Reader) ([]models.Snippet, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, invalid(err)
+	}
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	if files[manifestName] == nil {
+		return nil, invalid(fmt.Errorf("%s is missing", manifestName))
+	}
+	encoded, err := readZipFile(files[manifestName], maxManifestBytes)
+	if err != nil {
+		return nil, err
+	}
+	var index manifest
+	if err := json.Unmarshal(encoded, &index); err != nil {
+		return nil, invalid(fmt.Errorf("%s: %w", manifestName, err))
+	}
+	if index.Version != manifestVersion {
+		return nil, invalid(fmt.Errorf("unsupported manifest version %d", index.Version))
+	}
+
+	if len(index.Snippets) > MaxSnippets {
+		return nil, ErrTooLarge
+	}
+	listed := make([]*zip.File, len(index.Snippets))
+	seen := map[*zip.File]bool{}
+	for i, entry := range index.Snippets {
+		file := files[entry.File]
+		if file == nil {
+			return nil, invalid(
+				fmt.Errorf("%s is listed in %s but missing", entry.File, manifestName),
+			)
+		}
+		if seen[file] {
+			return nil, invalid(
+				fmt.Errorf("%s is listed in %s more than once", entry.File, manifestName),
+			)
+		}
+		seen[file] = true
+		listed[i] = file
+	}
+	if err := checkArchiveSize(listed); err != nil {
+		return nil, err
+	}
+
+	snippets := make([]models.Snippet, 0, len(index.Snippets))
+	for i, entry := range index.Snippets {
+		content, err := readZipFile(listed[i], maxSnippetBytes)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, models.Snippet{
+			SnippetId:  entry.SnippetID,
+			Title:      entry.Title,
+			Language:   entry.Language,
+			Content:    string(content),
+			Tags:       entry.Tags,
+			Visibility: entry.Visibility,
+			CreatedAt:  entry.CreatedAt,
+			UpdatedAt:  entry.UpdatedAt,
+		})
+	}
+	return snippets, nil
+}
+
+// checkArchiveSize returns ErrTooLarge unless files decompress to at most
+// maxArchiveBytes together. It goes by the sizes in the archive's directory,
+// which archive/zip holds files to as they are read, so nothing has to be
+// decompressed to find out.
+func checkArchiveSize(files []*zip.File) error {
+	var total uint64
+	for _, file := range files {
+		total += file.UncompressedSize64
+		if total > maxArchiveBytes {
+			return ErrTooLarge
+		}
+	}
+	return nil
+}
+
+// readZipFile decompresses file, failing once it gets past limit bytes.
+func readZipFile(file *zip.File, limit int64) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, invalid(fmt.Errorf("%s: %w", file.Name, err))
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, invalid(fmt.Errorf("%s: %w", file.Name, err))
+	}
+	if int64(len(data)) > limit {
+		return nil, invalid(fmt.Errorf("%s is larger than %d bytes", file.Name, limit))
+	}
+	return data, nil
+}
@@ -0,0 +1,184 @@
+// Package logging writes JSON logs with log/slog. Every request gets a
+// logger tagged with its request ID, carried in the request context down to
+// the database layer, and one access log line once it has been served.
+// Emails, passwords and tokens are redacted from everything logged.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// RequestIDHeader carries the request ID. One sent by the client or a
+	// proxy in front of the service is kept, so logs line up across both.
+	RequestIDHeader = "X-Request-ID"
+
+	redacted = "[REDACTED]"
+)
+
+// Levels are the names log levels are configured with.
+var Levels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// Setup makes a redacting JSON logger writing to w the default, for the log
+// package too, and returns it.
+func Setup(w io.Writer, level slog.Level) *slog.Logger {
+	logger := New(w, level)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// New returns a JSON logger writing to w that redacts sensitive values.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}))
+}
+
+// sensitiveKeys are attribute keys whose values are never logged, along
+// with any key ending in "token" such as refresh_token.
+var sensitiveKeys = []string{"password", "secret", "authorization", "cookie", "email"}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasSuffix(key, "token") {
+		return true
+	}
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitiveValues find credentials and emails inside any logged string, such
+// as an error message that quotes its input.
+var sensitiveValues = regexp.MustCompile(
+	`[\w.%+-]+@[\w-]+(\.[\w-]+)+` + // emails
+		`|(?i:bearer)\s+\S+` + // Authorization header values
+		`|` + regexp.QuoteMeta(models.AccessTokenPrefix) + `[\w-]+` + // personal access tokens
+		`|eyJ[\w-]+\.[\w-]+\.[\w-]*`, // JWTs
+)
+
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	if sensitiveKey(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(redactString(attr.Value.String()))
+	case slog.KindAny:
+		switch value := attr.Value.Any().(type) {
+		case error:
+			attr.Value = slog.StringValue(redactString(value.Error()))
+		case fmt.Stringer:
+			attr.Value = slog.StringValue(redactString(value.String()))
+		}
+	}
+	return attr
+}
+
+func redactString(s string) string {
+	return sensitiveValues.ReplaceAllString(s, redacted)
+}
+
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger of the request ctx belongs to, or the
+// default logger outside of requests.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// validRequestID keeps what clients send as a request ID short and
+// printable.
+var validRequestID = regexp.MustCompile(`^[\w.:-]{1,128}$`)
+
+// Middleware tags every request with a request ID before passing it to next
+// and writes its access log line, with the pattern of the route that matches
+// it in routes.
+func Middleware(routes *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		logger := slog.Default().With("request_id", requestID)
+		if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+			logger = logger.With("trace_id", span.TraceID().String())
+		}
+		response := &responseLogger{ResponseRecorder: helper.NewResponseRecorder(w)}
+		next.ServeHTTP(response, r.WithContext(WithLogger(r.Context(), logger)))
+
+		_, route := routes.Handler(r)
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.Int("status", response.Status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int64("bytes", response.Bytes),
+		}
+		level := slog.LevelInfo
+		if response.err != nil {
+			attrs = append(attrs, slog.Any("error", response.err))
+		}
+		if response.Status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(r.Context(), level, "request", attrs...)
+	})
+}
+
+// responseLogger is the response of a request Middleware logs.
+type responseLogger struct {
+	*helper.ResponseRecorder
+	err error
+}
+
+// SetError attaches err to the access log line of the request w answers.
+// The error is logged rather than shown to the client, so it can say what
+// went wrong in detail.
+func SetError(w http.ResponseWriter, err error) {
+	for {
+		if response, ok := w.(*responseLogger); ok {
+			response.err = errors.Join(response.err, err)
+			return
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			slog.Error("request failed", "error", err)
+			return
+		}
+		w = unwrapper.Unwrap()
+	}
+}
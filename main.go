@@ -1,44 +1,330 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/Jitesh117/snippet-manager-backend/config"
 	"github.com/Jitesh117/snippet-manager-backend/database"
 	"github.com/Jitesh117/snippet-manager-backend/handlers"
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+	"github.com/Jitesh117/snippet-manager-backend/metrics"
 	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
 )
 
 func main() {
-	database.InitDB()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	logging.Setup(os.Stderr, logging.Levels[cfg.LogLevel])
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(cfg, os.Args[2:])
+			return
+		case "config":
+			fmt.Print(cfg)
+			return
+		}
+	}
+
+	if err := serve(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// serve runs the server until SIGTERM or SIGINT, or until a listener fails,
+// and then shuts it down. It returns rather than exiting so the shutdown
+// always runs and the deferred cleanup closes the database and flushes
+// traces.
+func serve(cfg config.Config) error {
+	slog.Info("starting with configuration", "config", cfg)
+	// ctx is cancelled on SIGTERM or SIGINT, which starts the shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	if cfg.Tracing.Enabled {
+		shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+			OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+			File:         cfg.Tracing.File,
+			SampleRatio:  cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set up tracing: %w", err)
+		}
+		defer shutdownTracing(context.Background())
+	}
+	auth.JWTKey = []byte(cfg.JWTSecret)
+	auth.AccessTokenTTL = cfg.AccessTokenTTL
+	auth.RefreshTokenTTL = cfg.RefreshTokenTTL
+	helper.SetCursorKey([]byte(cfg.JWTSecret))
+	auth.SetRateLimits(
+		auth.RateLimitPolicy(cfg.RateLimit.Auth),
+		auth.RateLimitPolicy(cfg.RateLimit.Read),
+		auth.RateLimitPolicy(cfg.RateLimit.Write),
+		cfg.RateLimit.IdleTimeout,
+	)
+
+	database.InitDB(cfg.DatabaseURL, database.PoolConfig{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+	})
 	defer database.CloseDB()
 
-	// Protected endpoints with rate limiter and JWT middleware
+	if err := database.CheckSchema(ctx, database.DB); err != nil {
+		return fmt.Errorf("refusing to start: %w", err)
+	}
+
+	store := database.NewPostgresStore(database.DB, database.Timeouts{
+		Read:  cfg.Database.ReadTimeout,
+		Write: cfg.Database.WriteTimeout,
+	})
+	auth.Sessions = store
+	auth.AccessTokens = store
+	h := handlers.New(store, store, store)
+	h.TrashRetention = cfg.Trash.Retention
+	h.Ready = store.Ready
+	// The purger stops with ctx and is waited for before the database closes.
+	purgerDone := make(chan struct{})
+	go func() {
+		defer close(purgerDone)
+		database.PurgeTrashEvery(ctx, store, cfg.Trash.Retention, cfg.Trash.PurgeInterval)
+	}()
+
+	// Probes are left out of rate limiting, orchestrators poll them often.
+	http.HandleFunc("/healthz", h.Healthz)
+	http.HandleFunc("/readyz", h.Readyz)
+
+	// Protected endpoints with rate limiter and JWT middleware. Personal
+	// access tokens only work on routes with a scope.
 	http.HandleFunc(
 		"/snippets",
-		auth.RateLimiter(auth.JWTAuthMiddleware(http.HandlerFunc(handlers.HandleSnippets))),
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("HandleSnippets", h.HandleSnippets),
+			auth.SnippetScope,
+		)),
 	)
 	http.HandleFunc(
 		"/snippets/",
-		auth.RateLimiter(auth.JWTAuthMiddleware(http.HandlerFunc(handlers.HandleSnippet))),
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("HandleSnippet", h.HandleSnippet),
+			auth.SnippetScope,
+		)),
+	)
+	http.HandleFunc(
+		"/snippets/bulk",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("BulkSnippets", h.BulkSnippets),
+			auth.SnippetScope,
+		)),
 	)
 
 	http.HandleFunc(
 		"/snippets/language",
-		auth.RateLimiter(auth.JWTAuthMiddleware(http.HandlerFunc(handlers.GetSnippetByLanguage))),
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("GetSnippetByLanguage", h.GetSnippetByLanguage),
+			auth.SnippetScope,
+		)),
 	)
 
 	http.HandleFunc(
 		"/snippets/sorted",
-		auth.RateLimiter(auth.JWTAuthMiddleware(http.HandlerFunc(handlers.GetSortedSnippets))),
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("GetSortedSnippets", h.GetSortedSnippets),
+			auth.SnippetScope,
+		)),
+	)
+
+	http.HandleFunc(
+		"/snippets/search",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("SearchSnippets", h.SearchSnippets),
+			auth.SnippetScope,
+		)),
+	)
+
+	http.HandleFunc(
+		"/tags",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("HandleTags", h.HandleTags),
+			auth.SnippetScope,
+		)),
+	)
+	http.HandleFunc(
+		"/tags/",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("HandleTag", h.HandleTag),
+			auth.SnippetScope,
+		)),
+	)
+
+	http.HandleFunc(
+		"/trash",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("HandleTrash", h.HandleTrash),
+			auth.SnippetScope,
+		)),
+	)
+	http.HandleFunc(
+		"/trash/",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("HandleTrashedSnippet", h.HandleTrashedSnippet),
+			auth.SnippetScope,
+		)),
+	)
+
+	http.HandleFunc(
+		"/export",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("Export", h.Export),
+			auth.SnippetScope,
+		)),
+	)
+	http.HandleFunc(
+		"/import",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("Import", h.Import),
+			auth.SnippetScope,
+		)),
+	)
+
+	http.HandleFunc(
+		"/logout",
+		auth.RateLimiter(auth.JWTAuthMiddleware(tracing.Handler("Logout", h.Logout), nil)),
+	)
+
+	http.HandleFunc(
+		"/tokens",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("HandleAccessTokens", h.HandleAccessTokens),
+			nil,
+		)),
+	)
+	http.HandleFunc(
+		"/tokens/",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("HandleAccessToken", h.HandleAccessToken),
+			nil,
+		)),
+	)
+
+	http.HandleFunc(
+		"/orgs",
+		auth.RateLimiter(auth.JWTAuthMiddleware(tracing.Handler("HandleOrgs", h.HandleOrgs), nil)),
+	)
+	http.HandleFunc(
+		"/orgs/",
+		auth.RateLimiter(auth.JWTAuthMiddleware(tracing.Handler("HandleOrg", h.HandleOrg), nil)),
+	)
+	http.HandleFunc(
+		"/invites",
+		auth.RateLimiter(auth.JWTAuthMiddleware(
+			tracing.Handler("HandleInvites", h.HandleInvites),
+			nil,
+		)),
+	)
+	http.HandleFunc(
+		"/invites/",
+		auth.RateLimiter(auth.JWTAuthMiddleware(tracing.Handler("HandleInvite", h.HandleInvite), nil)),
 	)
 
 	// Open endpoints with just rate limiter
-	http.HandleFunc("/register", auth.RateLimiter(handlers.RegisterUser))
-	http.HandleFunc("/login", auth.RateLimiter(handlers.LoginUser))
-	http.HandleFunc("/deleteUser", auth.RateLimiter(handlers.DeleteUserByID))
-	http.HandleFunc("/changePassword", auth.RateLimiter(handlers.ChangePassword))
+	http.HandleFunc(
+		"/s/",
+		auth.RateLimiter(tracing.Handler("GetSharedSnippet", h.GetSharedSnippet)),
+	)
+	http.HandleFunc("/explore", auth.RateLimiter(tracing.Handler("Explore", h.Explore)))
+	http.HandleFunc(
+		"/register",
+		auth.AuthRateLimiter(tracing.Handler("RegisterUser", h.RegisterUser)),
+	)
+	http.HandleFunc("/login", auth.AuthRateLimiter(tracing.Handler("LoginUser", h.LoginUser)))
+	http.HandleFunc(
+		"/token/refresh",
+		auth.AuthRateLimiter(tracing.Handler("RefreshToken", h.RefreshToken)),
+	)
+	http.HandleFunc(
+		"/deleteUser",
+		auth.AuthRateLimiter(tracing.Handler("DeleteUserByID", h.DeleteUserByID)),
+	)
+	http.HandleFunc(
+		"/restoreUser",
+		auth.AuthRateLimiter(tracing.Handler("RestoreUser", h.RestoreUser)),
+	)
+	http.HandleFunc(
+		"/changePassword",
+		auth.AuthRateLimiter(tracing.Handler("ChangePassword", h.ChangePassword)),
+	)
+
+	// The admin listener serves metrics, which must not be public.
+	admin := http.NewServeMux()
+	admin.Handle("/metrics", metrics.Handler())
+	routes := http.DefaultServeMux
+	servers := []*http.Server{
+		newServer(
+			cfg.ListenAddr,
+			cfg.Server,
+			tracing.Middleware(routes, logging.Middleware(routes, metrics.Instrument(routes))),
+		),
+		newServer(cfg.AdminAddr, cfg.Server, admin),
+	}
+	failed := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				failed <- err
+			}
+		}()
+	}
+	slog.Info("server is running", "addr", cfg.ListenAddr, "admin_addr", cfg.AdminAddr)
+
+	var serveErr error
+	select {
+	case serveErr = <-failed:
+		serveErr = fmt.Errorf("server failed: %w", serveErr)
+	case <-ctx.Done():
+	}
+	// Stops the purger too. A second signal kills the process right away.
+	stop()
+
+	slog.Info(
+		"shutting down",
+		"drain_delay", cfg.Server.DrainDelay,
+		"timeout", cfg.Server.ShutdownTimeout,
+	)
+	h.Drain()
+	time.Sleep(cfg.Server.DrainDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to drain connections", "addr", server.Addr, "error", err)
+		}
+	}
+	<-purgerDone
+	slog.Info("server stopped")
+	return serveErr
+}
 
-	log.Println("Server is running on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+// newServer returns a server for handler on addr with the timeouts of cfg.
+func newServer(addr string, cfg config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
 }
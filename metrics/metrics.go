@@ -0,0 +1,128 @@
+// Package metrics collects the service's Prometheus metrics. They are served
+// by Handler, which belongs on the admin listener rather than the public one.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "snippet"
+
+// unmatchedRoute labels requests that no route handled, so probes for random
+// paths can't blow up the number of series.
+const unmatchedRoute = "unmatched"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time to serve HTTP requests, by route pattern, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests refused by the rate limiter, by policy.",
+	}, []string{"policy"})
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Requests refused by the auth middleware, by error code.",
+	}, []string{"code"})
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time taken by store operations, by operation and whether they failed.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		rateLimited,
+		authFailures,
+		dbQueryDuration,
+	)
+}
+
+// Handler serves every metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB exports the connection pool gauges and counters of db from
+// db.Stats().
+func RegisterDB(db *sql.DB) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, "snippets"))
+}
+
+// RateLimited counts a request the rate limiter refused under policy.
+func RateLimited(policy string) {
+	rateLimited.WithLabelValues(policy).Inc()
+}
+
+// AuthFailed counts a request the auth middleware refused with the problem
+// code.
+func AuthFailed(code string) {
+	authFailures.WithLabelValues(code).Inc()
+}
+
+// ObserveQuery records how long the store operation that began at start
+// took and whether it failed.
+func ObserveQuery(operation string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	dbQueryDuration.WithLabelValues(operation, result).Observe(time.Since(start).Seconds())
+}
+
+// Instrument counts and times every request served by mux, labelled with the
+// pattern of the route that matched rather than the path, which would carry
+// IDs.
+func Instrument(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := unmatchedRoute
+		if _, pattern := mux.Handler(r); pattern != "" {
+			route = pattern
+		}
+		recorder := helper.NewResponseRecorder(w)
+		mux.ServeHTTP(recorder, r)
+
+		method := methodLabel(r.Method)
+		status := strconv.Itoa(recorder.Status)
+		httpRequests.WithLabelValues(route, method, status).Inc()
+		httpDuration.WithLabelValues(route, method, status).
+			Observe(time.Since(start).Seconds())
+	})
+}
+
+// methodLabel keeps made-up methods from adding series.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
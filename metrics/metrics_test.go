@@ -0,0 +1,70 @@
+package metrics_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jitesh117/snippet-manager-backend/metrics"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestInstrumentLabelsByRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/things/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := metrics.Instrument(mux)
+	for _, path := range []string{"/things/1", "/things/2", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/things/3", nil))
+
+	body := scrape(t)
+	for _, want := range []string{
+		`snippet_http_requests_total{method="GET",route="/things/",status="418"} 2`,
+		`snippet_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`snippet_http_requests_total{method="OTHER",route="/things/",status="418"} 1`,
+		`snippet_http_request_duration_seconds_count{method="GET",route="/things/",status="418"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics are missing %s", want)
+		}
+	}
+	if strings.Contains(body, "/things/1") {
+		t.Errorf("paths leaked into labels")
+	}
+}
+
+func TestCounters(t *testing.T) {
+	metrics.RateLimited("read")
+	metrics.AuthFailed("invalid_token")
+	metrics.ObserveQuery("GetSnippetByID", time.Now(), nil)
+	metrics.ObserveQuery("GetSnippetByID", time.Now(), errors.New("boom"))
+
+	body := scrape(t)
+	for _, want := range []string{
+		`snippet_rate_limited_requests_total{policy="read"} 1`,
+		`snippet_auth_failures_total{code="invalid_token"} 1`,
+		`snippet_db_query_duration_seconds_count{operation="GetSnippetByID",result="ok"} 1`,
+		`snippet_db_query_duration_seconds_count{operation="GetSnippetByID",result="error"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics are missing %s", want)
+		}
+	}
+}
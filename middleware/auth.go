@@ -7,48 +7,144 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Jitesh117/snippet-manager-backend/metrics"
+	"github.com/Jitesh117/snippet-manager-backend/models"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var JWTKey = []byte("your_secret_key")
 
-func ExtractUserIDFromToken(r *http.Request) (uuid.UUID, error) {
+var (
+	// AccessTokenTTL is how long an access token works. They can be revoked,
+	// but keeping them short-lived limits the damage of a leaked one.
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a refresh token can be traded for a new
+	// pair before the user has to log in again.
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// SessionChecker tells whether a session has been revoked.
+type SessionChecker interface {
+	SessionActive(ctx context.Context, sessionID uuid.UUID) (bool, error)
+}
+
+// Sessions is consulted by JWTAuthMiddleware on every request so logging
+// out, changing the password or deleting the account cuts off access tokens
+// that haven't expired yet. It must be set before serving.
+var Sessions SessionChecker
+
+// AccessTokenAuthenticator resolves personal access tokens.
+type AccessTokenAuthenticator interface {
+	AuthenticateAccessToken(ctx context.Context, token string) (models.AccessToken, bool, error)
+}
+
+// AccessTokens lets JWTAuthMiddleware accept personal access tokens. It must
+// be set before serving.
+var AccessTokens AccessTokenAuthenticator
+
+// RouteScope returns the scope a personal access token needs to make r.
+type RouteScope func(r *http.Request) string
+
+// SnippetScope needs snippets:read to read and snippets:write for anything
+// else.
+func SnippetScope(r *http.Request) string {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return models.ScopeSnippetsRead
+	}
+	return models.ScopeSnippetsWrite
+}
+
+// hasScope reports whether scopes grant required. Write implies read.
+func hasScope(scopes []string, required string) bool {
+	for _, scope := range scopes {
+		if scope == required ||
+			scope == models.ScopeSnippetsWrite && required == models.ScopeSnippetsRead {
+			return true
+		}
+	}
+	return false
+}
+
+func bearerToken(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return uuid.UUID{}, fmt.Errorf("Authorization header missing")
+		return "", fmt.Errorf("Authorization header missing")
+	}
+
+	tokenString, ok := strings.CutPrefix(authHeader, "Bearer ") // "Bearer <token>"
+	if !ok {
+		return "", fmt.Errorf("Invalid token")
 	}
+	return tokenString, nil
+}
 
-	tokenString := strings.Split(authHeader, " ")[1] // "Bearer <token>"
+func parseToken(r *http.Request) (jwt.MapClaims, error) {
+	tokenString, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
 
-	claims := &jwt.MapClaims{}
+	claims := jwt.MapClaims{}
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		claims,
 		func(token *jwt.Token) (interface{}, error) {
 			return JWTKey, nil
 		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
 	)
 	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("Invalid token")
+	}
+	return claims, nil
+}
+
+func uuidClaim(claims jwt.MapClaims, name string) (uuid.UUID, error) {
+	value, ok := claims[name].(string)
+	if !ok {
 		return uuid.UUID{}, fmt.Errorf("Invalid token")
 	}
+	return uuid.Parse(value)
+}
+
+// ExtractUserIDFromToken returns the caller, as authenticated by
+// JWTAuthMiddleware or else from the session token.
+func ExtractUserIDFromToken(r *http.Request) (uuid.UUID, error) {
+	if userID, ok := r.Context().Value(UserContextKey).(uuid.UUID); ok {
+		return userID, nil
+	}
+	claims, err := parseToken(r)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return uuidClaim(claims, "user_id")
+}
 
-	userIDStr := (*claims)["user_id"].(string)
-	userID, err := uuid.Parse(userIDStr)
+// ExtractSessionIDFromToken returns the session the access token belongs to.
+func ExtractSessionIDFromToken(r *http.Request) (uuid.UUID, error) {
+	claims, err := parseToken(r)
 	if err != nil {
 		return uuid.UUID{}, err
 	}
-	return userID, nil
+	return uuidClaim(claims, "sid")
 }
 
 type contextKey string
 
 const UserContextKey = contextKey("user_id")
 
-func GenerateJWT(userID uuid.UUID) (string, error) {
+// GenerateJWT returns an access token for userID, tied to sessionID so it
+// dies with the session.
+func GenerateJWT(userID, sessionID uuid.UUID) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+		"sid":     sessionID,
+		"exp":     time.Now().Add(AccessTokenTTL).Unix(),
 	})
 	tokenString, err := token.SignedString(JWTKey)
 	if err != nil {
@@ -57,15 +153,107 @@ func GenerateJWT(userID uuid.UUID) (string, error) {
 	return tokenString, nil
 }
 
-func JWTAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+// JWTAuthMiddleware authenticates the caller with a session access token or
+// a personal access token. Personal access tokens need the scope returned by
+// scope and are refused outright when scope is nil.
+func JWTAuthMiddleware(next http.HandlerFunc, scope RouteScope) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userID, err := ExtractUserIDFromToken(r)
-		if err != nil {
-			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		ctx, span := tracing.Start(r.Context(), "JWTAuthMiddleware")
+		userID, ok := authenticate(w, r.WithContext(ctx), scope)
+		span.SetAttributes(attribute.Bool("auth.ok", ok))
+		span.End()
+		if !ok {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), UserContextKey, userID)
+		tracing.SetUser(r.Context(), userID)
+		ctx = context.WithValue(r.Context(), UserContextKey, userID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// authenticate returns the caller of r. When it refuses the request, it has
+// answered it already.
+func authenticate(w http.ResponseWriter, r *http.Request, scope RouteScope) (uuid.UUID, bool) {
+	if r.Header.Get("Authorization") == "" {
+		refuse(w, problem.AuthorizationMissing)
+		return uuid.UUID{}, false
+	}
+	tokenString, err := bearerToken(r)
+	if err != nil {
+		refuse(w, problem.InvalidToken)
+		return uuid.UUID{}, false
+	}
+	if strings.HasPrefix(tokenString, models.AccessTokenPrefix) {
+		return authenticateAccessToken(w, r, tokenString, scope)
+	}
+	return authenticateSession(w, r)
+}
+
+// refuse answers a request the middleware won't let through and counts it as
+// an auth failure.
+func refuse(w http.ResponseWriter, kind problem.Kind) {
+	metrics.AuthFailed(kind.Code)
+	problem.Write(w, kind)
+}
+
+// refuseError is refuse for a failure to check the credentials at all.
+func refuseError(w http.ResponseWriter, err error, kind problem.Kind) {
+	metrics.AuthFailed(kind.Code)
+	problem.WriteError(w, err, kind)
+}
+
+func authenticateSession(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	claims, err := parseToken(r)
+	if err != nil {
+		refuse(w, problem.InvalidToken)
+		return uuid.UUID{}, false
+	}
+	userID, err := uuidClaim(claims, "user_id")
+	if err != nil {
+		refuse(w, problem.InvalidToken)
+		return uuid.UUID{}, false
+	}
+	// Tokens without a session predate revocation and are refused.
+	sessionID, err := uuidClaim(claims, "sid")
+	if err != nil {
+		refuse(w, problem.InvalidToken)
+		return uuid.UUID{}, false
+	}
+	active, err := Sessions.SessionActive(r.Context(), sessionID)
+	if err != nil {
+		refuseError(w, err, problem.FailedToAuthenticate)
+		return uuid.UUID{}, false
+	}
+	if !active {
+		refuse(w, problem.SessionEnded)
+		return uuid.UUID{}, false
+	}
+	return userID, true
+}
+
+func authenticateAccessToken(
+	w http.ResponseWriter,
+	r *http.Request,
+	tokenString string,
+	scope RouteScope,
+) (uuid.UUID, bool) {
+	if scope == nil {
+		refuse(w, problem.TokenNotAllowed)
+		return uuid.UUID{}, false
+	}
+	token, ok, err := AccessTokens.AuthenticateAccessToken(r.Context(), tokenString)
+	if err != nil {
+		refuseError(w, err, problem.FailedToAuthenticate)
+		return uuid.UUID{}, false
+	}
+	if !ok {
+		refuse(w, problem.InvalidToken)
+		return uuid.UUID{}, false
+	}
+	if required := scope(r); !hasScope(token.Scopes, required) {
+		refuse(w, problem.MissingScope.Withf(required))
+		return uuid.UUID{}, false
+	}
+	return token.UserID, true
+}
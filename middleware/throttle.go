@@ -1,19 +1,181 @@
 package middleware
 
 import (
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/Jitesh117/snippet-manager-backend/metrics"
+	"github.com/Jitesh117/snippet-manager-backend/problem"
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/time/rate"
 )
 
-var rateLimiter = rate.NewLimiter(1, 5)
+// RateLimitPolicy is a token bucket: Burst requests at once, refilled at
+// RequestsPerSecond.
+type RateLimitPolicy struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// clientLimiters holds one bucket per client for a class of routes.
+type clientLimiters struct {
+	mu          sync.Mutex
+	name        string
+	policy      RateLimitPolicy
+	idleTimeout time.Duration
+	clients     map[string]*clientLimiter
+	lastSweep   time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newClientLimiters(
+	name string,
+	policy RateLimitPolicy,
+	idleTimeout time.Duration,
+) *clientLimiters {
+	return &clientLimiters{
+		name:        name,
+		policy:      policy,
+		idleTimeout: idleTimeout,
+		clients:     make(map[string]*clientLimiter),
+		lastSweep:   time.Now(),
+	}
+}
+
+// allow takes a token from the bucket of key. It returns how many requests
+// are left and, when refused, how long until the next one is allowed.
+func (l *clientLimiters) allow(key string) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.evictIdle(now)
+	client, ok := l.clients[key]
+	if !ok {
+		client = &clientLimiter{
+			limiter: rate.NewLimiter(rate.Limit(l.policy.RequestsPerSecond), l.policy.Burst),
+		}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+
+	if client.limiter.AllowN(now, 1) {
+		return true, int(client.limiter.TokensAt(now)), 0
+	}
+	missing := 1 - client.limiter.TokensAt(now)
+	return false, 0, time.Duration(missing / l.policy.RequestsPerSecond * float64(time.Second))
+}
+
+// evictIdle drops buckets that haven't been used for idleTimeout. An idle
+// bucket has refilled completely, so forgetting it changes nothing for the
+// client.
+func (l *clientLimiters) evictIdle(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idleTimeout {
+		return
+	}
+	for key, client := range l.clients {
+		if now.Sub(client.lastSeen) >= l.idleTimeout {
+			delete(l.clients, key)
+		}
+	}
+	l.lastSweep = now
+}
 
+var (
+	limitersMu                                sync.RWMutex
+	authLimiters, readLimiters, writeLimiters *clientLimiters
+)
+
+func init() {
+	SetRateLimits(
+		RateLimitPolicy{RequestsPerSecond: 0.2, Burst: 5},
+		RateLimitPolicy{RequestsPerSecond: 10, Burst: 20},
+		RateLimitPolicy{RequestsPerSecond: 2, Burst: 10},
+		10*time.Minute,
+	)
+}
+
+// SetRateLimits replaces the policies of every rate-limited route. Auth
+// routes are the credential endpoints wrapped with AuthRateLimiter, the rest
+// count as reads or writes by method. Buckets unused for idleTimeout are
+// dropped.
+func SetRateLimits(authPolicy, read, write RateLimitPolicy, idleTimeout time.Duration) {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	authLimiters = newClientLimiters("auth", authPolicy, idleTimeout)
+	readLimiters = newClientLimiters("read", read, idleTimeout)
+	writeLimiters = newClientLimiters("write", write, idleTimeout)
+}
+
+// RateLimiter limits each client separately, with GET and HEAD requests
+// drawing from the read policy and everything else from the write policy.
 func RateLimiter(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !rateLimiter.Allow() {
-			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
-			return
+		limitersMu.RLock()
+		limiters := writeLimiters
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			limiters = readLimiters
 		}
-		next.ServeHTTP(w, r)
+		limitersMu.RUnlock()
+		limit(limiters, w, r, next)
 	})
 }
+
+// AuthRateLimiter limits credential endpoints such as login with the strict
+// auth policy, so password guessing doesn't share a budget with normal use.
+func AuthRateLimiter(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limitersMu.RLock()
+		limiters := authLimiters
+		limitersMu.RUnlock()
+		limit(limiters, w, r, next)
+	})
+}
+
+func limit(limiters *clientLimiters, w http.ResponseWriter, r *http.Request, next http.Handler) {
+	_, span := tracing.Start(
+		r.Context(),
+		"RateLimiter",
+		attribute.String("ratelimit.policy", limiters.name),
+	)
+	allowed, remaining, retryAfter := limiters.allow(clientKey(r))
+	span.SetAttributes(attribute.Bool("ratelimit.allowed", allowed))
+	span.End()
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limiters.policy.Burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	if !allowed {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+		metrics.RateLimited(limiters.name)
+		problem.Write(w, problem.TooManyRequests)
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+// clientKey identifies who a request counts against. Rate limiting runs
+// before authentication, so a session token only counts as its user when its
+// signature checks out. Personal access tokens can't be checked without a
+// lookup, and anyone can make up new ones, so they count as their IP address
+// like everyone else; proxy headers are not trusted.
+func clientKey(r *http.Request) string {
+	if claims, err := parseToken(r); err == nil {
+		if userID, err := uuidClaim(claims, "user_id"); err == nil {
+			return "user:" + userID.String()
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	auth "github.com/Jitesh117/snippet-manager-backend/middleware"
+)
+
+func TestRateLimiterPerClient(t *testing.T) {
+	auth.SetRateLimits(
+		auth.RateLimitPolicy{RequestsPerSecond: 0.01, Burst: 1},
+		auth.RateLimitPolicy{RequestsPerSecond: 0.01, Burst: 2},
+		auth.RateLimitPolicy{RequestsPerSecond: 0.01, Burst: 1},
+		time.Minute,
+	)
+	handler := auth.RateLimiter(func(w http.ResponseWriter, r *http.Request) {})
+	request := func(method, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/snippets", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := request(http.MethodGet, "192.0.2.1:1000")
+	if rr.Code != http.StatusOK || rr.Header().Get("RateLimit-Limit") != "2" ||
+		rr.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("first read returned %v with headers %v", rr.Code, rr.Header())
+	}
+	request(http.MethodGet, "192.0.2.1:1001")
+	rr = request(http.MethodGet, "192.0.2.1:1002")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("read over the limit returned %v with headers %v", rr.Code, rr.Header())
+	}
+
+	// Writes have their own bucket, and so does every other client.
+	if rr := request(http.MethodPost, "192.0.2.1:1003"); rr.Code != http.StatusOK {
+		t.Errorf("first write returned %v", rr.Code)
+	}
+	if rr := request(http.MethodGet, "192.0.2.2:1000"); rr.Code != http.StatusOK {
+		t.Errorf("another client was throttled: %v", rr.Code)
+	}
+
+	// Made up access tokens don't get a bucket of their own.
+	login := auth.AuthRateLimiter(func(w http.ResponseWriter, r *http.Request) {})
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "192.0.2.3:1000"
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer snip_pat_guess%d", i))
+		rr := httptest.NewRecorder()
+		login.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("login %d returned %v want %v", i, rr.Code, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/Jitesh117/snippet-manager-backend/config"
+	"github.com/Jitesh117/snippet-manager-backend/database"
+)
+
+const migrateUsage = "usage: snippet-manager migrate up|down [steps]|status"
+
+// runMigrate implements the `migrate` subcommand.
+func runMigrate(cfg config.Config, args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	database.InitDB(cfg.DatabaseURL, database.PoolConfig{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+	})
+	defer database.CloseDB()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(database.DB)
+		for _, m := range applied {
+			slog.Info("applied migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		if len(applied) == 0 {
+			slog.Info("database is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatal(migrateUsage)
+			}
+		}
+		reverted, err := database.MigrateDown(database.DB, steps)
+		for _, m := range reverted {
+			slog.Info("reverted migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+	case "status":
+		statuses, err := database.GetMigrationStatus(database.DB)
+		if err != nil {
+			log.Fatal("Failed to get migration status: ", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(os.Stdout, "%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
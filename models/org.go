@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization is a team whose members share a snippet library. Role is the
+// requesting user's role when the organization is listed for them.
+type Organization struct {
+	OrgID     uuid.UUID `json:"org_id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+type OrgMember struct {
+	UserID   uuid.UUID `json:"user_id"`
+	UserName string    `json:"user_name"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// OrgInvite asks whoever registered with Email to join an organization.
+type OrgInvite struct {
+	InviteID  uuid.UUID `json:"invite_id"`
+	OrgID     uuid.UUID `json:"org_id"`
+	OrgName   string    `json:"org_name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
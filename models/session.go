@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a login with its current refresh token. RefreshToken is only
+// ever available right after the session is created or refreshed.
+type Session struct {
+	SessionID    uuid.UUID
+	UserID       uuid.UUID
+	RefreshToken string
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShareLink gives unauthenticated read access to an unlisted snippet. Token
+// is only ever set in the response that creates the link.
+type ShareLink struct {
+	ShareID   uuid.UUID  `json:"share_id"`
+	SnippetID uuid.UUID  `json:"snippet_id"`
+	Token     string     `json:"token,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
@@ -7,10 +7,72 @@ import (
 )
 
 type Snippet struct {
+	SnippetId  uuid.UUID  `json:"snippet_id"`
+	Title      string     `json:"title"`
+	Language   string     `json:"language"`
+	Content    string     `json:"content"`
+	Tags       []string   `json:"tags"`
+	Visibility string     `json:"visibility"`
+	OrgID      *uuid.UUID `json:"org_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Version    int        `json:"version"`
+	// DeletedAt is set while the snippet is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+const (
+	VisibilityPrivate  = "private"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPublic   = "public"
+)
+
+// SnippetPage is one page of a snippet listing. HasMore reports whether
+// another page follows it.
+type SnippetPage struct {
+	Snippets []Snippet
+	HasMore  bool
+}
+
+// SnippetSearchResult is a snippet matched by a full-text search. The
+// highlight fields are HTML: the text is escaped and matching words are
+// wrapped in <mark></mark>.
+type SnippetSearchResult struct {
+	Snippet
+	Rank             float64 `json:"rank"`
+	TitleHighlight   string  `json:"title_highlight"`
+	ContentHighlight string  `json:"content_highlight"`
+}
+
+// SnippetSearchPage is one page of search results, like SnippetPage.
+type SnippetSearchPage struct {
+	Results []SnippetSearchResult
+	HasMore bool
+}
+
+// SnippetRevision is an immutable copy of a snippet as of one create, update
+// or restore.
+type SnippetRevision struct {
 	SnippetId uuid.UUID `json:"snippet_id"`
+	Revision  int       `json:"revision"`
 	Title     string    `json:"title"`
 	Language  string    `json:"language"`
 	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Operations of a snippet batch.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// BulkOperation is one item of a snippet batch. Creates and updates carry the
+// snippet's fields; updates and deletes name it by SnippetId and must set
+// Version, which has to match like an If-Match header.
+type BulkOperation struct {
+	Op string `json:"op"`
+	Snippet
 }
@@ -0,0 +1,6 @@
+package models
+
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
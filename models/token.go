@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeSnippetsRead  = "snippets:read"
+	ScopeSnippetsWrite = "snippets:write"
+)
+
+// AccessTokenPrefix starts every personal access token, which tells them
+// apart from session JWTs and makes leaked ones easy to search for.
+const AccessTokenPrefix = "snip_pat_"
+
+// AccessToken is a personal access token. Token is only ever set in the
+// response that creates or rotates it.
+type AccessToken struct {
+	TokenID    uuid.UUID  `json:"token_id"`
+	UserID     uuid.UUID  `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,413 @@
+package problem
+
+import (
+	"net/http"
+
+	"github.com/Jitesh117/snippet-manager-backend/constants"
+)
+
+// The catalog of every error the API returns. Codes are part of the API:
+// clients match on them, so once published a code never changes meaning.
+var (
+	// General errors
+	MethodNotAllowed = kind(
+		"method_not_allowed",
+		http.StatusMethodNotAllowed,
+		constants.ErrMethodNotAllowed,
+	)
+	InvalidPayload   = kind("invalid_payload", http.StatusBadRequest, constants.ErrInvalidPayload)
+	UnsupportedMedia = kind(
+		"unsupported_media_type",
+		http.StatusUnsupportedMediaType,
+		constants.ErrUnsupportedMedia,
+	)
+	PayloadTooLarge = kind(
+		"payload_too_large",
+		http.StatusRequestEntityTooLarge,
+		constants.ErrPayloadTooLarge,
+	)
+	FailedToGetUserID = kind(
+		"failed_to_get_user_id",
+		http.StatusUnauthorized,
+		constants.ErrFailedToGetUserID,
+	)
+	InvalidCredentials = kind(
+		"invalid_credentials",
+		http.StatusUnauthorized,
+		constants.ErrInvalidCredentials,
+	)
+	NotFound = kind("not_found", http.StatusNotFound, constants.ErrNotFound)
+	Internal = kind("internal", http.StatusInternalServerError, constants.ErrInternal)
+	// Timeout and Unavailable are served when the database didn't answer in
+	// time or the request was cancelled, for instance by a shutdown.
+	Timeout     = kind("timeout", http.StatusGatewayTimeout, constants.ErrTimeout)
+	Unavailable = kind("unavailable", http.StatusServiceUnavailable, constants.ErrUnavailable)
+	// NotReady and ShuttingDown fail the readiness probe, so load balancers
+	// stop sending traffic to the instance.
+	NotReady     = kind("not_ready", http.StatusServiceUnavailable, constants.ErrNotReady)
+	ShuttingDown = kind("shutting_down", http.StatusServiceUnavailable, constants.ErrShuttingDown)
+
+	// Authentication-related errors
+	AuthorizationMissing = kind(
+		"authorization_missing",
+		http.StatusUnauthorized,
+		constants.ErrAuthorizationMissing,
+	)
+	InvalidToken         = kind("invalid_token", http.StatusUnauthorized, constants.ErrInvalidToken)
+	SessionEnded         = kind("session_ended", http.StatusUnauthorized, constants.ErrSessionEnded)
+	TokenNotAllowed      = kind("token_not_allowed", http.StatusForbidden, constants.ErrTokenNotAllowed)
+	MissingScope         = kind("missing_scope", http.StatusForbidden, constants.ErrMissingScope)
+	FailedToAuthenticate = kind(
+		"failed_to_authenticate",
+		http.StatusInternalServerError,
+		constants.ErrFailedToAuthenticate,
+	)
+	TooManyRequests = kind(
+		"too_many_requests",
+		http.StatusTooManyRequests,
+		constants.ErrTooManyRequests,
+	)
+
+	// Snippet-related errors
+	FailedToGetSnippets = kind(
+		"failed_to_get_snippets",
+		http.StatusInternalServerError,
+		constants.ErrFailedToGetSnippets,
+	)
+	FailedToCreateSnippet = kind(
+		"failed_to_create_snippet",
+		http.StatusInternalServerError,
+		constants.ErrFailedToCreateSnippet,
+	)
+	FailedToUpdateSnippet = kind(
+		"failed_to_update_snippet",
+		http.StatusInternalServerError,
+		constants.ErrFailedToUpdateSnippet,
+	)
+	SnippetNotFound       = kind("snippet_not_found", http.StatusNotFound, constants.ErrSnippetNotFound)
+	FailedToDeleteSnippet = kind(
+		"failed_to_delete_snippet",
+		http.StatusInternalServerError,
+		constants.ErrFailedToDeleteSnippet,
+	)
+	InvalidSnippetID = kind(
+		"invalid_snippet_id",
+		http.StatusBadRequest,
+		constants.ErrInvalidSnippetID,
+	)
+	InvalidSearchQuery = kind(
+		"invalid_search_query",
+		http.StatusBadRequest,
+		constants.ErrInvalidSearchQuery,
+	)
+	FailedToSearchSnippets = kind(
+		"failed_to_search_snippets",
+		http.StatusInternalServerError,
+		constants.ErrFailedToSearchSnippets,
+	)
+	InvalidLimit       = kind("invalid_limit", http.StatusBadRequest, constants.ErrInvalidLimit)
+	InvalidCursor      = kind("invalid_cursor", http.StatusBadRequest, constants.ErrInvalidCursor)
+	InvalidSortOptions = kind(
+		"invalid_sort_options",
+		http.StatusBadRequest,
+		constants.ErrInvalidSortOptions,
+	)
+	MissingLanguage    = kind("missing_language", http.StatusBadRequest, constants.ErrMissingLanguage)
+	CursorSortMismatch = kind(
+		"cursor_sort_mismatch",
+		http.StatusBadRequest,
+		constants.ErrCursorSortMismatch,
+	)
+	SnippetChanged = kind(
+		"snippet_changed",
+		http.StatusPreconditionFailed,
+		constants.ErrSnippetChanged,
+	)
+	IfMatchRequired = kind(
+		"if_match_required",
+		http.StatusPreconditionRequired,
+		constants.ErrIfMatchRequired,
+	)
+	VersionRequired = kind(
+		"version_required",
+		http.StatusPreconditionRequired,
+		constants.ErrVersionRequired,
+	)
+
+	// Batch-related errors
+	FailedToRunBatch = kind(
+		"failed_to_run_batch",
+		http.StatusInternalServerError,
+		constants.ErrFailedToRunBatch,
+	)
+	InvalidBatchMode = kind(
+		"invalid_batch_mode",
+		http.StatusBadRequest,
+		constants.ErrInvalidBatchMode,
+	)
+	EmptyBatch        = kind("empty_batch", http.StatusBadRequest, constants.ErrEmptyBatch)
+	TooManyOperations = kind(
+		"too_many_operations",
+		http.StatusBadRequest,
+		constants.ErrTooManyOperations,
+	)
+	InvalidOperation = kind(
+		"invalid_operation",
+		http.StatusBadRequest,
+		constants.ErrInvalidOperation,
+	)
+	BatchAborted = kind("batch_aborted", http.StatusFailedDependency, constants.ErrBatchAborted)
+
+	// Import and export errors
+	FailedToExport = kind(
+		"failed_to_export",
+		http.StatusInternalServerError,
+		constants.ErrFailedToExport,
+	)
+	FailedToImport = kind(
+		"failed_to_import",
+		http.StatusInternalServerError,
+		constants.ErrFailedToImport,
+	)
+	UnknownFormat         = kind("unknown_format", http.StatusBadRequest, constants.ErrUnknownFormat)
+	InvalidLibrary        = kind("invalid_library", http.StatusBadRequest, constants.ErrInvalidLibrary)
+	InvalidConflictPolicy = kind(
+		"invalid_conflict_policy",
+		http.StatusBadRequest,
+		constants.ErrInvalidConflictPolicy,
+	)
+	InvalidDryRun  = kind("invalid_dry_run", http.StatusBadRequest, constants.ErrInvalidDryRun)
+	ImportTooLarge = kind(
+		"import_too_large",
+		http.StatusRequestEntityTooLarge,
+		constants.ErrImportTooLarge,
+	)
+
+	// Revision-related errors
+	FailedToGetRevisions = kind(
+		"failed_to_get_revisions",
+		http.StatusInternalServerError,
+		constants.ErrFailedToGetRevisions,
+	)
+	FailedToRestoreRevision = kind(
+		"failed_to_restore_revision",
+		http.StatusInternalServerError,
+		constants.ErrFailedToRestoreRevision,
+	)
+	RevisionNotFound = kind(
+		"revision_not_found",
+		http.StatusNotFound,
+		constants.ErrRevisionNotFound,
+	)
+	InvalidRevision = kind("invalid_revision", http.StatusBadRequest, constants.ErrInvalidRevision)
+	DiffTooLarge    = kind(
+		"diff_too_large",
+		http.StatusUnprocessableEntity,
+		constants.ErrDiffTooLarge,
+	)
+
+	// Trash-related errors
+	FailedToGetTrash = kind(
+		"failed_to_get_trash",
+		http.StatusInternalServerError,
+		constants.ErrFailedToGetTrash,
+	)
+	FailedToRestoreSnippet = kind(
+		"failed_to_restore_snippet",
+		http.StatusInternalServerError,
+		constants.ErrFailedToRestoreSnippet,
+	)
+	FailedToPurgeSnippet = kind(
+		"failed_to_purge_snippet",
+		http.StatusInternalServerError,
+		constants.ErrFailedToPurgeSnippet,
+	)
+
+	// Sharing-related errors
+	FailedToShareSnippet = kind(
+		"failed_to_share_snippet",
+		http.StatusInternalServerError,
+		constants.ErrFailedToShareSnippet,
+	)
+	FailedToGetShareLinks = kind(
+		"failed_to_get_share_links",
+		http.StatusInternalServerError,
+		constants.ErrFailedToGetShareLinks,
+	)
+	FailedToRevokeShareLink = kind(
+		"failed_to_revoke_share_link",
+		http.StatusInternalServerError,
+		constants.ErrFailedToRevokeShareLink,
+	)
+	ShareLinkNotFound = kind(
+		"share_link_not_found",
+		http.StatusNotFound,
+		constants.ErrShareLinkNotFound,
+	)
+	InvalidShareID = kind("invalid_share_id", http.StatusBadRequest, constants.ErrInvalidShareID)
+	SnippetPrivate = kind("snippet_private", http.StatusConflict, constants.ErrSnippetPrivate)
+
+	// Organization-related errors
+	FailedToCreateOrg = kind(
+		"failed_to_create_org",
+		http.StatusInternalServerError,
+		constants.ErrFailedToCreateOrg,
+	)
+	FailedToGetOrgs = kind(
+		"failed_to_get_orgs",
+		http.StatusInternalServerError,
+		constants.ErrFailedToGetOrgs,
+	)
+	FailedToGetMembers = kind(
+		"failed_to_get_members",
+		http.StatusInternalServerError,
+		constants.ErrFailedToGetMembers,
+	)
+	FailedToRemoveMember = kind(
+		"failed_to_remove_member",
+		http.StatusInternalServerError,
+		constants.ErrFailedToRemoveMember,
+	)
+	FailedToInvite = kind(
+		"failed_to_invite",
+		http.StatusInternalServerError,
+		constants.ErrFailedToInvite,
+	)
+	FailedToGetInvites = kind(
+		"failed_to_get_invites",
+		http.StatusInternalServerError,
+		constants.ErrFailedToGetInvites,
+	)
+	FailedToAcceptInvite = kind(
+		"failed_to_accept_invite",
+		http.StatusInternalServerError,
+		constants.ErrFailedToAcceptInvite,
+	)
+	InvalidOrgID    = kind("invalid_org_id", http.StatusBadRequest, constants.ErrInvalidOrgID)
+	InvalidInviteID = kind("invalid_invite_id", http.StatusBadRequest, constants.ErrInvalidInviteID)
+	InvalidMemberID = kind("invalid_member_id", http.StatusBadRequest, constants.ErrInvalidMemberID)
+	OrgNotFound     = kind("org_not_found", http.StatusNotFound, constants.ErrOrgNotFound)
+	MemberNotFound  = kind("member_not_found", http.StatusNotFound, constants.ErrMemberNotFound)
+	InviteNotFound  = kind("invite_not_found", http.StatusNotFound, constants.ErrInviteNotFound)
+	AlreadyMember   = kind("already_member", http.StatusConflict, constants.ErrAlreadyMember)
+	InviteExists    = kind("invite_exists", http.StatusConflict, constants.ErrInviteExists)
+	LastOwner       = kind("last_owner", http.StatusConflict, constants.ErrLastOwner)
+	AccessDenied    = kind("access_denied", http.StatusForbidden, constants.ErrAccessDenied)
+
+	// Tag-related errors
+	FailedToGetTags = kind(
+		"failed_to_get_tags",
+		http.StatusInternalServerError,
+		constants.ErrFailedToGetTags,
+	)
+	FailedToRenameTag = kind(
+		"failed_to_rename_tag",
+		http.StatusInternalServerError,
+		constants.ErrFailedToRenameTag,
+	)
+	FailedToMergeTags = kind(
+		"failed_to_merge_tags",
+		http.StatusInternalServerError,
+		constants.ErrFailedToMergeTags,
+	)
+	TagNotFound      = kind("tag_not_found", http.StatusNotFound, constants.ErrTagNotFound)
+	TagAlreadyExists = kind(
+		"tag_already_exists",
+		http.StatusConflict,
+		constants.ErrTagAlreadyExists,
+	)
+	MergeIntoItself = kind("merge_into_itself", http.StatusBadRequest, constants.ErrMergeIntoItself)
+	InvalidTagMatch = kind("invalid_tag_match", http.StatusBadRequest, constants.ErrInvalidTagMatch)
+
+	// User-related errors
+	InvalidEmailFormat = kind(
+		"invalid_email_format",
+		http.StatusBadRequest,
+		constants.ErrInvalidEmailFormat,
+	)
+	FailedToCreateUser = kind(
+		"failed_to_create_user",
+		http.StatusInternalServerError,
+		constants.ErrFailedToCreateUser,
+	)
+	FailedToGenerateToken = kind(
+		"failed_to_generate_token",
+		http.StatusInternalServerError,
+		constants.ErrFailedToGenerateToken,
+	)
+	FailedToDeleteUser = kind(
+		"failed_to_delete_user",
+		http.StatusInternalServerError,
+		constants.ErrFailedToDeleteUser,
+	)
+	FailedToUpdatePassword = kind(
+		"failed_to_update_password",
+		http.StatusInternalServerError,
+		constants.ErrFailedToUpdatePassword,
+	)
+	PasswordUnchanged = kind(
+		"password_unchanged",
+		http.StatusBadRequest,
+		constants.ErrPasswordUnchanged,
+	)
+	UsernameTaken = kind("username_taken", http.StatusConflict, constants.ErrUsernameTaken)
+	EmailTaken    = kind("email_taken", http.StatusConflict, constants.ErrEmailTaken)
+
+	// Session-related errors
+	InvalidRefreshToken = kind(
+		"invalid_refresh_token",
+		http.StatusUnauthorized,
+		constants.ErrInvalidRefreshToken,
+	)
+	RefreshTokenReused = kind(
+		"refresh_token_reused",
+		http.StatusUnauthorized,
+		constants.ErrRefreshTokenReused,
+	)
+	FailedToRefreshToken = kind(
+		"failed_to_refresh_token",
+		http.StatusInternalServerError,
+		constants.ErrFailedToRefreshToken,
+	)
+	FailedToLogout = kind(
+		"failed_to_logout",
+		http.StatusInternalServerError,
+		constants.ErrFailedToLogout,
+	)
+
+	// Access token-related errors
+	FailedToCreateAccessToken = kind(
+		"failed_to_create_access_token",
+		http.StatusInternalServerError,
+		constants.ErrFailedToCreateAccessToken,
+	)
+	FailedToGetAccessTokens = kind(
+		"failed_to_get_access_tokens",
+		http.StatusInternalServerError,
+		constants.ErrFailedToGetAccessTokens,
+	)
+	FailedToRotateAccessToken = kind(
+		"failed_to_rotate_access_token",
+		http.StatusInternalServerError,
+		constants.ErrFailedToRotateAccessToken,
+	)
+	FailedToRevokeAccessToken = kind(
+		"failed_to_revoke_access_token",
+		http.StatusInternalServerError,
+		constants.ErrFailedToRevokeAccessToken,
+	)
+	AccessTokenNotFound = kind(
+		"access_token_not_found",
+		http.StatusNotFound,
+		constants.ErrAccessTokenNotFound,
+	)
+	InvalidAccessTokenID = kind(
+		"invalid_access_token_id",
+		http.StatusBadRequest,
+		constants.ErrInvalidAccessTokenID,
+	)
+)
+
+func kind(code string, status int, message string) Kind {
+	return Kind{Code: code, Status: status, Message: message}
+}
@@ -0,0 +1,145 @@
+// Package problem writes API errors as RFC 7807 problem details, so clients
+// can match on a stable code instead of on messages.
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/Jitesh117/snippet-manager-backend/logging"
+)
+
+const ContentType = "application/problem+json"
+
+// Kind is one entry of the error catalog: a stable machine-readable code, the
+// HTTP status it is served with and the message shown to people.
+type Kind struct {
+	Code    string
+	Status  int
+	Message string
+}
+
+// Error makes a Kind usable as an error, for helpers that can fail with a
+// client error before any response is written.
+func (k Kind) Error() string {
+	return k.Message
+}
+
+// Withf fills the verbs of a message such as constants.ErrMissingScope.
+func (k Kind) Withf(args ...any) Kind {
+	k.Message = fmt.Sprintf(k.Message, args...)
+	return k
+}
+
+// WithDetail appends detail, which must be safe to show to clients, to the
+// message.
+func (k Kind) WithDetail(detail string) Kind {
+	k.Message += ": " + detail
+	return k
+}
+
+// Details is the problem+json document. Code, Errors and Current are
+// extension members; Errors lists the invalid fields of a rejected request and
+// Current is the resource as it is now when the request was based on a stale
+// copy of it.
+type Details struct {
+	Type    string              `json:"type"`
+	Title   string              `json:"title"`
+	Status  int                 `json:"status"`
+	Detail  string              `json:"detail"`
+	Code    string              `json:"code"`
+	Errors  []helper.FieldError `json:"errors,omitempty"`
+	Current json.RawMessage     `json:"current,omitempty"`
+}
+
+// Write sends kind as the response.
+func Write(w http.ResponseWriter, kind Kind) {
+	write(w, kind, Details{})
+}
+
+// WriteCurrent sends kind along with current, the up to date version of the
+// resource the client tried to change.
+func WriteCurrent(w http.ResponseWriter, kind Kind, current any) {
+	body, err := json.Marshal(current)
+	if err != nil {
+		WriteError(w, err, Internal)
+		return
+	}
+	write(w, kind, Details{Current: body})
+}
+
+// WriteError sends From(err, failure). An err that isn't a Kind goes to the
+// access log of the request instead.
+func WriteError(w http.ResponseWriter, err error, failure Kind) {
+	if !errors.As(err, new(Kind)) {
+		logging.SetError(w, err)
+	}
+	Write(w, From(err, failure))
+}
+
+// From returns err when it is a Kind and failure otherwise. Anything that
+// isn't a Kind is never shown to the client. Operations cut short by their
+// deadline are reported as Timeout and cancelled ones as Unavailable instead
+// of failure.
+func From(err error, failure Kind) Kind {
+	var kind Kind
+	if errors.As(err, &kind) {
+		return kind
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		failure = Timeout
+	case errors.Is(err, context.Canceled):
+		failure = Unavailable
+	}
+	return failure
+}
+
+// WriteValidation rejects a request that failed one of the helper
+// validators, listing every invalid field when err is a
+// helper.ValidationError. Validator messages are meant for clients.
+func WriteValidation(w http.ResponseWriter, err error) {
+	send(w, Validation(err))
+}
+
+// Validation is the document WriteValidation sends for err, for responses
+// that embed problems rather than being one.
+func Validation(err error) Details {
+	var fields helper.ValidationError
+	if errors.As(err, &fields) {
+		details := InvalidPayload.Details()
+		details.Errors = fields
+		return details
+	}
+	return InvalidPayload.WithDetail(err.Error()).Details()
+}
+
+// Details is the problem document of kind without extension members.
+func (k Kind) Details() Details {
+	return Details{
+		Type:   "about:blank",
+		Title:  http.StatusText(k.Status),
+		Status: k.Status,
+		Detail: k.Message,
+		Code:   k.Code,
+	}
+}
+
+// write sends kind with the extension members set in extra.
+func write(w http.ResponseWriter, kind Kind, extra Details) {
+	details := kind.Details()
+	details.Errors = extra.Errors
+	details.Current = extra.Current
+	send(w, details)
+}
+
+func send(w http.ResponseWriter, details Details) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(details.Status)
+	json.NewEncoder(w).Encode(details)
+}
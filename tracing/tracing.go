@@ -0,0 +1,199 @@
+// Package tracing sets up OpenTelemetry and starts the spans the rest of the
+// service records: one per request, one per middleware and one per store
+// operation. Incoming W3C traceparent headers are continued.
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/Jitesh117/snippet-manager-backend/helper"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "snippet-manager"
+	tracerName  = "github.com/Jitesh117/snippet-manager-backend"
+)
+
+// Options picks where spans go. They are sent to OTLPEndpoint over OTLP/HTTP
+// when it is set and otherwise written as JSON lines to File, or to stdout
+// when File is empty too, which works without a collector. SampleRatio is the
+// share of new traces kept; traces started upstream keep their decision.
+type Options struct {
+	OTLPEndpoint string
+	File         string
+	SampleRatio  float64
+}
+
+// Setup installs the tracer provider and the W3C trace context propagator.
+// The returned func flushes the spans still buffered and must be called
+// before exiting.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	exporter, closeOutput, err := newExporter(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(
+			sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio)),
+		),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeErr := closeOutput(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+	if opts.OTLPEndpoint != "" {
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(opts.OTLPEndpoint))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create the OTLP exporter: %w", err)
+		}
+		return exporter, noClose, nil
+	}
+
+	var output io.Writer = os.Stdout
+	closeOutput := noClose
+	if opts.File != "" {
+		file, err := os.OpenFile(opts.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open the trace file: %w", err)
+		}
+		output, closeOutput = file, file.Close
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(output))
+	if err != nil {
+		return nil, nil, err
+	}
+	return exporter, closeOutput, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Start begins a span named name as a child of the one in ctx.
+func Start(
+	ctx context.Context,
+	name string,
+	attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End finishes span, marking it failed when err is set.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartQuery begins the span of a store operation.
+func StartQuery(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracer().Start(
+		ctx,
+		"db "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+		),
+	)
+}
+
+// SetRows records on the current span how many rows an operation returned.
+func SetRows(ctx context.Context, rows int) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("db.rows", rows))
+}
+
+// SetUser records who made the request on the current span. The ID is
+// hashed so traces don't carry it in the clear.
+func SetUser(ctx context.Context, userID uuid.UUID) {
+	sum := sha256.Sum256(userID[:])
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("enduser.id_hash", hex.EncodeToString(sum[:8])),
+	)
+}
+
+// Middleware starts a server span for every request before passing it to
+// next, named by the pattern of the route that matches it in routes and
+// continuing the trace of an incoming traceparent header. Spans record the
+// route rather than the path, which can carry secrets such as share tokens.
+func Middleware(routes *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		_, route := routes.Handler(r)
+		name := r.Method + " " + route
+		if route == "" {
+			name = r.Method
+		}
+		ctx, span := tracer().Start(
+			ctx,
+			name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+			),
+		)
+		defer span.End()
+
+		recorder := helper.NewResponseRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(
+			semconv.HTTPResponseStatusCode(recorder.Status),
+			semconv.HTTPResponseBodySize(int(recorder.Bytes)),
+		)
+		if recorder.Status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.Status))
+		}
+	})
+}
+
+// Handler runs next in a span named name, which tells the handler's own time
+// apart from that of the middleware in front of it.
+func Handler(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := Start(r.Context(), name)
+		defer span.End()
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,90 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jitesh117/snippet-manager-backend/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddlewareContinuesTraceparent(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	mux := http.NewServeMux()
+	created := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}
+	mux.HandleFunc("/things/", tracing.Handler("HandleThing", created))
+	req := httptest.NewRequest(http.MethodPost, "/things/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	tracing.Middleware(mux, mux).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans want the request and the handler", len(spans))
+	}
+	handler, server := spans[0], spans[1]
+	if server.Name != "POST /things/" || handler.Name != "HandleThing" {
+		t.Errorf("got spans %q and %q", server.Name, handler.Name)
+	}
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("got trace %s want the incoming one", got)
+	}
+	if got := server.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("got parent %s want the incoming span", got)
+	}
+	if handler.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("handler span isn't a child of the request span")
+	}
+	want := map[attribute.Key]string{
+		"http.route":                "/things/",
+		"http.response.status_code": "201",
+	}
+	for _, attr := range server.Attributes {
+		if attr.Key == "url.path" {
+			t.Errorf("request span records the path %s", attr.Value.Emit())
+		}
+		if value, ok := want[attr.Key]; ok {
+			if attr.Value.Emit() != value {
+				t.Errorf("got %s=%s want %s", attr.Key, attr.Value.Emit(), value)
+			}
+			delete(want, attr.Key)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("request span is missing %v", want)
+	}
+}
+
+func TestSetupWritesToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{File: path, SampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := tracing.Start(context.Background(), "offline")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(spans), `"Name":"offline"`) {
+		t.Errorf("span wasn't written to the file: %s", spans)
+	}
+}